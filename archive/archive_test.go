@@ -0,0 +1,294 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+// testTimeOf {"ts":<unix saniye>,...} satırlarının zamanını döner.
+func testTimeOf(line []byte) (time.Time, bool) {
+	rest, ok := bytes.CutPrefix(line, []byte(`{"ts":`))
+	if !ok {
+		return time.Time{}, false
+	}
+	end := bytes.IndexByte(rest, ',')
+	if end < 0 {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(string(rest[:end]), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+// writeArchive n satırlık bir arşiv yazar ve yolunu döner.
+func writeArchive(t *testing.T, opts Options, n int) string {
+	t.Helper()
+	opts.ChunkEvents = 3
+	opts.TimeOf = testTimeOf
+
+	var buf bytes.Buffer
+	aw, err := NewWriter(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		// Satırlar bilerek parça parça yazılır
+		line := fmt.Sprintf(`{"ts":%d,"i":%d}`+"\n", 1000+i, i)
+		if _, err := aw.Write([]byte(line[:5])); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := aw.Write([]byte(line[5:])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "home_id_a_01_01_2024_all_event_log"+Ext)
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func keyCreds() Credentials {
+	return Credentials{Keys: func(id string) ([]byte, error) {
+		if id != "k1" {
+			return nil, fmt.Errorf("bilinmeyen anahtar %s", id)
+		}
+		return testKey, nil
+	}}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		creds Credentials
+	}{
+		{"şifresiz", Options{}, Credentials{}},
+		{"şifre", Options{Password: "gizli"}, Credentials{Password: "gizli"}},
+		{"veri anahtarı", Options{Key: testKey, KeyID: "k1"}, keyCreds()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeArchive(t, tt.opts, 10)
+
+			n, err := Verify(path, tt.creds)
+			if err != nil || n != 10 {
+				t.Fatalf("Verify = %d, %v; 10 bekleniyordu", n, err)
+			}
+
+			r, err := Open(path, tt.creds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if got := len(r.Index().Chunks); got != 4 {
+				t.Errorf("chunk sayısı = %d, 4 bekleniyordu", got)
+			}
+
+			// [1004, 1005] yalnızca ikinci chunk'ı (1003-1005) okur
+			var lines []string
+			err = r.ForEach(time.Unix(1004, 0), time.Unix(1005, 0), func(line []byte) error {
+				lines = append(lines, string(line))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{`{"ts":1003,"i":3}`, `{"ts":1004,"i":4}`, `{"ts":1005,"i":5}`}
+			if fmt.Sprint(lines) != fmt.Sprint(want) {
+				t.Errorf("ForEach = %v, %v bekleniyordu", lines, want)
+			}
+		})
+	}
+}
+
+func TestWrongCredentials(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		creds Credentials
+	}{
+		{"şifre yok", Options{Password: "gizli"}, Credentials{}},
+		{"yanlış şifre", Options{Password: "gizli"}, Credentials{Password: "başka"}},
+		{"keyring yok", Options{Key: testKey, KeyID: "k1"}, Credentials{}},
+		{"yanlış anahtar", Options{Key: testKey, KeyID: "k1"}, Credentials{Keys: func(string) ([]byte, error) {
+			return bytes.Repeat([]byte{8}, 32), nil
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeArchive(t, tt.opts, 4)
+			if _, err := Verify(path, tt.creds); err == nil {
+				t.Fatal("hata bekleniyordu")
+			}
+		})
+	}
+}
+
+func TestCorruptArchive(t *testing.T) {
+	putUint64 := func(at func(size int) int, v uint64) func([]byte) []byte {
+		return func(b []byte) []byte {
+			binary.BigEndian.PutUint64(b[at(len(b)):], v)
+			return b
+		}
+	}
+	flip := func(at func(size int) int) func([]byte) []byte {
+		return func(b []byte) []byte {
+			b[at(len(b))] ^= 0xff
+			return b
+		}
+	}
+	footerOffset := func(size int) int { return size - footerSize }
+	footerLength := func(size int) int { return size - footerSize + 8 }
+
+	tests := []struct {
+		name    string
+		mutate  func([]byte) []byte
+		corrupt bool // errors.Is(err, ErrCorrupt) bekleniyor (şifresizde)
+	}{
+		{"footer kesik", func(b []byte) []byte { return b[:len(b)-1] }, true},
+		{"index kesik", func(b []byte) []byte { return append(b[:len(b)-footerSize-5], b[len(b)-footerSize:]...) }, true},
+		{"negatif index uzunluğu", putUint64(footerLength, 1<<63), true},
+		{"maksimum index uzunluğu", putUint64(footerLength, ^uint64(0)), true},
+		{"sıfır index uzunluğu", putUint64(footerLength, 0), true},
+		{"index offset dosya dışında", putUint64(footerOffset, 1<<62), true},
+		{"index offset header'da", putUint64(footerOffset, 1), true},
+		{"header reserved byte", flip(func(int) int { return 6 }), true},
+		{"header salt", flip(func(int) int { return 10 }), true},
+		{"ilk chunk", flip(func(int) int { return headerSize + 2 }), true},
+		{"index", flip(func(size int) int { return size - footerSize - 40 }), true},
+		{"desteklenmeyen sürüm", flip(func(int) int { return 4 }), false},
+		{"sürüm 1'e düşürülmüş", func(b []byte) []byte { b[4] = 1; return b }, false},
+	}
+	archives := []struct {
+		name  string
+		opts  Options
+		creds Credentials
+	}{
+		{"şifresiz", Options{}, Credentials{}},
+		{"veri anahtarı", Options{Key: testKey, KeyID: "k1"}, keyCreds()},
+	}
+	for _, a := range archives {
+		for _, tt := range tests {
+			t.Run(a.name+"/"+tt.name, func(t *testing.T) {
+				path := writeArchive(t, a.opts, 10)
+				b, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, tt.mutate(b), 0o600); err != nil {
+					t.Fatal(err)
+				}
+
+				_, err = Verify(path, a.creds)
+				if err == nil {
+					t.Fatal("hata bekleniyordu")
+				}
+				if tt.corrupt && a.opts.Key == nil && !errors.Is(err, ErrCorrupt) {
+					t.Errorf("ErrCorrupt bekleniyordu: %v", err)
+				}
+			})
+		}
+	}
+}
+
+// TestCorruptChunkIndex index'te dosya sınırlarını aşan chunk'ların okumadan önce
+// reddedildiğini doğrular.
+func TestCorruptChunkIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		chunk ChunkInfo
+	}{
+		{"negatif uzunluk", ChunkInfo{Offset: headerSize, Length: -1, Events: 1}},
+		{"sıfır uzunluk", ChunkInfo{Offset: headerSize, Length: 0, Events: 1}},
+		{"taşan uzunluk", ChunkInfo{Offset: headerSize, Length: 1<<63 - 1, Events: 1}},
+		{"header'da", ChunkInfo{Offset: 0, Length: 4, Events: 1}},
+		{"index'te", ChunkInfo{Offset: headerSize, Length: 1 << 20, Events: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Index'i geçerli anahtarla yeniden yazabilmek için Writer'ın index'i değiştirilir
+			var buf bytes.Buffer
+			aw, err := NewWriter(&buf, Options{Key: testKey, KeyID: "k1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			aw.index.Chunks = append(aw.index.Chunks, tt.chunk)
+			aw.index.Events = tt.chunk.Events
+			if err := aw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "a"+Ext)
+			if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = Open(path, keyCreds())
+			if !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("ErrCorrupt bekleniyordu: %v", err)
+			}
+		})
+	}
+}
+
+// TestChunkSizeLimit büyük event'lerin yazılmadığını ve okuyucunun sınırdan büyük açılan
+// chunk'ları reddettiğini doğrular.
+func TestChunkSizeLimit(t *testing.T) {
+	var buf bytes.Buffer
+	aw, err := NewWriter(&buf, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := append(bytes.Repeat([]byte("a"), maxChunkBytes+1), '\n')
+	if _, err := aw.Write(big); err == nil {
+		t.Fatal("maxChunkBytes'tan büyük event yazıldı")
+	}
+
+	tests := []struct {
+		name    string
+		size    int
+		wantErr bool
+	}{
+		{"sınırda", 2 * maxChunkBytes, false},
+		{"sınırın üstünde", 2*maxChunkBytes + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Writer'ın sınırı atlanarak tek bir büyük (iyi sıkışan) chunk yazılır
+			var buf bytes.Buffer
+			aw, err := NewWriter(&buf, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			aw.chunk.Write(bytes.Repeat([]byte("a"), tt.size))
+			aw.cur.Events = 1
+			if err := aw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "a"+Ext)
+			if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = Verify(path, Credentials{})
+			if tt.wantErr != errors.Is(err, ErrCorrupt) || (!tt.wantErr && err != nil) {
+				t.Errorf("Verify hata = %v, hata bekleniyor = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
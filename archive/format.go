@@ -0,0 +1,163 @@
+// Package archive zstd ile sıkıştırılmış, parçalı (chunked) ve zaman indeksli
+// arşiv formatını (.lsa) okur ve yazar.
+//
+// Dosya yapısı:
+//
+//	header  : "LSA1" | version(1) | flags(1) | reserved(2) | salt(16)
+//	key id  : uzunluk(1) | id (yalnızca flagKeyID varsa; veri anahtarının keyring id'si)
+//	chunk*  : her biri bağımsız bir zstd frame'i (şifreliyse nonce(12) | AES-GCM ciphertext)
+//	index   : JSON Index (şifreliyse chunk'larla aynı şekilde, değilse frame | sha256(32))
+//	footer  : index offset(8) | index uzunluğu(8) | "LSAI"
+//
+// Her chunk NDJSON event satırlarından oluşur. Index her chunk'ın konumunu, event sayısını
+// ve en küçük/en büyük event zamanını tutar; böylece bir zaman aralığı için yalnızca
+// ilgili chunk'lar okunup çözülür.
+//
+// Index header'ın tamamına (salt, flag'ler, key id) ve index offset'ine bağlıdır: şifreli
+// arşivlerde bunlar index'in AES-GCM ek verisidir, şifresizlerde index'in sonundaki SHA-256'ya
+// dahildir. Index chunk konumlarını ve (şifresizse) chunk özetlerini taşıdığı için footer'dan
+// chunk'lara kadar her şey doğrulanır. Şifresiz arşivlerde anahtar olmadığından bu yalnızca
+// bozulmaya karşı korur; kasıtlı değişikliğe karşı arşiv şifrelenmelidir.
+//
+// Bir chunk açıldığında en fazla 2*maxChunkBytes olabilir (chunk maxChunkBytes'ı geçince
+// kapatılır ve tek bir event maxChunkBytes'tan büyük olamaz); okuyucu daha büyük chunk'ları
+// açmaz.
+//
+// Şifreleme anahtarı ya zip_password'den PBKDF2 ile ya da (flagKeyID) keyring'deki
+// veri anahtarından HMAC-SHA256(veri anahtarı, salt) ile türetilir.
+package archive
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// Ext yeni formatın dosya uzantısı
+	Ext = ".lsa"
+
+	headerMagic = "LSA1"
+	footerMagic = "LSAI"
+	version     = 2 // Yazılan sürüm
+	minVersion  = 2 // Okunan en eski sürüm
+
+	headerSize = 24
+	footerSize = 20
+	saltSize   = 16
+	sumSize    = sha256.Size
+
+	flagEncrypted = 1 << 0
+	flagKeyID     = 1 << 1
+
+	maxKeyIDLen = 255
+
+	kdfIterations = 100_000
+
+	// DefaultChunkEvents bir chunk'taki varsayılan en fazla event sayısı
+	DefaultChunkEvents = 5000
+	// Bir chunk'ın sıkıştırılmamış en fazla boyutu (event sayısından önce dolarsa); tek bir
+	// event de bundan büyük olamaz
+	maxChunkBytes = 4 * 1024 * 1024
+)
+
+var (
+	ErrNotArchive = errors.New("lsa arşivi değil")
+	ErrCorrupt    = errors.New("lsa arşivi bozuk")
+)
+
+// Index arşivin sonundaki chunk tablosu.
+type Index struct {
+	Events int         `json:"events"`
+	Chunks []ChunkInfo `json:"chunks"`
+}
+
+// ChunkInfo tek bir chunk'ın konumu ve zaman aralığı. Zamanlar unix milisaniyedir;
+// Untimed, zaman damgası okunamayan event sayısıdır (bu chunk'lar aralık sorgularında hep okunur).
+type ChunkInfo struct {
+	Offset  int64  `json:"offset"`
+	Length  int64  `json:"length"`
+	Events  int    `json:"events"`
+	MinTime int64  `json:"min_ts,omitempty"`
+	MaxTime int64  `json:"max_ts,omitempty"`
+	Untimed int    `json:"untimed,omitempty"`
+	Sum     string `json:"sha256,omitempty"` // Şifresiz arşivlerde saklanan byte'ların özeti
+}
+
+// overlaps chunk'ın [from, to] aralığıyla kesişip kesişmediğini döner. Sıfır zaman sınırsızdır.
+func (ci ChunkInfo) overlaps(from, to time.Time) bool {
+	if ci.Untimed > 0 {
+		return true
+	}
+	if !from.IsZero() && ci.MaxTime < from.UnixMilli() {
+		return false
+	}
+	if !to.IsZero() && ci.MinTime > to.UnixMilli() {
+		return false
+	}
+	return true
+}
+
+// IsArchive dosyanın bir backup arşivi (.zip veya .lsa) olup olmadığını döner.
+func IsArchive(name string) bool {
+	return strings.HasSuffix(name, ".zip") || strings.HasSuffix(name, Ext)
+}
+
+// IsLSA dosyanın yeni formatta olup olmadığını döner.
+func IsLSA(name string) bool {
+	return strings.HasSuffix(name, Ext)
+}
+
+// EntryName arşivin eski zip formatındaki entry adını döner (ör: ..._all_event_log.json).
+func EntryName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + ".json"
+}
+
+// LegacyName arşivin eski zip formatındaki dosya adını döner.
+func LegacyName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + ".zip"
+}
+
+func deriveKey(password string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, password, salt, kdfIterations, 32)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkAAD chunk'ların yerinin değiştirilmesini engellemek için sıra numarasını AAD olarak kullanır.
+func chunkAAD(seq int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(seq))
+	return b
+}
+
+// indexAD index'i header'a ve kendi konumuna bağlayan ek veri.
+func indexAD(header []byte, indexOffset int64) []byte {
+	const prefix = "index"
+	ad := make([]byte, 0, len(prefix)+len(header)+8)
+	ad = append(ad, prefix...)
+	ad = append(ad, header...)
+	return binary.BigEndian.AppendUint64(ad, uint64(indexOffset))
+}
+
+// indexSum şifresiz index'in sonuna eklenen özet.
+func indexSum(ad, frame []byte) []byte {
+	h := sha256.New()
+	h.Write(ad)
+	h.Write(frame)
+	return h.Sum(nil)
+}
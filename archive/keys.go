@@ -0,0 +1,105 @@
+package archive
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	yzip "github.com/yeka/zip"
+)
+
+// keyCommentPrefix veri anahtarıyla şifrelenmiş zip entry'lerinin yorum alanındaki işaret.
+const keyCommentPrefix = "key_id="
+
+// Credentials arşivleri açmak için gereken bilgiler.
+type Credentials struct {
+	// Password key id'si olmayan (eski) arşivlerin şifresi (zip_password)
+	Password string
+	// Keys key id'si kayıtlı arşivler için veri anahtarını döner; nil ise bu arşivler açılamaz
+	Keys func(id string) ([]byte, error)
+}
+
+func (c Credentials) key(id string) ([]byte, error) {
+	if c.Keys == nil {
+		return nil, fmt.Errorf("arşiv %s anahtarıyla şifreli, keyring yüklü değil", id)
+	}
+	key, err := c.Keys(id)
+	if err != nil {
+		return nil, fmt.Errorf("arşiv anahtarı %s alınamadı: %w", id, err)
+	}
+	return key, nil
+}
+
+// KeyPassword veri anahtarından zip AES şifresini türetir.
+func KeyPassword(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// KeyComment veri anahtarıyla şifrelenen zip entry'sine yazılacak yorumu döner.
+func KeyComment(id string) string {
+	return keyCommentPrefix + id
+}
+
+// zipKeyID zip entry yorumundaki key id'sini döner; yoksa boş.
+func zipKeyID(f *yzip.File) string {
+	if !strings.HasPrefix(f.Comment, keyCommentPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(f.Comment, keyCommentPrefix)
+}
+
+// OpenZipEntry şifreli zip entry'sinin şifresini (key id'si varsa veri anahtarından) ayarlar.
+func OpenZipEntry(f *yzip.File, creds Credentials) error {
+	if !f.IsEncrypted() {
+		return nil
+	}
+	if id := zipKeyID(f); id != "" {
+		key, err := creds.key(id)
+		if err != nil {
+			return err
+		}
+		f.SetPassword(KeyPassword(key))
+		return nil
+	}
+	f.SetPassword(creds.Password)
+	return nil
+}
+
+// fileKey veri anahtarından arşive özel chunk anahtarını türetir.
+func fileKey(dataKey, salt []byte) []byte {
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write(salt)
+	return mac.Sum(nil)
+}
+
+// KeyID arşivin şifrelendiği veri anahtarının id'sini döner; zip_password ile
+// şifrelenmiş veya şifresiz arşivler için boş döner.
+func KeyID(path string) (string, error) {
+	if IsLSA(path) {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		h, err := readHeader(f)
+		if err != nil {
+			return "", err
+		}
+		return h.keyID, nil
+	}
+
+	zr, err := yzip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if id := zipKeyID(f); id != "" {
+			return id, nil
+		}
+	}
+	return "", nil
+}
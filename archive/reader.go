@@ -0,0 +1,426 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	yzip "github.com/yeka/zip"
+)
+
+// ErrStop ForEach callback'lerinden dönülürse okuma hatasız olarak durur.
+var ErrStop = errors.New("stop")
+
+// Reader bir .lsa arşivini index üzerinden okur.
+type Reader struct {
+	f     *os.File
+	aead  cipher.AEAD
+	dec   *zstd.Decoder
+	index Index
+}
+
+// Open arşivin header, footer ve index'ini okuyup doğrular. Chunk'lar ihtiyaç oldukça okunur.
+func Open(path string, creds Credentials) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(f, creds)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// header arşiv başlığının çözülmüş hali.
+type header struct {
+	flags     byte
+	salt      []byte
+	keyID     string
+	dataStart int64  // İlk chunk'ın başlayabileceği konum
+	raw       []byte // Key id dahil header byte'ları (index'e bağlı)
+}
+
+func readHeader(f *os.File) (header, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return header{}, err
+	}
+	if info.Size() < headerSize+footerSize {
+		return header{}, ErrNotArchive
+	}
+
+	buf := make([]byte, headerSize+1+maxKeyIDLen)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return header{}, err
+	}
+	buf = buf[:n]
+	if string(buf[:4]) != headerMagic {
+		return header{}, ErrNotArchive
+	}
+	if buf[4] < minVersion || buf[4] > version {
+		return header{}, fmt.Errorf("desteklenmeyen lsa sürümü: %d", buf[4])
+	}
+
+	h := header{flags: buf[5], salt: buf[8 : 8+saltSize], dataStart: headerSize}
+	if h.flags&flagKeyID != 0 {
+		if len(buf) <= headerSize {
+			return header{}, fmt.Errorf("%w: key id okunamadı", ErrCorrupt)
+		}
+		idLen := int(buf[headerSize])
+		if idLen == 0 || len(buf) < headerSize+1+idLen {
+			return header{}, fmt.Errorf("%w: key id okunamadı", ErrCorrupt)
+		}
+		h.keyID = string(buf[headerSize+1 : headerSize+1+idLen])
+		h.dataStart = int64(headerSize + 1 + idLen)
+	}
+	h.raw = buf[:h.dataStart]
+	return h, nil
+}
+
+func newReader(f *os.File, creds Credentials) (*Reader, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	h, err := readHeader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reader{f: f}
+	if h.flags&flagEncrypted != 0 {
+		var key []byte
+		if h.keyID != "" {
+			dataKey, err := creds.key(h.keyID)
+			if err != nil {
+				return nil, err
+			}
+			key = fileKey(dataKey, h.salt)
+		} else {
+			if creds.Password == "" {
+				return nil, fmt.Errorf("arşiv şifreli, şifre verilmedi")
+			}
+			if key, err = deriveKey(creds.Password, h.salt); err != nil {
+				return nil, err
+			}
+		}
+		if r.aead, err = newAEAD(key); err != nil {
+			return nil, err
+		}
+	}
+
+	footer := make([]byte, footerSize)
+	if _, err := f.ReadAt(footer, info.Size()-footerSize); err != nil {
+		return nil, err
+	}
+	if string(footer[16:]) != footerMagic {
+		return nil, fmt.Errorf("%w: footer bulunamadı (yarım yazılmış olabilir)", ErrCorrupt)
+	}
+	// Footer doğrulanmamış veridir: değerler int64'e çevrilmeden önce dosya sınırlarıyla
+	// karşılaştırılır, böylece negatif veya taşan uzunluklar için bellek ayrılmaz.
+	indexEnd := uint64(info.Size() - footerSize)
+	offset, length := binary.BigEndian.Uint64(footer[0:8]), binary.BigEndian.Uint64(footer[8:16])
+	if offset < uint64(h.dataStart) || offset >= indexEnd || length != indexEnd-offset {
+		return nil, fmt.Errorf("%w: geçersiz index konumu", ErrCorrupt)
+	}
+	indexOffset, indexLength := int64(offset), int64(length)
+
+	// Bozuk veya kasıtlı hazırlanmış bir chunk sınırsız açılmasın
+	if r.dec, err = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(2*maxChunkBytes), zstd.WithDecoderConcurrency(1)); err != nil {
+		return nil, err
+	}
+	indexJSON, err := r.readIndex(h, indexOffset, indexLength)
+	if err != nil {
+		r.dec.Close()
+		return nil, fmt.Errorf("index okunamadı: %w", err)
+	}
+	if err := json.Unmarshal(indexJSON, &r.index); err != nil {
+		r.dec.Close()
+		return nil, fmt.Errorf("%w: index çözülemedi", ErrCorrupt)
+	}
+	for _, ci := range r.index.Chunks {
+		if ci.Length <= 0 || ci.Offset < h.dataStart || ci.Offset > indexOffset-ci.Length {
+			r.dec.Close()
+			return nil, fmt.Errorf("%w: chunk index dışında", ErrCorrupt)
+		}
+		if r.checksummed() && len(ci.Sum) != 2*sha256.Size {
+			r.dec.Close()
+			return nil, fmt.Errorf("%w: chunk özeti eksik", ErrCorrupt)
+		}
+	}
+	return r, nil
+}
+
+// checksummed chunk'ların index'teki SHA-256 özetleriyle doğrulanıp doğrulanmadığını döner
+// (şifresiz arşivler; şifreli arşivlerde AES-GCM zaten doğrular).
+func (r *Reader) checksummed() bool {
+	return r.aead == nil
+}
+
+// readIndex index'i okur ve header'a ve kendi konumuna bağlı olduğunu doğrular.
+func (r *Reader) readIndex(h header, offset, length int64) ([]byte, error) {
+	ad := indexAD(h.raw, offset)
+	if r.aead != nil {
+		return r.readBlock(offset, length, ad, "")
+	}
+
+	data, err := r.readAt(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < sumSize {
+		return nil, fmt.Errorf("%w: index özeti eksik", ErrCorrupt)
+	}
+	frame, sum := data[:len(data)-sumSize], data[len(data)-sumSize:]
+	if !bytes.Equal(sum, indexSum(ad, frame)) {
+		return nil, fmt.Errorf("%w: index özeti uyuşmuyor", ErrCorrupt)
+	}
+	return r.decode(frame)
+}
+
+// readAt dosyadan tam olarak length byte okur. Uzunluklar newReader'da dosya sınırlarına
+// göre doğrulanmıştır; buradaki kontrol yine de negatif uzunlukla bellek ayrılmasını önler.
+func (r *Reader) readAt(offset, length int64) ([]byte, error) {
+	if offset < 0 || length <= 0 {
+		return nil, ErrCorrupt
+	}
+	data := make([]byte, length)
+	if _, err := r.f.ReadAt(data, offset); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: beklenmeyen dosya sonu", ErrCorrupt)
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// readBlock bir chunk'ı veya index'i okur, şifresini çözer ve açar. sum boş değilse
+// okunan byte'lar bu SHA-256 özetiyle karşılaştırılır.
+func (r *Reader) readBlock(offset, length int64, aad []byte, sum string) ([]byte, error) {
+	data, err := r.readAt(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	if sum != "" {
+		got := sha256.Sum256(data)
+		if hex.EncodeToString(got[:]) != sum {
+			return nil, fmt.Errorf("%w: özet uyuşmuyor", ErrCorrupt)
+		}
+	}
+	if r.aead != nil {
+		ns := r.aead.NonceSize()
+		if len(data) < ns {
+			return nil, ErrCorrupt
+		}
+		data, err = r.aead.Open(nil, data[:ns], data[ns:], aad)
+		if err != nil {
+			return nil, fmt.Errorf("şifre çözülemedi (yanlış şifre veya bozuk veri): %w", err)
+		}
+	}
+	return r.decode(data)
+}
+
+func (r *Reader) decode(data []byte) ([]byte, error) {
+	out, err := r.dec.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: zstd açılamadı: %v", ErrCorrupt, err)
+	}
+	return out, nil
+}
+
+// Index arşivin chunk tablosunu döner.
+func (r *Reader) Index() Index {
+	return r.index
+}
+
+// ForEach [from, to] aralığıyla kesişen chunk'lardaki satırları sırayla fn'e verir.
+// Sıfır zaman sınırsızdır. Aralık filtresi chunk düzeyindedir; satır düzeyinde
+// kesin filtre çağıranın sorumluluğundadır. fn ErrStop dönerse okuma hatasız durur.
+func (r *Reader) ForEach(from, to time.Time, fn func(line []byte) error) error {
+	for seq, ci := range r.index.Chunks {
+		if !ci.overlaps(from, to) {
+			continue
+		}
+		sum := ""
+		if r.checksummed() {
+			sum = ci.Sum
+		}
+		data, err := r.readBlock(ci.Offset, ci.Length, chunkAAD(seq), sum)
+		if err != nil {
+			return fmt.Errorf("chunk %d: %w", seq, err)
+		}
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				i = len(data)
+			}
+			if line := data[:i]; len(line) > 0 {
+				if err := fn(line); err != nil {
+					if errors.Is(err, ErrStop) {
+						return nil
+					}
+					return err
+				}
+			}
+			if i == len(data) {
+				break
+			}
+			data = data[i+1:]
+		}
+	}
+	return nil
+}
+
+// WriteTo tüm event'leri NDJSON olarak w'ye yazar.
+func (r *Reader) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	err := r.ForEach(time.Time{}, time.Time{}, func(line []byte) error {
+		m, err := w.Write(line)
+		n += int64(m)
+		if err != nil {
+			return err
+		}
+		m, err = w.Write([]byte{'\n'})
+		n += int64(m)
+		return err
+	})
+	return n, err
+}
+
+func (r *Reader) Close() error {
+	r.dec.Close()
+	return r.f.Close()
+}
+
+// Verify arşivin tüm chunk'larını okuyup şifre/bütünlük doğrulaması yapar ve event sayısını döner.
+func Verify(path string, creds Credentials) (int, error) {
+	r, err := Open(path, creds)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	count := 0
+	err = r.ForEach(time.Time{}, time.Time{}, func([]byte) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	if count != r.index.Events {
+		return count, fmt.Errorf("%w: index %d event diyor, okunan %d", ErrCorrupt, r.index.Events, count)
+	}
+	return count, nil
+}
+
+// WriteLegacyZip arşivi eski istemcilerin beklediği, outPassword ile şifreli tek entry'li zip
+// olarak w'ye yazar. zip_password ile şifrelenmiş .zip arşivler olduğu gibi kopyalanır; veri
+// anahtarıyla şifrelenmiş .zip'ler ve .lsa arşivler akış halinde dönüştürülür.
+func WriteLegacyZip(w io.Writer, path string, creds Credentials, outPassword string) error {
+	if !IsLSA(path) {
+		keyID, err := KeyID(path)
+		if err != nil {
+			return err
+		}
+		if keyID == "" {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(w, f)
+			return err
+		}
+	}
+
+	zw := yzip.NewWriter(w)
+	var entry io.Writer
+	var err error
+	if outPassword != "" {
+		entry, err = zw.Encrypt(EntryName(path), outPassword, yzip.AES256Encryption)
+	} else {
+		entry, err = zw.CreateHeader(&yzip.FileHeader{Name: EntryName(path), Method: yzip.Deflate})
+	}
+	if err != nil {
+		return fmt.Errorf("zip entry oluşturulamadı: %w", err)
+	}
+
+	bw := bufio.NewWriterSize(entry, 64*1024)
+	err = ForEachLine(path, creds, time.Time{}, time.Time{}, func(line []byte) error {
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ForEachLine .zip veya .lsa arşivdeki event satırlarını fn'e verir. .lsa için yalnızca
+// [from, to] ile kesişen chunk'lar okunur; .zip arşivlerin tamamı okunur.
+func ForEachLine(path string, creds Credentials, from, to time.Time, fn func(line []byte) error) error {
+	if IsLSA(path) {
+		r, err := Open(path, creds)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return r.ForEach(from, to, fn)
+	}
+
+	zr, err := yzip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if err := OpenZipEntry(f, creds); err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%s açılamadı: %w", f.Name, err)
+		}
+		scanner := bufio.NewScanner(rc)
+		scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			if err := fn(line); err != nil {
+				rc.Close()
+				if errors.Is(err, ErrStop) {
+					return nil
+				}
+				return err
+			}
+		}
+		err = scanner.Err()
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
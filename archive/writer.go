@@ -0,0 +1,251 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Options yeni arşiv yazarken kullanılan ayarlar.
+type Options struct {
+	Password    string // Boşsa ve Key verilmemişse chunk'lar şifrelenmez
+	KeyID       string // Key verildiğinde header'a yazılan veri anahtarı id'si
+	Key         []byte // Veri anahtarı; verilirse Password yerine kullanılır
+	ChunkEvents int    // 0 ise DefaultChunkEvents
+	// TimeOf bir event satırının zamanını döner; index'teki zaman aralıkları buradan hesaplanır.
+	TimeOf func(line []byte) (time.Time, bool)
+}
+
+// Writer NDJSON satırlarını alıp chunk'lara bölerek .lsa formatında yazar.
+// Write'a satırlar parça parça verilebilir; satır sonları Writer tarafından bulunur.
+type Writer struct {
+	w      io.Writer
+	opts   Options
+	aead   cipher.AEAD
+	enc    *zstd.Encoder
+	offset int64
+	header []byte // Yazılan header (key id dahil); index'in ek verisine girer
+
+	pending []byte       // Henüz tamamlanmamış satır
+	chunk   bytes.Buffer // Sıkıştırılmayı bekleyen satırlar
+	cur     ChunkInfo
+	index   Index
+	closed  bool
+}
+
+// NewWriter header'ı yazar ve yeni bir Writer döner. Close çağrılmadan arşiv geçerli değildir.
+func NewWriter(w io.Writer, opts Options) (*Writer, error) {
+	if opts.ChunkEvents <= 0 {
+		opts.ChunkEvents = DefaultChunkEvents
+	}
+
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	aw := &Writer{w: w, opts: opts, enc: enc, index: Index{Chunks: []ChunkInfo{}}}
+
+	header := make([]byte, headerSize)
+	copy(header, headerMagic)
+	header[4] = version
+	if len(opts.Key) > 0 {
+		if opts.KeyID == "" || len(opts.KeyID) > maxKeyIDLen {
+			return nil, fmt.Errorf("geçersiz key id: %q", opts.KeyID)
+		}
+		header[5] |= flagEncrypted | flagKeyID
+		salt := header[8 : 8+saltSize]
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		if aw.aead, err = newAEAD(fileKey(opts.Key, salt)); err != nil {
+			return nil, err
+		}
+		header = append(header, byte(len(opts.KeyID)))
+		header = append(header, opts.KeyID...)
+	} else if opts.Password != "" {
+		header[5] |= flagEncrypted
+		salt := header[8 : 8+saltSize]
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		key, err := deriveKey(opts.Password, salt)
+		if err != nil {
+			return nil, err
+		}
+		if aw.aead, err = newAEAD(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := aw.write(header); err != nil {
+		return nil, err
+	}
+	aw.header = header
+	return aw, nil
+}
+
+func (aw *Writer) write(p []byte) error {
+	n, err := aw.w.Write(p)
+	aw.offset += int64(n)
+	return err
+}
+
+// Write p'deki tamamlanmış satırları chunk'a ekler.
+func (aw *Writer) Write(p []byte) (int, error) {
+	if aw.closed {
+		return 0, fmt.Errorf("lsa writer kapalı")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			aw.pending = append(aw.pending, p...)
+			break
+		}
+		line := p[:i]
+		if len(aw.pending) > 0 {
+			aw.pending = append(aw.pending, line...)
+			line = aw.pending
+		}
+		if err := aw.addLine(line); err != nil {
+			return 0, err
+		}
+		aw.pending = aw.pending[:0]
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+func (aw *Writer) addLine(line []byte) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	if len(line) > maxChunkBytes {
+		// Okuyucu chunk başına en fazla 2*maxChunkBytes açar
+		return fmt.Errorf("event çok büyük (%d byte, en fazla %d)", len(line), maxChunkBytes)
+	}
+
+	aw.chunk.Write(line)
+	aw.chunk.WriteByte('\n')
+	aw.cur.Events++
+
+	var t time.Time
+	ok := false
+	if aw.opts.TimeOf != nil {
+		t, ok = aw.opts.TimeOf(line)
+	}
+	if !ok {
+		aw.cur.Untimed++
+	} else {
+		ms := t.UnixMilli()
+		if aw.cur.Events-aw.cur.Untimed == 1 || ms < aw.cur.MinTime {
+			aw.cur.MinTime = ms
+		}
+		if aw.cur.Events-aw.cur.Untimed == 1 || ms > aw.cur.MaxTime {
+			aw.cur.MaxTime = ms
+		}
+	}
+
+	if aw.cur.Events >= aw.opts.ChunkEvents || aw.chunk.Len() >= maxChunkBytes {
+		return aw.flushChunk()
+	}
+	return nil
+}
+
+// seal veriyi sıkıştırır ve (şifreliyse) nonce | ciphertext olarak döner.
+func (aw *Writer) seal(plain, aad []byte) ([]byte, error) {
+	frame := aw.enc.EncodeAll(plain, nil)
+	if aw.aead == nil {
+		return frame, nil
+	}
+	nonce := make([]byte, aw.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aw.aead.Seal(nonce, nonce, frame, aad), nil
+}
+
+func (aw *Writer) flushChunk() error {
+	if aw.cur.Events == 0 {
+		return nil
+	}
+
+	data, err := aw.seal(aw.chunk.Bytes(), chunkAAD(len(aw.index.Chunks)))
+	if err != nil {
+		return err
+	}
+
+	aw.cur.Offset = aw.offset
+	aw.cur.Length = int64(len(data))
+	if aw.aead == nil {
+		sum := sha256.Sum256(data)
+		aw.cur.Sum = hex.EncodeToString(sum[:])
+	}
+	if err := aw.write(data); err != nil {
+		return err
+	}
+
+	aw.index.Events += aw.cur.Events
+	aw.index.Chunks = append(aw.index.Chunks, aw.cur)
+	aw.cur = ChunkInfo{}
+	aw.chunk.Reset()
+	return nil
+}
+
+// Events şu ana kadar yazılan event sayısını döner.
+func (aw *Writer) Events() int {
+	return aw.index.Events + aw.cur.Events
+}
+
+// Close kalan satırları yazar, index'i ve footer'ı ekler. Alttaki writer'ı kapatmaz.
+func (aw *Writer) Close() error {
+	if aw.closed {
+		return nil
+	}
+	defer aw.enc.Close()
+
+	if len(aw.pending) > 0 {
+		if err := aw.addLine(aw.pending); err != nil {
+			return err
+		}
+		aw.pending = nil
+	}
+	if err := aw.flushChunk(); err != nil {
+		return err
+	}
+	aw.closed = true
+
+	indexJSON, err := json.Marshal(aw.index)
+	if err != nil {
+		return err
+	}
+	// Index header'a ve kendi konumuna bağlanır; şifresizse sonuna özet eklenir
+	ad := indexAD(aw.header, aw.offset)
+	data, err := aw.seal(indexJSON, ad)
+	if err != nil {
+		return err
+	}
+	if aw.aead == nil {
+		data = append(data, indexSum(ad, data)...)
+	}
+
+	footer := make([]byte, footerSize)
+	binary.BigEndian.PutUint64(footer[0:8], uint64(aw.offset))
+	binary.BigEndian.PutUint64(footer[8:16], uint64(len(data)))
+	copy(footer[16:], footerMagic)
+
+	if err := aw.write(data); err != nil {
+		return err
+	}
+	return aw.write(footer)
+}
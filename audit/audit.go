@@ -0,0 +1,256 @@
+// Package audit veri erişimlerini (log indirme, arama, restore ve yönetim işlemleri) hash
+// zincirli, yalnızca sona eklenen bir dosyaya kaydeder.
+//
+// Her satır sıra numarasını, bir önceki satırın hash'ini ve kendi hash'ini taşır:
+//
+//	hash = hex(sha256(seq + "\n" + prev + "\n" + event))
+//	hash = hex(hmac-sha256(audit.hmac_key, seq + "\n" + prev + "\n" + event))  (keyed: true)
+//
+// Araya kayıt eklemek, kayıt silmek veya bir kaydı değiştirmek zinciri bozar ve Verify ile
+// tespit edilir. Anahtarsız zincir yalnızca kazara bozulmaya karşı korur: dosyaya yazabilen
+// biri zinciri baştan yazıp tüm hash'leri yeniden hesaplayabilir. Buna karşı audit.hmac_key
+// verilmeli (anahtar audit dosyasından ayrı tutulmalı) ve/veya Verify'ın döndüğü son hash
+// düzenli olarak dışarıda saklanıp anchor olarak kontrol edilmelidir; sondan kesilme yalnızca
+// anchor ile tespit edilir. Dosyaya birden fazla instance yazabilir; her ekleme küme kilidi
+// altında yapılır.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log-server/config"
+	"log-server/lock"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kaydedilen işlemler
+const (
+	ActionHomeLogs = "home_logs" // GET /home-logs
+	ActionAllLogs  = "all_logs"  // GET /all-logs
+	ActionSearch   = "search"    // GET /home-logs/search
+	ActionRestore  = "restore"   // logctl restore
+	ActionCat      = "cat"       // logctl cat
+	ActionAdmin    = "admin"     // /admin ve ev silme
+)
+
+const (
+	auditLockName = "audit"
+	tailChunk     = 64 * 1024
+	maxLineSize   = 16 * 1024 * 1024
+)
+
+// genesis ilk kaydın prev değeri.
+var genesis = strings.Repeat("0", 64)
+
+var ErrChainBroken = errors.New("denetim kaydı zinciri bozuk")
+
+// Event tek bir erişim.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Action     string    `json:"action"`
+	Actor      string    `json:"actor"` // Kimlik id'si (ak_..., dev_..., jwt:..., cert:..., legacy, logctl:<kullanıcı>)
+	ActorName  string    `json:"actor_name,omitempty"`
+	AuthMethod string    `json:"auth_method,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	Status     int       `json:"status,omitempty"`
+	HomeIds    []string  `json:"home_ids,omitempty"`
+	From       string    `json:"from,omitempty"` // İstenen aralık, istekteki biçimde
+	To         string    `json:"to,omitempty"`
+	Bytes      int64     `json:"bytes"` // Gönderilen veya yazılan byte
+	Error      string    `json:"error,omitempty"`
+}
+
+// Entry dosyadaki bir satır. Event, hash'lendiği haliyle (byte byte) saklanır.
+type Entry struct {
+	Seq   uint64          `json:"seq"`
+	Prev  string          `json:"prev"`
+	Hash  string          `json:"hash"`
+	Keyed bool            `json:"keyed,omitempty"` // Hash audit.hmac_key ile HMAC
+	Event json.RawMessage `json:"event"`
+}
+
+// entryHash key verilmişse HMAC-SHA256, değilse SHA-256 hesaplar.
+func entryHash(key []byte, seq uint64, prev string, event []byte) string {
+	var h hash.Hash
+	if key != nil {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	fmt.Fprintf(h, "%d\n%s\n", seq, prev)
+	h.Write(event)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Key config'deki zincir anahtarını döner; verilmemişse nil.
+func Key() []byte {
+	if k := config.Get().Audit.HMACKey; k != "" {
+		return []byte(k)
+	}
+	return nil
+}
+
+// Path config'deki denetim dosyasının yolunu döner (varsayılan: backup_dir/audit.ndjson).
+func Path() string {
+	cfg := config.Get()
+	if cfg.Audit.File != "" {
+		return cfg.Audit.File
+	}
+	return filepath.Join(cfg.KettasLog.Backup.BackupDir, "audit.ndjson")
+}
+
+// chain son yazılan kaydı hatırlar; dosya başka bir instance tarafından büyütülmüşse
+// son satır yeniden okunur.
+var chain struct {
+	mu   sync.Mutex
+	path string
+	size int64
+	seq  uint64
+	last string
+}
+
+// Record olayı zincirin sonuna ekler ve diske yazılmasını (fsync) bekler.
+func Record(ev Event) error {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	ev.Time = ev.Time.UTC()
+	event, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	release, err := lock.Get().Lock(ctx, auditLockName)
+	if err != nil {
+		return fmt.Errorf("denetim kilidi alınamadı: %w", err)
+	}
+	defer release()
+
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("denetim dizini oluşturulamadı: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("denetim dosyası açılamadı: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if path != chain.path || size != chain.size {
+		last, valid, err := lastEntry(f, size)
+		if err != nil {
+			return fmt.Errorf("denetim dosyasının sonu okunamadı: %w", err)
+		}
+		if valid < size {
+			// Yazma sırasında çökme: yarım kalan satır hiçbir zaman zincire girmedi, kesilir
+			if err := f.Truncate(valid); err != nil {
+				return fmt.Errorf("denetim dosyasının yarım satırı kesilemedi: %w", err)
+			}
+			slog.Warn("Denetim dosyasının sonundaki yarım satır kesildi", "path", path, "bytes", size-valid)
+			size = valid
+		}
+		chain.path, chain.seq, chain.last = path, last.Seq, last.Hash
+		if last.Hash == "" {
+			chain.last = genesis
+		}
+	}
+
+	key := Key()
+	entry := Entry{Seq: chain.seq + 1, Prev: chain.last, Keyed: key != nil, Event: event}
+	entry.Hash = entryHash(key, entry.Seq, entry.Prev, event)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("denetim kaydı yazılamadı: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("denetim kaydı diske yazılamadı: %w", err)
+	}
+
+	chain.seq, chain.last = entry.Seq, entry.Hash
+	chain.size = size + int64(len(line))
+	return nil
+}
+
+// lastEntry dosyanın son tam satırını ve geçerli boyutunu (son satır sonuna kadar) döner.
+// Dosya yeni satırla bitmiyorsa (yazma sırasında çökme) geçerli boyut dosya boyutundan küçüktür.
+func lastEntry(f *os.File, size int64) (Entry, int64, error) {
+	var buf []byte
+	valid := int64(-1)
+	for pos := size; ; {
+		if end := bytes.LastIndexByte(buf, '\n'); end >= 0 {
+			if valid < 0 {
+				valid = pos + int64(end) + 1
+			}
+			if start := bytes.LastIndexByte(buf[:end], '\n'); start >= 0 || pos == 0 {
+				var e Entry
+				if err := json.Unmarshal(buf[start+1:end], &e); err != nil {
+					return Entry{}, valid, fmt.Errorf("%w: son kayıt çözülemedi", ErrChainBroken)
+				}
+				return e, valid, nil
+			}
+		}
+		if pos == 0 {
+			// Hiç tam satır yok
+			return Entry{}, max(valid, 0), nil
+		}
+		if len(buf) > maxLineSize {
+			return Entry{}, valid, fmt.Errorf("%w: son satır çok uzun", ErrChainBroken)
+		}
+		n := min(int64(tailChunk), pos)
+		pos -= n
+		chunk := make([]byte, n)
+		if _, err := f.ReadAt(chunk, pos); err != nil {
+			return Entry{}, valid, err
+		}
+		buf = append(chunk, buf...)
+	}
+}
+
+// Anchor dışarıda saklanan bir zincir noktası ("seq:hash").
+type Anchor struct {
+	Seq  uint64
+	Hash string
+}
+
+// ParseAnchor "seq:hash" biçimindeki anchor'ı ayrıştırır.
+func ParseAnchor(s string) (Anchor, error) {
+	seqStr, hash, ok := strings.Cut(s, ":")
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if !ok || err != nil || len(hash) != 64 {
+		return Anchor{}, fmt.Errorf("anchor seq:hash biçiminde olmalı: %q", s)
+	}
+	return Anchor{Seq: seq, Hash: strings.ToLower(hash)}, nil
+}
+
+func (a Anchor) String() string {
+	return fmt.Sprintf("%d:%s", a.Seq, a.Hash)
+}
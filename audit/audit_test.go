@@ -0,0 +1,247 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log-server/config"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const testKey = "0123456789abcdef-audit"
+
+// loadConfig denetim dosyası dir içinde olan geçerli bir config yükler; key boş değilse
+// audit.hmac_key olarak verilir.
+func loadConfig(t *testing.T, dir, key string) string {
+	t.Helper()
+	file := filepath.Join(dir, "audit.ndjson")
+	yaml := fmt.Sprintf("internal_log: {log_file: %q}\nkettas_log: {zip_password: test, backup: {backup_dir: %q}}\naudit: {file: %q, hmac_key: %q}\n",
+		filepath.Join(dir, "app.log"), filepath.Join(dir, "backups"), file, key)
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// record n olay yazar ve denetim dosyasının satırlarını döner.
+func record(t *testing.T, file string, n int) [][]byte {
+	t.Helper()
+	for i := range n {
+		if err := Record(Event{Action: ActionHomeLogs, Actor: fmt.Sprintf("ak_%d", i), HomeIds: []string{"home-1"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return lines(t, file)
+}
+
+func lines(t *testing.T, file string) [][]byte {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := bytes.SplitAfter(data, []byte("\n"))
+	return l[:len(l)-1]
+}
+
+func writeLines(t *testing.T, file string, lines [][]byte) {
+	t.Helper()
+	if err := os.WriteFile(file, bytes.Join(lines, nil), 0o640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		wantKeyed uint64
+	}{
+		{"anahtarsız", "", 0},
+		{"anahtarlı", testKey, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := loadConfig(t, t.TempDir(), tt.key)
+			if got := record(t, file, 3); len(got) != 3 {
+				t.Fatalf("%d satır yazıldı, 3 bekleniyordu", len(got))
+			}
+
+			res, err := Verify(file, Key())
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if res.Entries != 3 || res.Keyed != tt.wantKeyed || len(res.Skipped) != 0 {
+				t.Errorf("Verify = %+v", res)
+			}
+			head, err := ParseAnchor(res.Head)
+			if err != nil || head.Seq != 3 {
+				t.Fatalf("Head = %q, %v", res.Head, err)
+			}
+			if _, err := Verify(file, Key(), head); err != nil {
+				t.Errorf("Head anchor ile Verify: %v", err)
+			}
+
+			records, err := Read(Query{Actor: "ak_1"})
+			if err != nil || len(records) != 1 || records[0].Seq != 2 {
+				t.Errorf("Read = %+v, %v", records, err)
+			}
+		})
+	}
+}
+
+func TestRecordTruncatesTornTail(t *testing.T) {
+	tests := []struct {
+		name string
+		tail string
+	}{
+		{"yarım kayıt", `{"seq":3,"prev":"ab`},
+		{"yeni satırsız tam kayıt", `{"seq":3,"prev":"x","hash":"y","event":{}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := loadConfig(t, t.TempDir(), testKey)
+			before := record(t, file, 2)
+
+			f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString(tt.tail)
+			f.Close()
+
+			after := record(t, file, 1)
+			if len(after) != 3 || !bytes.Equal(bytes.Join(after[:2], nil), bytes.Join(before, nil)) {
+				t.Fatalf("yarım satır kesilmedi:\n%s", bytes.Join(after, nil))
+			}
+			res, err := Verify(file, Key())
+			if err != nil || res.Entries != 3 || len(res.Skipped) != 0 {
+				t.Errorf("Verify = %+v, %v", res, err)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		edit        func(lines [][]byte) [][]byte
+		verifyKey   []byte
+		anchors     func(res VerifyResult) []Anchor
+		wantErr     bool
+		wantSkipped []int
+	}{
+		{name: "değişmemiş", key: testKey, verifyKey: []byte(testKey)},
+		{name: "çözülemeyen satır atlanır", key: testKey, verifyKey: []byte(testKey),
+			edit: func(l [][]byte) [][]byte {
+				return slices.Insert(l, 1, []byte("{bozuk\n"))
+			}, wantSkipped: []int{2}},
+		{name: "değiştirilmiş olay", edit: func(l [][]byte) [][]byte {
+			l[1] = bytes.Replace(l[1], []byte("ak_1"), []byte("ak_9"), 1)
+			return l
+		}, wantErr: true},
+		{name: "silinmiş kayıt", edit: func(l [][]byte) [][]byte {
+			return slices.Delete(l, 1, 2)
+		}, wantErr: true},
+		{name: "yer değiştirmiş kayıtlar", edit: func(l [][]byte) [][]byte {
+			l[0], l[1] = l[1], l[0]
+			return l
+		}, wantErr: true},
+		{name: "yanlış anahtar", key: testKey, verifyKey: []byte("another-key-0123456"), wantErr: true},
+		{name: "anahtarlı kayıt, anahtar yok", key: testKey, wantErr: true},
+		{name: "anahtarsız zincir anahtarla doğrulanır", verifyKey: []byte(testKey)},
+		{name: "anchor eşleşiyor",
+			anchors: func(res VerifyResult) []Anchor {
+				a, _ := ParseAnchor(res.Head)
+				return []Anchor{a}
+			}},
+		{name: "sondan kesilme anchor ile tespit edilir",
+			edit: func(l [][]byte) [][]byte {
+				return l[:2]
+			},
+			anchors: func(res VerifyResult) []Anchor {
+				a, _ := ParseAnchor(res.Head)
+				return []Anchor{a}
+			}, wantErr: true},
+		{name: "yeniden yazılmış zincir anchor ile tespit edilir",
+			anchors: func(res VerifyResult) []Anchor {
+				a, _ := ParseAnchor(res.Head)
+				a.Hash = genesis
+				return []Anchor{a}
+			}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := loadConfig(t, t.TempDir(), tt.key)
+			original := record(t, file, 3)
+
+			var anchors []Anchor
+			if tt.anchors != nil {
+				res, err := Verify(file, Key())
+				if err != nil {
+					t.Fatal(err)
+				}
+				anchors = tt.anchors(res)
+			}
+			if tt.edit != nil {
+				writeLines(t, file, tt.edit(original))
+			}
+
+			res, err := Verify(file, tt.verifyKey, anchors...)
+			if tt.wantErr {
+				if !errors.Is(err, ErrChainBroken) {
+					t.Fatalf("Verify = %+v, %v; ErrChainBroken bekleniyordu", res, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if res.Entries != 3 || !slices.Equal(res.Skipped, tt.wantSkipped) {
+				t.Errorf("Verify = %+v", res)
+			}
+		})
+	}
+}
+
+func TestVerifyUnkeyedAfterKeyed(t *testing.T) {
+	dir := t.TempDir()
+	file := loadConfig(t, dir, testKey)
+	record(t, file, 2)
+	loadConfig(t, dir, "")
+	record(t, file, 1)
+
+	if _, err := Verify(file, []byte(testKey)); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("anahtarlı kayıtlardan sonra anahtarsız kayıt kabul edildi: %v", err)
+	}
+}
+
+func TestParseAnchor(t *testing.T) {
+	hash := genesis[:63] + "A"
+	tests := []struct {
+		in      string
+		want    Anchor
+		wantErr bool
+	}{
+		{"12:" + hash, Anchor{Seq: 12, Hash: genesis[:63] + "a"}, false},
+		{"12", Anchor{}, true},
+		{"x:" + hash, Anchor{}, true},
+		{"12:abc", Anchor{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseAnchor(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseAnchor(%q) = %v, %v", tt.in, got, err)
+			}
+		})
+	}
+}
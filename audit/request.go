@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"errors"
+	"log-server/auth"
+	"log/slog"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const accessKey = "audit.access"
+
+// Access bir HTTP isteğinin henüz yazılmamış denetim kaydı. Handler'lar istekten okudukları
+// evleri ve tarih aralığını ekler; kayıt yanıt (akış dahil) bittiğinde yazılır.
+type Access struct {
+	mu      sync.Mutex
+	ev      Event
+	held    bool
+	written bool
+}
+
+// Begin isteğin kimliğiyle yeni bir kayıt başlatır ve isteğe ekler.
+func Begin(c *fiber.Ctx, action string) *Access {
+	a := &Access{ev: Event{
+		Action: action,
+		IP:     c.IP(),
+		Method: c.Method(),
+		Path:   c.OriginalURL(),
+	}}
+	if p := auth.FromCtx(c); p != nil {
+		a.ev.Actor, a.ev.ActorName, a.ev.AuthMethod = p.ID, p.Name, p.Method
+	}
+	c.Locals(accessKey, a)
+	return a
+}
+
+// FromCtx isteğin kaydını döner; kayıt yoksa nil (metotlar nil'de bir şey yapmaz).
+func FromCtx(c *fiber.Ctx) *Access {
+	a, _ := c.Locals(accessKey).(*Access)
+	return a
+}
+
+// SetHomes erişilen evleri ayarlar.
+func (a *Access) SetHomes(homeIds ...string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.ev.HomeIds = homeIds
+	a.mu.Unlock()
+}
+
+// SetRange istenen tarih aralığını ayarlar.
+func (a *Access) SetRange(from, to string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.ev.From, a.ev.To = from, to
+	a.mu.Unlock()
+}
+
+// Hold kaydı handler döndükten sonra da açık tutar ve akış bittiğinde gönderilen byte
+// sayısıyla çağrılacak fonksiyonu döner. SetBodyStreamWriter kullanan handler'lar içindir.
+func Hold(c *fiber.Ctx) func(bytes int64) {
+	a := FromCtx(c)
+	if a == nil {
+		return func(int64) {}
+	}
+	a.mu.Lock()
+	a.held = true
+	a.mu.Unlock()
+	return func(bytes int64) {
+		a.mu.Lock()
+		a.ev.Bytes = bytes
+		a.mu.Unlock()
+		a.write()
+	}
+}
+
+// End handler döndükten sonra yanıt durumunu kaydeder; kayıt Hold ile tutulmuyorsa yazar.
+func End(c *fiber.Ctx, err error) {
+	a := FromCtx(c)
+	if a == nil {
+		return
+	}
+
+	status := c.Response().StatusCode()
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		status = fe.Code
+	} else if err != nil {
+		status = fiber.StatusInternalServerError
+	}
+
+	a.mu.Lock()
+	a.ev.Status = status
+	if err != nil {
+		a.ev.Error = err.Error()
+	}
+	held := a.held && err == nil
+	if !held {
+		if c.Response().IsBodyStream() {
+			// SendFile: boyut Content-Length'tedir
+			a.ev.Bytes = int64(max(c.Response().Header.ContentLength(), 0))
+		} else {
+			a.ev.Bytes = int64(len(c.Response().Body()))
+		}
+	}
+	a.mu.Unlock()
+
+	if !held {
+		a.write()
+	}
+}
+
+func (a *Access) write() {
+	a.mu.Lock()
+	if a.written {
+		a.mu.Unlock()
+		return
+	}
+	a.written = true
+	ev := a.ev
+	a.mu.Unlock()
+
+	if err := Record(ev); err != nil {
+		// Erişim gerçekleşti; denetim kaydı yazılamadıysa en azından internal log'da kalsın
+		slog.Error("Denetim kaydı yazılamadı", "error", err, "action", ev.Action, "actor", ev.Actor, "path", ev.Path, "home_ids", ev.HomeIds)
+	}
+}
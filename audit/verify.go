@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// VerifyResult doğrulanan zincirin özeti. Head, dışarıda saklanıp sonraki doğrulamalarda
+// anchor olarak kullanılabilir.
+type VerifyResult struct {
+	Path    string `json:"path"`
+	Entries uint64 `json:"entries"`
+	Keyed   uint64 `json:"keyed"`                   // audit.hmac_key ile doğrulanan kayıtlar
+	Skipped []int  `json:"skipped_lines,omitempty"` // Çözülemeyen (çökme sırasında yarım kalmış) satırlar
+	Head    string `json:"head,omitempty"`          // Son kayıt, seq:hash
+}
+
+// Verify dosyadaki tüm satırların sırasını, prev bağlantılarını ve hash'lerini kontrol eder.
+// anchors verilmişse ilgili sıra numaralı kayıtların hash'leri de eşleşmelidir; böylece
+// anchor'dan sonraki bir noktadan kesilme veya dosyanın baştan yeniden yazılması tespit edilir.
+//
+// key verilmişse anahtarlı kayıtların HMAC'i doğrulanır; anahtarlı bir kayıttan sonra anahtarsız
+// kayıt gelmesi hata sayılır. Çözülemeyen satırlar Skipped'da raporlanıp atlanır; sonraki kayıt
+// yine son geçerli kayda bağlı olmalıdır, bu yüzden atlanan satır gerçek bir kaydı gizleyemez.
+func Verify(path string, key []byte, anchors ...Anchor) (VerifyResult, error) {
+	res := VerifyResult{Path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		return res, err
+	}
+	defer f.Close()
+
+	want := make(map[uint64]string, len(anchors))
+	for _, a := range anchors {
+		want[a.Seq] = a.Hash
+	}
+
+	prev := genesis
+	var seq uint64
+	line := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line++
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			res.Skipped = append(res.Skipped, line)
+			continue
+		}
+		if e.Seq != seq+1 {
+			return res, fmt.Errorf("%w: satır %d: sıra %d bekleniyordu, %d bulundu", ErrChainBroken, line, seq+1, e.Seq)
+		}
+		if e.Prev != prev {
+			return res, fmt.Errorf("%w: satır %d (seq %d): önceki kayda bağlı değil", ErrChainBroken, line, e.Seq)
+		}
+		var k []byte
+		switch {
+		case e.Keyed && key == nil:
+			return res, fmt.Errorf("%w: satır %d (seq %d) anahtarlı, doğrulamak için audit.hmac_key gerekli", ErrChainBroken, line, e.Seq)
+		case e.Keyed:
+			k = key
+		case res.Keyed > 0:
+			return res, fmt.Errorf("%w: satır %d (seq %d): anahtarlı kayıtlardan sonra anahtarsız kayıt", ErrChainBroken, line, e.Seq)
+		}
+		if entryHash(k, e.Seq, e.Prev, e.Event) != e.Hash {
+			return res, fmt.Errorf("%w: satır %d (seq %d): hash uyuşmuyor, kayıt değiştirilmiş", ErrChainBroken, line, e.Seq)
+		}
+		if h, ok := want[e.Seq]; ok && h != e.Hash {
+			return res, fmt.Errorf("%w: seq %d anchor ile uyuşmuyor", ErrChainBroken, e.Seq)
+		}
+		seq, prev = e.Seq, e.Hash
+		res.Entries++
+		if e.Keyed {
+			res.Keyed++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return res, err
+	}
+	for s := range want {
+		if s > seq {
+			return res, fmt.Errorf("%w: anchor seq %d dosyada yok (son seq %d), kayıt sonu kesilmiş", ErrChainBroken, s, seq)
+		}
+	}
+
+	if seq > 0 {
+		res.Head = Anchor{Seq: seq, Hash: prev}.String()
+	}
+	return res, nil
+}
+
+// Query okuma filtreleri. Boş alanlar filtre uygulanmaz.
+type Query struct {
+	Since  time.Time
+	Until  time.Time
+	Action string
+	Actor  string
+	HomeId string
+	Limit  int // Verilmişse en yeni Limit kadar kayıt
+}
+
+// Stored dosyadan okunan, çözülmüş bir kayıt.
+type Stored struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+	Event
+}
+
+// Read kayıtları filtreleyerek döner. Zinciri doğrulamaz; bunun için Verify kullanılmalıdır.
+func Read(q Query) ([]Stored, error) {
+	f, err := os.Open(Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Stored{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	records := []Stored{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		rec := Stored{Seq: e.Seq, Hash: e.Hash}
+		if err := json.Unmarshal(e.Event, &rec.Event); err != nil {
+			continue
+		}
+		if !q.Since.IsZero() && rec.Time.Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && rec.Time.After(q.Until) {
+			continue
+		}
+		if q.Action != "" && rec.Action != q.Action {
+			continue
+		}
+		if q.Actor != "" && rec.Actor != q.Actor {
+			continue
+		}
+		if q.HomeId != "" && !slices.Contains(rec.HomeIds, q.HomeId) {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[len(records)-q.Limit:]
+	}
+	return records, nil
+}
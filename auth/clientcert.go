@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/x509"
+	"fmt"
+	"log-server/config"
+	"slices"
+	"strings"
+)
+
+// cnHomeId kuralın home_id'si bu değerse sertifikanın CN'i home_id olarak kullanılır.
+const cnHomeId = "$cn"
+
+func init() {
+	config.RegisterValidator(validateClientCertRules)
+}
+
+// validateClientCertRules server.tls.client_certs kurallarının scope ve home_id'lerini kontrol eder.
+func validateClientCertRules(cfg *config.Config) []config.Problem {
+	var problems []config.Problem
+	for i, rule := range cfg.Server.TLS.ClientCerts {
+		key := fmt.Sprintf("server.tls.client_certs[%d]", i)
+		for _, sc := range rule.Scopes {
+			if !ValidScope(sc) {
+				problems = append(problems, config.Problem{
+					Key:     key + ".scopes",
+					Message: fmt.Sprintf("geçersiz scope %q (geçerli: %s)", sc, strings.Join(Scopes, ", ")),
+				})
+			}
+		}
+		if rule.HomeId != "" && rule.HomeId != cnHomeId && !ValidHomeId(rule.HomeId) {
+			problems = append(problems, config.Problem{Key: key + ".home_id", Message: fmt.Sprintf("geçersiz home_id %q", rule.HomeId)})
+		}
+	}
+	return problems
+}
+
+// ClientCertPrincipal doğrulanmış istemci sertifikasını server.tls.client_certs'teki ilk
+// eşleşen kurala göre kimliğe çevirir. home_id'ye bağlanan sertifikalar cihaz token'ları gibi
+// yalnızca o evin adına işlem yapabilir.
+func ClientCertPrincipal(cert *x509.Certificate) (*Principal, bool) {
+	if cert == nil {
+		return nil, false
+	}
+	cn := cert.Subject.CommonName
+	for _, rule := range config.Get().Server.TLS.ClientCerts {
+		if rule.CommonName != "*" && rule.CommonName != cn {
+			continue
+		}
+		if rule.OrgUnit != "" && !slices.Contains(cert.Subject.OrganizationalUnit, rule.OrgUnit) {
+			continue
+		}
+
+		p := &Principal{
+			ID:     "cert:" + cn,
+			Name:   cert.Subject.String(),
+			Method: MethodClientCert,
+			Scopes: slices.Clone(rule.Scopes),
+		}
+		if rule.HomeId != "" {
+			homeId := rule.HomeId
+			if homeId == cnHomeId {
+				homeId = cn
+			}
+			if !ValidHomeId(homeId) {
+				return nil, false
+			}
+			p.HomeIds = []string{homeId}
+			if len(p.Scopes) == 0 {
+				p.Scopes = []string{ScopeUpload}
+			}
+		}
+		return p, true
+	}
+	return nil, false
+}
@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"fmt"
+	"log-server/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadConfig geçerli bir temel config'e extra'yı (üst seviye YAML anahtarları) ekleyip yükler.
+func loadConfig(t *testing.T, extra string) {
+	t.Helper()
+	dir := t.TempDir()
+	base := fmt.Sprintf("internal_log: {log_file: %q}\nkettas_log: {zip_password: test, backup: {backup_dir: %q}}\n",
+		filepath.Join(dir, "app.log"), filepath.Join(dir, "backups"))
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(base+extra), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+}
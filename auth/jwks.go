@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log-server/config"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultJWKSRefresh = 60 * time.Minute
+	// Bilinmeyen kid geldiğinde (anahtar rotasyonu) JWKS en fazla bu sıklıkla yeniden indirilir
+	jwksMinRefetch = time.Minute
+	jwksTimeout    = 10 * time.Second
+	maxJWKSSize    = 1 << 20
+
+	// İndirme başarısız olursa ilk yeniden deneme gecikmesi
+	jwksRetryMin = 5 * time.Second
+)
+
+// JWK JSON Web Key (RFC 7517); yalnızca imza doğrulama için gereken alanlar.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS anahtar listesi.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type jwksKey struct {
+	kid string
+	alg string // JWK'de belirtilmişse yalnızca bu algoritma kabul edilir
+	pub crypto.PublicKey
+}
+
+// keySet JWKS'i dosyadan veya URL'den yükler ve periyodik olarak yeniler. İndirme kilit
+// dışında yapılır; eşzamanlı istekler aynı indirmeyi bekler.
+type keySet struct {
+	mu        sync.Mutex
+	source    string // Yüklendiği dosya/URL; config değişince yeniden yüklenir
+	keys      []jwksKey
+	loadedAt  time.Time
+	fetchedAt time.Time // Son deneme (başarısız olsa da)
+	failures  int       // Art arda başarısız deneme sayısı
+	group     singleflight.Group
+}
+
+var jwks keySet
+
+// lookup kid'e ait anahtarı döner. kid boşsa ve tek anahtar varsa o kullanılır.
+func (ks *keySet) lookup(cfg config.JWTConfig, kid string) (jwksKey, error) {
+	refresh := defaultJWKSRefresh
+	if cfg.JWKSRefreshMin > 0 {
+		refresh = time.Duration(cfg.JWKSRefreshMin) * time.Minute
+	}
+	source := jwksSource(cfg)
+
+	ks.mu.Lock()
+	fetchedAt := ks.fetchedAt
+	stale := ks.source != source || (time.Since(ks.loadedAt) > refresh && time.Since(fetchedAt) > ks.retryDelay())
+	ks.mu.Unlock()
+	if stale {
+		ks.load(cfg, fetchedAt)
+	}
+
+	ks.mu.Lock()
+	key, ok := ks.find(kid)
+	fetchedAt = ks.fetchedAt
+	ks.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	// Kimlik sağlayıcısı anahtarlarını yenilemiş olabilir
+	if time.Since(fetchedAt) > max(jwksMinRefetch, ks.retryDelay()) {
+		ks.load(cfg, fetchedAt)
+		ks.mu.Lock()
+		key, ok = ks.find(kid)
+		ks.mu.Unlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return jwksKey{}, fmt.Errorf("%w: JWKS'te anahtar yok (kid=%q)", ErrInvalidToken, kid)
+}
+
+// retryDelay başarısız denemelerden sonra bir sonraki denemeye kadar beklenecek süre
+// (5 sn'den başlayıp jwksMinRefetch'e kadar ikiye katlanır). mu tutulurken çağrılmalıdır.
+func (ks *keySet) retryDelay() time.Duration {
+	if ks.failures == 0 {
+		return 0
+	}
+	return min(jwksRetryMin<<min(ks.failures-1, 8), jwksMinRefetch)
+}
+
+// find kid'e ait anahtarı arar. mu tutulurken çağrılmalıdır.
+func (ks *keySet) find(kid string) (jwksKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		return ks.keys[0], true
+	}
+	for _, k := range ks.keys {
+		if k.kid == kid {
+			return k, true
+		}
+	}
+	return jwksKey{}, false
+}
+
+// load JWKS'i okur. Hata durumunda önceki anahtarlar kullanılmaya devam edilir. seen çağıranın
+// gördüğü son deneme zamanıdır; o zamandan beri başka bir istek yüklediyse tekrar indirilmez.
+func (ks *keySet) load(cfg config.JWTConfig, seen time.Time) {
+	source := jwksSource(cfg)
+	ks.group.Do(source, func() (any, error) {
+		ks.mu.Lock()
+		if ks.fetchedAt.After(seen) && ks.source == source {
+			ks.mu.Unlock()
+			return nil, nil
+		}
+		ks.fetchedAt = time.Now()
+		ks.mu.Unlock()
+
+		data, err := readJWKS(cfg)
+		var keys []jwksKey
+		if err == nil {
+			keys, err = parseJWKS(data)
+		}
+
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+		if err != nil {
+			ks.failures++
+			slog.Error("JWKS yüklenemedi, önceki anahtarlar kullanılıyor", "source", source, "error", err, "retry_in", ks.retryDelay().String())
+			if ks.source != source {
+				// Kaynak değiştiyse eski kaynağın anahtarlarına güvenilmez
+				ks.keys = nil
+				ks.source = source
+			}
+			return nil, nil
+		}
+
+		ks.keys = keys
+		ks.source = source
+		ks.loadedAt = time.Now()
+		ks.failures = 0
+		slog.Info("JWKS yüklendi", "source", source, "keys", len(keys))
+		return nil, nil
+	})
+}
+
+func jwksSource(cfg config.JWTConfig) string {
+	if cfg.JWKSFile != "" {
+		return cfg.JWKSFile
+	}
+	return cfg.JWKSUrl
+}
+
+func readJWKS(cfg config.JWTConfig) ([]byte, error) {
+	if cfg.JWKSFile != "" {
+		return os.ReadFile(cfg.JWKSFile)
+	}
+	if cfg.JWKSUrl == "" {
+		return nil, errors.New("jwks_file veya jwks_url gerekli")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwksTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.JWKSUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS isteği başarısız: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+// parseJWKS JWKS JSON'ındaki imza anahtarlarını çözer. Desteklenmeyen anahtarlar atlanır.
+func parseJWKS(data []byte) ([]jwksKey, error) {
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("JWKS çözülemedi: %w", err)
+	}
+
+	var keys []jwksKey
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			slog.Warn("JWKS anahtarı atlandı", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys = append(keys, jwksKey{kid: k.Kid, alg: k.Alg, pub: pub})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS'te kullanılabilir anahtar yok")
+	}
+	return keys, nil
+}
+
+// PublicKey JWK'yi Go public key'ine çevirir (RSA, EC P-256/P-384, Ed25519).
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("geçersiz n: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("geçersiz e")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if pub.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA anahtarı en az 2048 bit olmalı")
+		}
+		return pub, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("desteklenmeyen eğri: %s", k.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("geçersiz x/y")
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("x/y uzunluğu %d byte olmalı", size)
+		}
+		point := append([]byte{4}, append(x, y...)...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("desteklenmeyen eğri: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("geçersiz x")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("desteklenmeyen anahtar türü: %s", k.Kty)
+}
@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log-server/config"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLeeway       = 60 * time.Second
+	defaultRolesClaim   = "roles"
+	defaultHomeIdsClaim = "home_ids"
+	allHomes            = "*"
+)
+
+var ErrInvalidToken = errors.New("geçersiz token")
+
+func init() {
+	config.RegisterValidator(validateRoleScopes)
+}
+
+// validateRoleScopes auth.jwt.role_scopes'taki scope'ların tanımlı olduğunu kontrol eder.
+func validateRoleScopes(cfg *config.Config) []config.Problem {
+	var problems []config.Problem
+	for role, scopes := range cfg.Auth.JWT.RoleScopes {
+		for _, sc := range scopes {
+			if !ValidScope(sc) {
+				problems = append(problems, config.Problem{
+					Key:     "auth.jwt.role_scopes." + role,
+					Message: fmt.Sprintf("geçersiz scope %q (geçerli: %s)", sc, strings.Join(Scopes, ", ")),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// JWTEnabled bearer token doğrulamasının açık olup olmadığını döner.
+func JWTEnabled() bool {
+	return config.Get().Auth.JWT.Enabled
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// VerifyJWT bearer token'ın imzasını JWKS ile ve exp/nbf/iss/aud claim'lerini doğrular,
+// rolleri scope'lara ve home_ids claim'ini evlere çevirerek kimliği döner.
+func VerifyJWT(token string) (*Principal, error) {
+	cfg := config.Get().Auth.JWT
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: üç parçalı olmalı", ErrInvalidToken)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: imza çözülemedi", ErrInvalidToken)
+	}
+
+	key, err := jwks.lookup(cfg, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.alg != "" && key.alg != header.Alg {
+		return nil, fmt.Errorf("%w: anahtar %s için alg %s kabul edilmez", ErrInvalidToken, key.alg, header.Alg)
+	}
+	if err := verifyJWS(header.Alg, key.pub, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := checkClaims(cfg, claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claimsPrincipal(cfg, claims), nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("%w: base64url çözülemedi", ErrInvalidToken)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: JSON çözülemedi", ErrInvalidToken)
+	}
+	return nil
+}
+
+// verifyJWS imzayı algoritmaya göre doğrular. "none" ve HMAC algoritmaları kabul edilmez.
+func verifyJWS(alg string, pub crypto.PublicKey, signed, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512":
+		hash = crypto.SHA512
+	case "EdDSA":
+	default:
+		return fmt.Errorf("%w: desteklenmeyen alg %q", ErrInvalidToken, alg)
+	}
+
+	var ok bool
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		ok = strings.HasPrefix(alg, "RS") && rsa.VerifyPKCS1v15(k, hash, digest(hash, signed), sig) == nil
+	case *ecdsa.PublicKey:
+		// Eğri algoritmayla eşleşmeli: ES256 ↔ P-256, ES384 ↔ P-384
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if alg == ecdsaAlg(k.Curve) && len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			ok = ecdsa.Verify(k, digest(hash, signed), r, s)
+		}
+	case ed25519.PublicKey:
+		ok = alg == "EdDSA" && ed25519.Verify(k, signed, sig)
+	}
+	if !ok {
+		return fmt.Errorf("%w: imza doğrulanamadı", ErrInvalidToken)
+	}
+	return nil
+}
+
+// ecdsaAlg eğrinin JWS algoritmasını döner (JWK'de alg yoksa token'ın alg'ı buna göre kontrol edilir).
+func ecdsaAlg(curve elliptic.Curve) string {
+	switch curve {
+	case elliptic.P256():
+		return "ES256"
+	case elliptic.P384():
+		return "ES384"
+	}
+	return ""
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	switch hash {
+	case crypto.SHA384:
+		sum := sha512.Sum384(data)
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// checkClaims exp (zorunlu), nbf, iss ve aud claim'lerini kontrol eder.
+func checkClaims(cfg config.JWTConfig, claims map[string]any, now time.Time) error {
+	leeway := defaultLeeway
+	if cfg.LeewaySec > 0 {
+		leeway = time.Duration(cfg.LeewaySec) * time.Second
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: exp claim'i gerekli", ErrInvalidToken)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return fmt.Errorf("%w: süresi dolmuş", ErrInvalidToken)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(leeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: henüz geçerli değil", ErrInvalidToken)
+	}
+	if claims["iss"] != cfg.Issuer {
+		return fmt.Errorf("%w: beklenmeyen iss", ErrInvalidToken)
+	}
+	if !slices.Contains(claimStrings(claims["aud"]), cfg.Audience) {
+		return fmt.Errorf("%w: beklenmeyen aud", ErrInvalidToken)
+	}
+	return nil
+}
+
+// claimsPrincipal claim'lerden kimlik oluşturur. home_ids claim'i olmayan kullanıcılar
+// read:all veya admin yetkisi yoksa hiçbir eve erişemez (read:home ve upload düşürülür).
+// auth.rbac açıksa okunabilecek evleri roller belirlediği için read:home korunur.
+func claimsPrincipal(cfg config.JWTConfig, claims map[string]any) *Principal {
+	sub, _ := claims["sub"].(string)
+	nameClaim := cfg.NameClaim
+	if nameClaim == "" {
+		nameClaim = "email"
+	}
+	name, _ := claimPath(claims, nameClaim).(string)
+	if name == "" {
+		name = sub
+	}
+
+	rolesClaim := cfg.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = defaultRolesClaim
+	}
+	roles := claimStrings(claimPath(claims, rolesClaim))
+
+	// viper map anahtarlarını küçük harfe çevirir; roller büyük/küçük harf duyarsız eşlenir
+	var scopes []string
+	for _, role := range roles {
+		scopes = append(scopes, cfg.RoleScopes[strings.ToLower(role)]...)
+	}
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	p := &Principal{
+		ID:     "jwt:" + sub,
+		Name:   name,
+		Method: MethodJWT,
+		Roles:  roles,
+		Scopes: scopes,
+	}
+
+	homesClaim := cfg.HomeIdsClaim
+	if homesClaim == "" {
+		homesClaim = defaultHomeIdsClaim
+	}
+	homes := claimStrings(claimPath(claims, homesClaim))
+	switch {
+	case slices.Contains(homes, allHomes):
+	case len(homes) > 0:
+		p.HomeIds = homes
+	case !p.HasScope(ScopeReadAll):
+		rbac := RBACEnabled()
+		p.Scopes = slices.DeleteFunc(p.Scopes, func(s string) bool { return s == ScopeUpload || (s == ScopeReadHome && !rbac) })
+	}
+	return p
+}
+
+// claimPath noktalı yoldaki claim'i döner (ör: realm_access.roles).
+func claimPath(claims map[string]any, path string) any {
+	var v any = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+// claimStrings string veya string dizisi claim'ini dilime çevirir.
+func claimStrings(v any) []string {
+	switch t := v.(type) {
+	case string:
+		if t == "" {
+			return nil
+		}
+		return []string{t}
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
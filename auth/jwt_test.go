@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log-server/config"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testKeys struct {
+	rsa   *rsa.PrivateKey
+	ec256 *ecdsa.PrivateKey
+	ec384 *ecdsa.PrivateKey
+	ed    ed25519.PrivateKey
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// writeJWKS test anahtarlarını JWKS dosyasına yazar. RSA anahtarı alg'a bağlıdır, EC
+// anahtarlarında alg yoktur (eğriden çıkarılır).
+func writeJWKS(t *testing.T, keys testKeys) string {
+	t.Helper()
+	ecJWK := func(kid string, k *ecdsa.PrivateKey) JWK {
+		size := (k.Curve.Params().BitSize + 7) / 8
+		crv := map[int]string{32: "P-256", 48: "P-384"}[size]
+		return JWK{Kty: "EC", Kid: kid, Crv: crv, X: b64(k.X.FillBytes(make([]byte, size))), Y: b64(k.Y.FillBytes(make([]byte, size)))}
+	}
+	set := JWKS{Keys: []JWK{
+		{Kty: "RSA", Kid: "rsa", Use: "sig", Alg: "RS256", N: b64(keys.rsa.N.Bytes()), E: b64(big.NewInt(int64(keys.rsa.E)).Bytes())},
+		ecJWK("ec256", keys.ec256),
+		ecJWK("ec384", keys.ec384),
+		{Kty: "OKP", Kid: "ed", Crv: "Ed25519", X: b64(keys.ed.Public().(ed25519.PublicKey))},
+		{Kty: "RSA", Kid: "enc", Use: "enc", N: b64(keys.rsa.N.Bytes()), E: "AQAB"},
+	}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// sign header ve claim'leri alg ile imzalar.
+func sign(t *testing.T, keys testKeys, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(jwtHeader{Alg: alg, Kid: kid})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+
+	var sig []byte
+	var err error
+	ecSign := func(k *ecdsa.PrivateKey, hash crypto.Hash) {
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest(hash, []byte(signed)))
+		if err == nil {
+			size := (k.Curve.Params().BitSize + 7) / 8
+			sig = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+		}
+	}
+	switch alg {
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, keys.rsa, crypto.SHA256, digest(crypto.SHA256, []byte(signed)))
+	case "RS384":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, keys.rsa, crypto.SHA384, digest(crypto.SHA384, []byte(signed)))
+	case "ES256":
+		ecSign(keys.ec256, crypto.SHA256)
+	case "ES384":
+		ecSign(keys.ec384, crypto.SHA384)
+	case "EdDSA":
+		sig = ed25519.Sign(keys.ed, []byte(signed))
+	case "none":
+	default:
+		t.Fatalf("test alg'ı desteklenmiyor: %s", alg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + b64(sig)
+}
+
+func generateKeys(t *testing.T) testKeys {
+	t.Helper()
+	var keys testKeys
+	var err error
+	if keys.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if keys.ec256, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if keys.ec384, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if _, keys.ed, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestVerifyJWT(t *testing.T) {
+	keys := generateKeys(t)
+	loadConfig(t, fmt.Sprintf(`auth:
+  jwt:
+    enabled: true
+    jwks_file: %q
+    issuer: https://idp.example.com
+    audience: log-server
+    role_scopes:
+      Support: [read:home]
+      uploader: [upload]
+`, writeJWKS(t, keys)))
+
+	now := time.Now()
+	claims := func(edit func(c map[string]any)) map[string]any {
+		c := map[string]any{
+			"sub":      "u1",
+			"email":    "u1@example.com",
+			"iss":      "https://idp.example.com",
+			"aud":      []string{"other", "log-server"},
+			"exp":      now.Add(time.Hour).Unix(),
+			"roles":    []string{"support"},
+			"home_ids": []string{"home-1"},
+		}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+	tampered := func() string {
+		token := sign(t, keys, "ES256", "ec256", claims(nil))
+		parts := strings.Split(token, ".")
+		return parts[0] + "." + b64(mustJSON(t, claims(func(c map[string]any) { c["home_ids"] = "*" }))) + "." + parts[2]
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"RS256", sign(t, keys, "RS256", "rsa", claims(nil)), false},
+		{"ES256", sign(t, keys, "ES256", "ec256", claims(nil)), false},
+		{"ES384", sign(t, keys, "ES384", "ec384", claims(nil)), false},
+		{"EdDSA", sign(t, keys, "EdDSA", "ed", claims(nil)), false},
+		{"nbf leeway içinde", sign(t, keys, "ES256", "ec256", claims(func(c map[string]any) { c["nbf"] = now.Add(30 * time.Second).Unix() })), false},
+		{"exp leeway içinde", sign(t, keys, "ES256", "ec256", claims(func(c map[string]any) { c["exp"] = now.Add(-30 * time.Second).Unix() })), false},
+
+		{"JWK alg'ından farklı alg", sign(t, keys, "RS384", "rsa", claims(nil)), true},
+		{"eğriyle eşleşmeyen alg", sign(t, keys, "ES384", "ec256", claims(nil)), true},
+		{"alg none", sign(t, keys, "none", "ec256", claims(nil)), true},
+		{"HMAC", b64([]byte(`{"alg":"HS256","kid":"ec256"}`)) + "." + b64(mustJSON(t, claims(nil))) + ".c2ln", true},
+		{"bilinmeyen kid", sign(t, keys, "ES256", "yok", claims(nil)), true},
+		{"kid yok, birden çok anahtar", sign(t, keys, "ES256", "", claims(nil)), true},
+		{"şifreleme anahtarı", sign(t, keys, "RS256", "enc", claims(nil)), true},
+		{"değiştirilmiş payload", tampered(), true},
+		{"süresi dolmuş", sign(t, keys, "ES256", "ec256", claims(func(c map[string]any) { c["exp"] = now.Add(-2 * time.Minute).Unix() })), true},
+		{"exp yok", sign(t, keys, "ES256", "ec256", claims(func(c map[string]any) { delete(c, "exp") })), true},
+		{"henüz geçerli değil", sign(t, keys, "ES256", "ec256", claims(func(c map[string]any) { c["nbf"] = now.Add(5 * time.Minute).Unix() })), true},
+		{"yanlış iss", sign(t, keys, "ES256", "ec256", claims(func(c map[string]any) { c["iss"] = "https://evil.example.com" })), true},
+		{"yanlış aud", sign(t, keys, "ES256", "ec256", claims(func(c map[string]any) { c["aud"] = "other" })), true},
+		{"iki parça", "a.b", true},
+		{"bozuk imza", sign(t, keys, "ES256", "ec256", claims(nil)) + "!", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := VerifyJWT(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("VerifyJWT hata = %v, ErrInvalidToken bekleniyordu", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyJWT: %v", err)
+			}
+			if p.ID != "jwt:u1" || p.Name != "u1@example.com" || p.Method != MethodJWT {
+				t.Errorf("kimlik = %+v", p)
+			}
+			if !slices.Equal(p.Scopes, []string{ScopeReadHome}) || !slices.Equal(p.HomeIds, []string{"home-1"}) {
+				t.Errorf("scope/ev = %v, %v", p.Scopes, p.HomeIds)
+			}
+		})
+	}
+}
+
+func TestClaimsPrincipal(t *testing.T) {
+	loadConfig(t, "")
+	cfg := config.JWTConfig{
+		RolesClaim: "realm_access.roles",
+		RoleScopes: map[string][]string{"support": {ScopeReadHome}, "uploader": {ScopeUpload}, "ops": {ScopeReadAll}},
+	}
+
+	tests := []struct {
+		name       string
+		claims     map[string]any
+		wantScopes []string
+		wantHomes  []string
+	}{
+		{"evli", map[string]any{"realm_access": map[string]any{"roles": []any{"Support", "uploader"}}, "home_ids": []any{"h1", "h2"}},
+			[]string{ScopeReadHome, ScopeUpload}, []string{"h1", "h2"}},
+		{"tüm evler", map[string]any{"realm_access": map[string]any{"roles": []any{"support"}}, "home_ids": "*"},
+			[]string{ScopeReadHome}, nil},
+		{"evsiz: ev scope'ları düşer", map[string]any{"realm_access": map[string]any{"roles": []any{"support", "uploader"}}},
+			nil, nil},
+		{"evsiz read:all korunur", map[string]any{"realm_access": map[string]any{"roles": []any{"ops", "support"}}},
+			[]string{ScopeReadAll, ScopeReadHome}, nil},
+		{"bilinmeyen rol", map[string]any{"realm_access": map[string]any{"roles": "guest"}, "home_ids": "h1"},
+			nil, []string{"h1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := claimsPrincipal(cfg, tt.claims)
+			if !slices.Equal(p.Scopes, tt.wantScopes) {
+				t.Errorf("Scopes = %v, %v bekleniyordu", p.Scopes, tt.wantScopes)
+			}
+			if !slices.Equal(p.HomeIds, tt.wantHomes) {
+				t.Errorf("HomeIds = %v, %v bekleniyordu", p.HomeIds, tt.wantHomes)
+			}
+		})
+	}
+}
+
+func TestValidateRoleScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes map[string][]string
+		want   int
+	}{
+		{"geçerli", map[string][]string{"support": {ScopeReadHome}, "admin": {ScopeAdmin, ScopeReadAll}}, 0},
+		{"geçersiz", map[string][]string{"support": {"read:everything", ScopeReadHome}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Auth.JWT.RoleScopes = tt.scopes
+			if got := validateRoleScopes(&cfg); len(got) != tt.want {
+				t.Errorf("validateRoleScopes = %v, %d sorun bekleniyordu", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,541 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log-server/config"
+	"log-server/lock"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	secretPrefix     = "lsk_"
+	devicePrefix     = "lsd_"
+	keyStoreLockName = "api_keys"
+	keyStoreVersion  = 1
+
+	// Başka bir instance'ın yaptığı değişiklikleri (ör: revoke) görmek için dosya en fazla bu sıklıkla kontrol edilir
+	reloadInterval = 5 * time.Second
+)
+
+var (
+	ErrKeyNotFound = errors.New("API anahtarı bulunamadı")
+	ErrInvalidKey  = errors.New("geçersiz API anahtarı")
+	ErrKeyExpired  = errors.New("API anahtarının süresi dolmuş")
+	ErrKeyRevoked  = errors.New("API anahtarı iptal edilmiş")
+)
+
+// Anahtar türleri. Cihaz token'ları tek bir eve bağlıdır ve yalnızca upload yetkisine sahiptir.
+const (
+	KindApiKey = "api_key"
+	KindDevice = "device"
+)
+
+// Anahtar durumları
+const (
+	StatusActive  = "active"
+	StatusExpired = "expired"
+	StatusRevoked = "revoked"
+)
+
+// apiKey depoda saklanan kayıt. Secret'ın kendisi saklanmaz, yalnızca SHA-256 özeti ve şifreli
+// imza anahtarı tutulur.
+type apiKey struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind,omitempty"` // Boşsa api_key
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`                // Secret'ın ilk karakterleri (tanımak için)
+	Hash       string     `json:"hash"`                  // hex(sha256(secret))
+	SigningKey string     `json:"signing_key,omitempty"` // İmza anahtarı, secret anahtarıyla ENC(vN:...); boşsa imza kullanılamaz
+	Scopes     []string   `json:"scopes"`
+	HomeIds    []string   `json:"home_ids,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type keyStoreFile struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Keys      []apiKey  `json:"keys"`
+}
+
+// KeyInfo bir API anahtarının özeti (hash olmadan).
+type KeyInfo struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	HomeIds   []string   `json:"home_ids,omitempty"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateKeyRequest yeni anahtarın özellikleri.
+type CreateKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	HomeIds   []string   `json:"home_ids"`   // Boşsa tüm evler
+	ExpiresAt *time.Time `json:"expires_at"` // Boşsa süresiz
+	CreatedBy string     `json:"-"`
+}
+
+// KeyStore isimli, scope'lu API anahtarlarını JSON dosyasında tutar.
+type KeyStore struct {
+	mu        sync.Mutex
+	path      string
+	file      keyStoreFile
+	byHash    map[string]*apiKey
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+var store *KeyStore
+
+// Init API anahtarı deposunu açar. lock.Init'ten sonra çağrılmalıdır.
+func Init() error {
+	s, err := OpenKeyStore(KeyStorePath())
+	if err != nil {
+		return err
+	}
+	store = s
+
+	if len(s.file.Keys) == 0 && config.Get().Auth.ApiValue == "" {
+		slog.Warn("Hiç API anahtarı yok, tüm istekler reddedilecek", "keys_file", s.path)
+	}
+	slog.Info("API anahtarı deposu yüklendi", "keys_file", s.path, "keys", len(s.file.Keys))
+	return nil
+}
+
+// Store aktif anahtar deposunu döner; Init çağrılmadıysa nil.
+func Store() *KeyStore {
+	return store
+}
+
+// KeyStorePath config'deki anahtar dosyasının yolunu döner (varsayılan: backup_dir/api_keys.json).
+func KeyStorePath() string {
+	cfg := config.Get()
+	if cfg.Auth.KeysFile != "" {
+		return cfg.Auth.KeysFile
+	}
+	return filepath.Join(cfg.KettasLog.Backup.BackupDir, "api_keys.json")
+}
+
+// OpenKeyStore anahtar dosyasını yükler; dosya yoksa boş depo ile başlar.
+func OpenKeyStore(path string) (*KeyStore, error) {
+	s := &KeyStore{path: path}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reload dosyayı diskten tekrar okur. mu tutulurken çağrılmalıdır.
+func (s *KeyStore) reload() error {
+	s.checkedAt = time.Now()
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.setFile(keyStoreFile{Version: keyStoreVersion})
+			s.modTime = time.Time{}
+			return nil
+		}
+		return fmt.Errorf("API anahtarı dosyası okunamadı: %w", err)
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("API anahtarı dosyası okunamadı: %w", err)
+	}
+	var f keyStoreFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("API anahtarı dosyası çözülemedi: %w", err)
+	}
+	s.setFile(f)
+	s.modTime = info.ModTime()
+	return nil
+}
+
+func (s *KeyStore) setFile(f keyStoreFile) {
+	s.file = f
+	s.byHash = make(map[string]*apiKey, len(f.Keys))
+	for i := range s.file.Keys {
+		s.byHash[s.file.Keys[i].Hash] = &s.file.Keys[i]
+	}
+}
+
+// refresh dosya başka bir instance tarafından değiştirildiyse yeniden okur. mu tutulurken çağrılmalıdır.
+func (s *KeyStore) refresh() {
+	if time.Since(s.checkedAt) < reloadInterval {
+		return
+	}
+	s.checkedAt = time.Now()
+	info, err := os.Stat(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if err == nil && info.ModTime().Equal(s.modTime) {
+		return
+	}
+	if err := s.reload(); err != nil {
+		slog.Error("API anahtarı dosyası yeniden okunamadı, önceki anahtarlar kullanılıyor", "error", err)
+	}
+}
+
+// update depoyu diğer instance'larla kilitleyerek diskten yeniden okur, fn ile değiştirir
+// ve atomik olarak kaydeder. mu tutulurken çağrılmalıdır.
+func (s *KeyStore) update(fn func(f *keyStoreFile) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	release, err := lock.Get().Lock(ctx, keyStoreLockName)
+	if err != nil {
+		return fmt.Errorf("API anahtarı kilidi alınamadı: %w", err)
+	}
+	defer release()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+	f := s.file
+	f.Keys = slices.Clone(f.Keys)
+	if err := fn(&f); err != nil {
+		return err
+	}
+	f.Version = keyStoreVersion
+	f.UpdatedAt = time.Now()
+	if err := s.save(f); err != nil {
+		return err
+	}
+	return s.reload()
+}
+
+// save dosyayı geçici dosyaya yazıp fsync ettikten sonra yerine taşır.
+func (s *KeyStore) save(f keyStoreFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("API anahtarı dizini oluşturulamadı: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("API anahtarı dosyası yazılamadı: %w", err)
+	}
+	_, err = out.Write(data)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("API anahtarı dosyası yazılamadı: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("API anahtarı dosyası yazılamadı: %w", err)
+	}
+	return nil
+}
+
+// Create yeni bir anahtar oluşturur ve secret'ı döner. Secret yalnızca bir kez gösterilir,
+// depoda sadece özeti tutulur.
+func (s *KeyStore) Create(req CreateKeyRequest) (KeyInfo, string, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return KeyInfo{}, "", fmt.Errorf("name gerekli")
+	}
+	if len(req.Scopes) == 0 {
+		return KeyInfo{}, "", fmt.Errorf("en az bir scope gerekli")
+	}
+	for _, sc := range req.Scopes {
+		if !ValidScope(sc) {
+			return KeyInfo{}, "", fmt.Errorf("geçersiz scope: %q (geçerli: %s)", sc, strings.Join(Scopes, ", "))
+		}
+	}
+	for _, homeId := range req.HomeIds {
+		// home_id'ler dosya yollarında kullanılır
+		if !ValidHomeId(homeId) {
+			return KeyInfo{}, "", fmt.Errorf("geçersiz home_id: %q", homeId)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return KeyInfo{}, "", fmt.Errorf("expires_at gelecekte olmalı")
+	}
+
+	secret, err := newToken(secretPrefix)
+	if err != nil {
+		return KeyInfo{}, "", err
+	}
+	id, err := newId("ak_")
+	if err != nil {
+		return KeyInfo{}, "", err
+	}
+	key := apiKey{
+		ID:        id,
+		Kind:      KindApiKey,
+		Name:      req.Name,
+		Prefix:    secret[:len(secretPrefix)+6],
+		Hash:      hashSecret(secret),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		HomeIds:   req.HomeIds,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: req.CreatedBy,
+		ExpiresAt: req.ExpiresAt,
+	}
+	return s.add(key, secret)
+}
+
+// ProvisionDeviceRequest yeni cihaz token'ının özellikleri.
+type ProvisionDeviceRequest struct {
+	HomeId    string     `json:"home_id"`
+	Name      string     `json:"name"`       // Boşsa home_id
+	ExpiresAt *time.Time `json:"expires_at"` // Boşsa süresiz
+	CreatedBy string     `json:"-"`
+}
+
+// ProvisionDevice bir ev için cihaz token'ı oluşturur ve secret'ı döner. Token ile yapılan
+// upload'larda home_id token'dan alınır; bir eve birden fazla token verilebilir.
+func (s *KeyStore) ProvisionDevice(req ProvisionDeviceRequest) (KeyInfo, string, error) {
+	if !ValidHomeId(req.HomeId) {
+		return KeyInfo{}, "", fmt.Errorf("geçersiz home_id: %q", req.HomeId)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return KeyInfo{}, "", fmt.Errorf("expires_at gelecekte olmalı")
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = req.HomeId
+	}
+
+	secret, err := newToken(devicePrefix)
+	if err != nil {
+		return KeyInfo{}, "", err
+	}
+	id, err := newId("dev_")
+	if err != nil {
+		return KeyInfo{}, "", err
+	}
+	key := apiKey{
+		ID:        id,
+		Kind:      KindDevice,
+		Name:      name,
+		Prefix:    secret[:len(devicePrefix)+6],
+		Hash:      hashSecret(secret),
+		Scopes:    []string{ScopeUpload},
+		HomeIds:   []string{req.HomeId},
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: req.CreatedBy,
+		ExpiresAt: req.ExpiresAt,
+	}
+	return s.add(key, secret)
+}
+
+func (s *KeyStore) add(key apiKey, secret string) (KeyInfo, string, error) {
+	sealed, err := sealSigningKey(secret)
+	if err != nil {
+		// Anahtar düz header ile yine kullanılabilir; imza için secret anahtarı gerekir
+		slog.Warn("İmza anahtarı saklanamadı, anahtar imzalı isteklerde kullanılamaz", "key_id", key.ID, "error", err)
+	}
+	key.SigningKey = sealed
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.update(func(f *keyStoreFile) error {
+		f.Keys = append(f.Keys, key)
+		return nil
+	})
+	if err != nil {
+		return KeyInfo{}, "", err
+	}
+	return key.info(time.Now()), secret, nil
+}
+
+// Revoke verilen türdeki anahtarı iptal eder. Kayıt listede "revoked" olarak kalır.
+func (s *KeyStore) Revoke(kind, id string) (KeyInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var info KeyInfo
+	err := s.update(func(f *keyStoreFile) error {
+		for i := range f.Keys {
+			if f.Keys[i].ID != id || f.Keys[i].kind() != kind {
+				continue
+			}
+			if f.Keys[i].RevokedAt == nil {
+				now := time.Now().UTC()
+				f.Keys[i].RevokedAt = &now
+			}
+			info = f.Keys[i].info(time.Now())
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	})
+	return info, err
+}
+
+// List verilen türdeki anahtarların özetini oluşturulma sırasına göre döner.
+// homeId verilirse yalnızca o eve bağlı anahtarlar döner.
+func (s *KeyStore) List(kind, homeId string) []KeyInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	now := time.Now()
+	infos := make([]KeyInfo, 0, len(s.file.Keys))
+	for _, k := range s.file.Keys {
+		if k.kind() != kind || (homeId != "" && !slices.Contains(k.HomeIds, homeId)) {
+			continue
+		}
+		infos = append(infos, k.info(now))
+	}
+	sort.SliceStable(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos
+}
+
+// Authenticate secret'a ait anahtarı bulur ve kimliği döner.
+func (s *KeyStore) Authenticate(secret string) (*Principal, error) {
+	if !strings.HasPrefix(secret, secretPrefix) && !strings.HasPrefix(secret, devicePrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	k, ok := s.byHash[hashSecret(secret)]
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return k.principal(time.Now())
+}
+
+// principal anahtar geçerliyse kimliğini döner.
+func (k *apiKey) principal(now time.Time) (*Principal, error) {
+	switch k.status(now) {
+	case StatusRevoked:
+		return nil, ErrKeyRevoked
+	case StatusExpired:
+		return nil, ErrKeyExpired
+	}
+	method := MethodApiKey
+	if k.kind() == KindDevice {
+		method = MethodDevice
+	}
+	return &Principal{
+		ID:      k.ID,
+		Name:    k.Name,
+		Method:  method,
+		Scopes:  slices.Clone(k.Scopes),
+		HomeIds: slices.Clone(k.HomeIds),
+	}, nil
+}
+
+func (k *apiKey) kind() string {
+	if k.Kind == "" {
+		return KindApiKey
+	}
+	return k.Kind
+}
+
+func (k *apiKey) status(now time.Time) string {
+	switch {
+	case k.RevokedAt != nil:
+		return StatusRevoked
+	case k.ExpiresAt != nil && !now.Before(*k.ExpiresAt):
+		return StatusExpired
+	}
+	return StatusActive
+}
+
+func (k *apiKey) info(now time.Time) KeyInfo {
+	return KeyInfo{
+		ID:        k.ID,
+		Kind:      k.kind(),
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+		HomeIds:   k.HomeIds,
+		Status:    k.status(now),
+		CreatedAt: k.CreatedAt,
+		CreatedBy: k.CreatedBy,
+		ExpiresAt: k.ExpiresAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
+
+// Authenticate header'daki değeri önce anahtar deposunda, sonra config'deki tek
+// auth.api_value ile (tüm yetkilerle) doğrular.
+func Authenticate(value string) (*Principal, error) {
+	if value == "" {
+		return nil, ErrInvalidKey
+	}
+	if store != nil {
+		p, err := store.Authenticate(value)
+		if !errors.Is(err, ErrInvalidKey) {
+			return p, err
+		}
+	}
+
+	legacy := config.Get().Auth.ApiValue
+	if legacy != "" && subtle.ConstantTimeCompare([]byte(value), []byte(legacy)) == 1 {
+		return legacyPrincipal(), nil
+	}
+	return nil, ErrInvalidKey
+}
+
+// legacyId auth.api_value kimliğinin id'si (imzalı isteklerde key id olarak da kullanılır).
+const legacyId = "legacy"
+
+func legacyPrincipal() *Principal {
+	return &Principal{ID: legacyId, Name: "auth.api_value", Method: MethodLegacy, Scopes: []string{ScopeAdmin}}
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidHomeId home_id'nin dosya adlarında ve dizin yollarında güvenle kullanılabileceğini kontrol eder.
+// Upload dosya adı "_" ile bölündüğü için home_id "_" içeremez.
+func ValidHomeId(homeId string) bool {
+	return homeId != "" && !strings.ContainsAny(homeId, `/\_`) && !strings.Contains(homeId, "..")
+}
+
+func newToken(prefix string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func newId(prefix string) (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCreateKeyValidation(t *testing.T) {
+	loadConfig(t, "")
+	s, err := OpenKeyStore(filepath.Join(t.TempDir(), "api_keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		req     CreateKeyRequest
+		wantErr bool
+	}{
+		{"geçerli", CreateKeyRequest{Name: "destek", Scopes: []string{ScopeReadHome}, HomeIds: []string{"home-1"}}, false},
+		{"tüm evler", CreateKeyRequest{Name: "rapor", Scopes: []string{ScopeReadAll}}, false},
+		{"isimsiz", CreateKeyRequest{Name: "  ", Scopes: []string{ScopeReadHome}}, true},
+		{"scope yok", CreateKeyRequest{Name: "destek"}, true},
+		{"geçersiz scope", CreateKeyRequest{Name: "destek", Scopes: []string{"write:all"}}, true},
+		{"home_id'de yol", CreateKeyRequest{Name: "destek", Scopes: []string{ScopeReadHome}, HomeIds: []string{"../etc"}}, true},
+		{"home_id'de alt çizgi", CreateKeyRequest{Name: "destek", Scopes: []string{ScopeReadHome}, HomeIds: []string{"home_1"}}, true},
+		{"boş home_id", CreateKeyRequest{Name: "destek", Scopes: []string{ScopeReadHome}, HomeIds: []string{""}}, true},
+		{"geçmiş expires_at", CreateKeyRequest{Name: "destek", Scopes: []string{ScopeReadHome}, ExpiresAt: &past}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, secret, err := s.Create(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create hata = %v, hata bekleniyor = %v", err, tt.wantErr)
+			}
+			if err == nil && (secret == "" || info.Status != StatusActive) {
+				t.Errorf("Create = %+v, %q", info, secret)
+			}
+		})
+	}
+}
+
+func TestKeyStoreAuthenticate(t *testing.T) {
+	loadConfig(t, "")
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	s, err := OpenKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, activeSecret, err := s.Create(CreateKeyRequest{Name: "destek", Scopes: []string{ScopeReadHome}, HomeIds: []string{"home-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedSecret, err := s.Create(CreateKeyRequest{Name: "eski", Scopes: []string{ScopeReadHome}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Revoke(KindApiKey, revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Revoke(KindDevice, active.ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("başka türdeki anahtar iptal edildi: %v", err)
+	}
+
+	// Dosyadan yeniden açılan depo aynı sonuçları vermeli
+	reopened, err := OpenKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr error
+	}{
+		{"geçerli", activeSecret, nil},
+		{"iptal edilmiş", revokedSecret, ErrKeyRevoked},
+		{"bilinmeyen", activeSecret + "x", ErrInvalidKey},
+		{"önek yok", "not-a-key", ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		for name, ks := range map[string]*KeyStore{"aynı": s, "yeniden açılan": reopened} {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				p, err := ks.Authenticate(tt.secret)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate hata = %v, %v bekleniyordu", err, tt.wantErr)
+				}
+				if err == nil && (p.ID != active.ID || !slices.Equal(p.HomeIds, []string{"home-1"}) || p.Method != MethodApiKey) {
+					t.Errorf("kimlik = %+v", p)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package auth istekleri yapan kimlikleri (principal) ve API anahtarı deposunu yönetir.
+//
+// Kimlik doğrulama middleware'i isteği doğruladıktan sonra Principal'ı fiber context'ine
+// koyar; route'lar ve handler'lar yetkiyi scope ve home_id bağlaması üzerinden kontrol eder.
+package auth
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Scope'lar. admin tüm scope'ları, read:all ise her evin read:home yetkisini kapsar.
+const (
+	ScopeUpload   = "upload"    // POST /upload
+	ScopeReadHome = "read:home" // Tek bir evin logları ve araması
+	ScopeReadAll  = "read:all"  // Tüm evlerin logları (/all-logs)
+	ScopeAdmin    = "admin"     // /admin, anahtar yönetimi, ev silme
+)
+
+// Scopes geçerli tüm scope'lar.
+var Scopes = []string{ScopeUpload, ScopeReadHome, ScopeReadAll, ScopeAdmin}
+
+// Kimlik doğrulama yöntemleri
+const (
+	MethodApiKey = "api_key"
+	MethodDevice = "device" // Tek bir eve bağlı cihaz token'ı
+	MethodLegacy = "legacy" // config'deki tek auth.api_value
+	MethodJWT    = "jwt"    // Kimlik sağlayıcısının verdiği bearer token
+
+	MethodClientCert = "client_cert" // mTLS istemci sertifikası
+)
+
+// ValidScope scope'un tanımlı olup olmadığını döner.
+func ValidScope(s string) bool {
+	return slices.Contains(Scopes, s)
+}
+
+// Principal doğrulanmış bir isteğin kimliği.
+type Principal struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Method  string   `json:"method"`
+	Roles   []string `json:"roles,omitempty"` // Yalnızca JWT; token'daki roller
+	Scopes  []string `json:"scopes"`
+	HomeIds []string `json:"home_ids,omitempty"` // Boşsa tüm evler
+}
+
+// HasScope kimliğin scope'a sahip olup olmadığını döner. nil kimlik hiçbir scope'a sahip değildir.
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	if slices.Contains(p.Scopes, ScopeAdmin) || slices.Contains(p.Scopes, scope) {
+		return true
+	}
+	return scope == ScopeReadHome && slices.Contains(p.Scopes, ScopeReadAll)
+}
+
+// CanAccessHome kimliğin verilen home_id'ye bağlı olup olmadığını döner.
+// Ev bağlaması olmayan kimlikler tüm evlere erişebilir; scope ayrıca kontrol edilmelidir.
+func (p *Principal) CanAccessHome(homeId string) bool {
+	if p == nil {
+		return false
+	}
+	return len(p.HomeIds) == 0 || slices.Contains(p.HomeIds, homeId)
+}
+
+// DeviceHome kimlik bir cihaz token'ı veya eve bağlı bir istemci sertifikası ise bağlı
+// olduğu home_id'yi döner. Cihazlar için home_id istekten değil kimlikten alınır.
+func (p *Principal) DeviceHome() (string, bool) {
+	if p == nil || (p.Method != MethodDevice && p.Method != MethodClientCert) || len(p.HomeIds) != 1 {
+		return "", false
+	}
+	return p.HomeIds[0], true
+}
+
+const principalKey = "auth.principal"
+
+// SetPrincipal doğrulanmış kimliği isteğe ekler.
+func SetPrincipal(c *fiber.Ctx, p *Principal) {
+	c.Locals(principalKey, p)
+}
+
+// FromCtx isteğin doğrulanmış kimliğini döner; yoksa nil.
+func FromCtx(c *fiber.Ctx) *Principal {
+	p, _ := c.Locals(principalKey).(*Principal)
+	return p
+}
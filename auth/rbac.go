@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log-server/config"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RBAC ile korunan endpoint'ler (auth.rbac.roles.*.permissions[].endpoints)
+const (
+	EndpointAllLogs  = "all_logs"  // GET /all-logs
+	EndpointHomeLogs = "home_logs" // GET /home-logs
+	EndpointSearch   = "search"    // GET /home-logs/search
+)
+
+// Endpoints RBAC ile korunan tüm endpoint'ler.
+var Endpoints = []string{EndpointAllLogs, EndpointHomeLogs, EndpointSearch}
+
+const allEndpoints = "*"
+
+var ErrNoPermission = errors.New("kimliğin rolleri bu endpoint'e izin vermiyor")
+
+func init() {
+	config.RegisterValidator(validateRBAC)
+}
+
+// validateRBAC rollerin endpoint ve home set'lerini, binding'lerin rollerini ve home set
+// desenlerini kontrol eder.
+func validateRBAC(cfg *config.Config) []config.Problem {
+	r := cfg.Auth.RBAC
+	var problems []config.Problem
+	add := func(key, format string, args ...any) {
+		problems = append(problems, config.Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(r.HomeSets)) {
+		for _, pattern := range r.HomeSets[name] {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				add("auth.rbac.home_sets."+name, "geçersiz home_id deseni %q", pattern)
+			}
+		}
+	}
+
+	for _, role := range slices.Sorted(maps.Keys(r.Roles)) {
+		for i, perm := range r.Roles[role].Permissions {
+			key := fmt.Sprintf("auth.rbac.roles.%s.permissions[%d]", role, i)
+			if len(perm.Endpoints) == 0 {
+				add(key+".endpoints", "boş olamaz")
+			}
+			for _, ep := range perm.Endpoints {
+				if ep != allEndpoints && !slices.Contains(Endpoints, ep) {
+					add(key+".endpoints", "geçersiz endpoint %q (geçerli: %s, *)", ep, strings.Join(Endpoints, ", "))
+				}
+			}
+			if len(perm.HomeSets) == 0 {
+				add(key+".home_sets", "boş olamaz (tüm evler için \"*\")")
+			}
+			for _, set := range perm.HomeSets {
+				if _, ok := r.HomeSets[strings.ToLower(set)]; !ok && set != allHomes {
+					add(key+".home_sets", "tanımsız home set %q", set)
+				}
+			}
+		}
+	}
+
+	for i, b := range r.Bindings {
+		key := fmt.Sprintf("auth.rbac.bindings[%d]", i)
+		if _, err := path.Match(b.Principal, ""); err != nil || b.Principal == "" {
+			add(key+".principal", "geçersiz kimlik %q", b.Principal)
+		}
+		for _, role := range b.Roles {
+			if _, ok := r.Roles[strings.ToLower(role)]; !ok {
+				add(key+".roles", "tanımsız rol %q", role)
+			}
+		}
+	}
+	for _, role := range r.DefaultRoles {
+		if _, ok := r.Roles[strings.ToLower(role)]; !ok {
+			add("auth.rbac.default_roles", "tanımsız rol %q", role)
+		}
+	}
+	return problems
+}
+
+// RBACEnabled rol tabanlı yetkilendirmenin açık olup olmadığını döner.
+func RBACEnabled() bool {
+	return config.Get().Auth.RBAC.Enabled
+}
+
+// Grant bir kimliğin bir endpoint'te okuyabileceği evler.
+type Grant struct {
+	Endpoint string
+	Roles    []string // İzni veren roller; RBAC kapalıysa veya kimlik admin ise boş
+	all      bool
+	patterns []string
+}
+
+// AllowsHome grant'ın evi kapsayıp kapsamadığını döner. nil grant hiçbir evi kapsamaz.
+func (g *Grant) AllowsHome(homeId string) bool {
+	if g == nil {
+		return false
+	}
+	if g.all {
+		return true
+	}
+	for _, pattern := range g.patterns {
+		if ok, _ := path.Match(pattern, homeId); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize kimliğin rollerinden endpoint'e izin verenleri bulur ve okunabilecek evlerin
+// birleşimini döner. RBAC kapalıysa veya kimlik admin ise tüm evleri kapsayan bir grant döner;
+// kimliğin ev bağlaması (HomeIds) ayrıca kontrol edilmelidir.
+func Authorize(p *Principal, endpoint string) (*Grant, error) {
+	rbac := config.Get().Auth.RBAC
+	g := &Grant{Endpoint: endpoint}
+	if !rbac.Enabled || p.HasScope(ScopeAdmin) {
+		g.all = true
+		return g, nil
+	}
+	if p == nil {
+		return nil, ErrNoPermission
+	}
+
+	for _, role := range principalRoles(rbac, p) {
+		granted := false
+		for _, perm := range rbac.Roles[role].Permissions {
+			if !slices.Contains(perm.Endpoints, endpoint) && !slices.Contains(perm.Endpoints, allEndpoints) {
+				continue
+			}
+			granted = true
+			for _, set := range perm.HomeSets {
+				if set == allHomes {
+					g.all = true
+					continue
+				}
+				g.patterns = append(g.patterns, rbac.HomeSets[strings.ToLower(set)]...)
+			}
+		}
+		if granted {
+			g.Roles = append(g.Roles, role)
+		}
+	}
+	if len(g.Roles) == 0 {
+		return nil, ErrNoPermission
+	}
+	return g, nil
+}
+
+// principalRoles kimliğin token'daki ve bindings'teki rollerini küçük harfle döner; hiç rolü
+// yoksa default_roles.
+func principalRoles(rbac config.RBACConfig, p *Principal) []string {
+	var roles []string
+	for _, role := range p.Roles {
+		roles = append(roles, strings.ToLower(role))
+	}
+	for _, b := range rbac.Bindings {
+		if ok, _ := path.Match(b.Principal, p.ID); ok || b.Principal == p.ID {
+			for _, role := range b.Roles {
+				roles = append(roles, strings.ToLower(role))
+			}
+		}
+	}
+	if len(roles) == 0 {
+		for _, role := range rbac.DefaultRoles {
+			roles = append(roles, strings.ToLower(role))
+		}
+	}
+	slices.Sort(roles)
+	return slices.Compact(roles)
+}
+
+const grantKey = "auth.grant"
+
+// SetGrant endpoint için hesaplanan grant'ı isteğe ekler.
+func SetGrant(c *fiber.Ctx, g *Grant) {
+	c.Locals(grantKey, g)
+}
+
+// GrantFromCtx isteğin grant'ını döner; Authorize middleware'i çalışmamışsa nil.
+func GrantFromCtx(c *fiber.Ctx) *Grant {
+	g, _ := c.Locals(grantKey).(*Grant)
+	return g
+}
+
+// ReadableHome isteği yapan kimliğin bir evi okuyup okuyamayacağını söyleyen fonksiyonu döner.
+// Hem kimliğin ev bağlaması hem de grant kontrol edilir; grant yoksa hiçbir ev okunamaz.
+// Dönen fonksiyon istek context'ine erişmediği için akış sırasında da kullanılabilir.
+func ReadableHome(c *fiber.Ctx) func(homeId string) bool {
+	p, g := FromCtx(c), GrantFromCtx(c)
+	return func(homeId string) bool {
+		return p.CanAccessHome(homeId) && g.AllowsHome(homeId)
+	}
+}
+
+// CanReadHome isteği yapan kimliğin evi okuyup okuyamayacağını döner.
+func CanReadHome(c *fiber.Ctx, homeId string) bool {
+	return ReadableHome(c)(homeId)
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"errors"
+	"log-server/config"
+	"slices"
+	"testing"
+)
+
+const rbacConfig = `auth:
+  rbac:
+    enabled: true
+    home_sets:
+      region_x: [ist-*, ank-01]
+      lab: [lab-01]
+    roles:
+      Support:
+        permissions:
+          - endpoints: [home_logs, search]
+            home_sets: [region_x]
+      auditor:
+        permissions:
+          - endpoints: ["*"]
+            home_sets: [lab]
+      ops:
+        permissions:
+          - endpoints: [all_logs]
+            home_sets: ["*"]
+    bindings:
+      - principal: "cert:support-*"
+        roles: [support]
+      - principal: ak_lab
+        roles: [auditor]
+    default_roles: [auditor]
+`
+
+func TestAuthorize(t *testing.T) {
+	loadConfig(t, rbacConfig)
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		endpoint  string
+		wantRoles []string // nil ise ErrNoPermission beklenir
+		allowed   []string
+		denied    []string
+	}{
+		{"admin her şeyi okur", &Principal{ID: "ak_admin", Scopes: []string{ScopeAdmin}}, EndpointAllLogs,
+			[]string{}, []string{"ist-01", "lab-01", "izm-01"}, nil},
+		{"token rolü, büyük harf", &Principal{ID: "jwt:u1", Roles: []string{"SUPPORT"}}, EndpointSearch,
+			[]string{"support"}, []string{"ist-01", "ist-99", "ank-01"}, []string{"ank-02", "lab-01"}},
+		{"desenli binding", &Principal{ID: "cert:support-eu"}, EndpointHomeLogs,
+			[]string{"support"}, []string{"ist-01"}, []string{"lab-01"}},
+		{"binding ve default birleşmez", &Principal{ID: "cert:support-eu"}, EndpointAllLogs, nil, nil, nil},
+		{"tam binding", &Principal{ID: "ak_lab"}, EndpointAllLogs,
+			[]string{"auditor"}, []string{"lab-01"}, []string{"ist-01"}},
+		{"rolsüz kimliğe default_roles", &Principal{ID: "ak_other"}, EndpointSearch,
+			[]string{"auditor"}, []string{"lab-01"}, []string{"ist-01"}},
+		{"birden çok rolün birleşimi", &Principal{ID: "jwt:u2", Roles: []string{"ops", "auditor"}}, EndpointAllLogs,
+			[]string{"auditor", "ops"}, []string{"lab-01", "izm-01"}, nil},
+		{"rol endpoint'e izin vermiyor", &Principal{ID: "jwt:u3", Roles: []string{"ops"}}, EndpointSearch, nil, nil, nil},
+		{"tanımsız rol", &Principal{ID: "jwt:u4", Roles: []string{"guest"}}, EndpointHomeLogs, nil, nil, nil},
+		{"kimlik yok", nil, EndpointHomeLogs, nil, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Authorize(tt.principal, tt.endpoint)
+			if tt.wantRoles == nil {
+				if !errors.Is(err, ErrNoPermission) || g != nil {
+					t.Fatalf("Authorize = %v, %v; ErrNoPermission bekleniyordu", g, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authorize: %v", err)
+			}
+			if !slices.Equal(g.Roles, tt.wantRoles) {
+				t.Errorf("Roles = %v, %v bekleniyordu", g.Roles, tt.wantRoles)
+			}
+			for _, home := range tt.allowed {
+				if !g.AllowsHome(home) {
+					t.Errorf("%s okunabilmeli", home)
+				}
+			}
+			for _, home := range tt.denied {
+				if g.AllowsHome(home) {
+					t.Errorf("%s okunamamalı", home)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorizeDisabled(t *testing.T) {
+	loadConfig(t, "")
+	for _, p := range []*Principal{nil, {ID: "jwt:u1", Roles: []string{"unknown"}}} {
+		g, err := Authorize(p, EndpointAllLogs)
+		if err != nil || !g.AllowsHome("any-home") {
+			t.Errorf("RBAC kapalıyken Authorize(%v) = %v, %v; tüm evler bekleniyordu", p, g, err)
+		}
+	}
+	var nilGrant *Grant
+	if nilGrant.AllowsHome("any-home") {
+		t.Error("nil grant bir evi kapsamamalı")
+	}
+}
+
+func TestValidateRBAC(t *testing.T) {
+	valid := config.RBACConfig{
+		HomeSets: map[string][]string{"region_x": {"ist-*"}},
+		Roles: map[string]config.RoleConfig{"support": {Permissions: []config.PermissionConfig{
+			{Endpoints: []string{EndpointHomeLogs}, HomeSets: []string{"region_x"}},
+		}}},
+		Bindings:     []config.RoleBinding{{Principal: "cert:support-*", Roles: []string{"Support"}}},
+		DefaultRoles: []string{"support"},
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(r *config.RBACConfig)
+		wantKey string // Boşsa sorun beklenmez
+	}{
+		{"geçerli", func(r *config.RBACConfig) {}, ""},
+		{"bozuk desen", func(r *config.RBACConfig) { r.HomeSets = map[string][]string{"region_x": {"ist-["}} }, "auth.rbac.home_sets.region_x"},
+		{"boş desen", func(r *config.RBACConfig) { r.HomeSets = map[string][]string{"region_x": {""}} }, "auth.rbac.home_sets.region_x"},
+		{"geçersiz endpoint", func(r *config.RBACConfig) {
+			r.Roles = map[string]config.RoleConfig{"support": {Permissions: []config.PermissionConfig{{Endpoints: []string{"upload"}, HomeSets: []string{"*"}}}}}
+		}, "auth.rbac.roles.support.permissions[0].endpoints"},
+		{"boş endpoint", func(r *config.RBACConfig) {
+			r.Roles = map[string]config.RoleConfig{"support": {Permissions: []config.PermissionConfig{{HomeSets: []string{"*"}}}}}
+		}, "auth.rbac.roles.support.permissions[0].endpoints"},
+		{"tanımsız home set", func(r *config.RBACConfig) {
+			r.Roles = map[string]config.RoleConfig{"support": {Permissions: []config.PermissionConfig{{Endpoints: []string{"*"}, HomeSets: []string{"region_y"}}}}}
+		}, "auth.rbac.roles.support.permissions[0].home_sets"},
+		{"boş home set", func(r *config.RBACConfig) {
+			r.Roles = map[string]config.RoleConfig{"support": {Permissions: []config.PermissionConfig{{Endpoints: []string{"*"}}}}}
+		}, "auth.rbac.roles.support.permissions[0].home_sets"},
+		{"binding'de tanımsız rol", func(r *config.RBACConfig) { r.Bindings[0].Roles = []string{"ops"} }, "auth.rbac.bindings[0].roles"},
+		{"boş binding kimliği", func(r *config.RBACConfig) { r.Bindings[0].Principal = "" }, "auth.rbac.bindings[0].principal"},
+		{"tanımsız default rol", func(r *config.RBACConfig) { r.DefaultRoles = []string{"ops"} }, "auth.rbac.default_roles"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Auth.RBAC = valid
+			cfg.Auth.RBAC.Bindings = slices.Clone(valid.Bindings)
+			tt.edit(&cfg.Auth.RBAC)
+
+			problems := validateRBAC(&cfg)
+			if tt.wantKey == "" {
+				if len(problems) != 0 {
+					t.Errorf("beklenmeyen sorunlar: %v", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || problems[0].Key != tt.wantKey {
+				t.Errorf("validateRBAC = %v, %s bekleniyordu", problems, tt.wantKey)
+			}
+		})
+	}
+}
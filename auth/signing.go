@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log-server/config"
+	"log-server/crypto"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// İmzalı isteklerin header'ları. İmza şu satırların HMAC-SHA256'sıdır (hex):
+//
+//	METHOD
+//	/yol?query
+//	timestamp (unix saniye)
+//	nonce
+//	hex(sha256(body))
+//
+// İmza anahtarı HMAC-SHA256(secret, "logserver-signing-v1")'dir; API anahtarları, cihaz
+// token'ları ve auth.api_value için aynı şekilde türetilir. auth.api_value ile imzalarken key
+// id "legacy"dir. Depodaki hash (sha256(secret)) imza anahtarını vermez; imza anahtarı depoya
+// config secret anahtarıyla (secrets.key_file) şifrelenerek yazılır.
+const (
+	HeaderKeyId     = "X-Signature-Key-Id"
+	HeaderTimestamp = "X-Signature-Timestamp"
+	HeaderNonce     = "X-Signature-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+// İmza modları (auth.signing.mode)
+const (
+	SigningOff      = "off"      // İmza header'ları yok sayılır
+	SigningOptional = "optional" // İmza varsa doğrulanır, yoksa düz header anahtarı kabul edilir
+	SigningRequired = "required" // Düz header anahtarı reddedilir
+)
+
+const (
+	defaultMaxSkew        = 5 * time.Minute
+	defaultNonceCacheSize = 100000
+	maxNonceLength        = 128
+)
+
+var (
+	ErrBadSignature = errors.New("geçersiz imza")
+	ErrClockSkew    = errors.New("imza zamanı izin verilen aralığın dışında")
+	ErrReplay       = errors.New("nonce daha önce kullanılmış")
+	// ErrNonceCacheFull kimlik hatası değildir; istek daha sonra tekrar denenebilir
+	ErrNonceCacheFull = errors.New("nonce cache dolu")
+
+	ErrSignatureRequired = errors.New("imzalı istek gerekli")
+	ErrNoSigningKey      = errors.New("anahtar imza anahtarı olmadan oluşturulmuş (secret anahtarı yoktu); yeniden oluşturulmalı")
+)
+
+// SignedRequest imzası doğrulanacak isteğin parçaları.
+type SignedRequest struct {
+	KeyId     string
+	Method    string
+	Path      string // Query dahil (ör: /home-logs?x=1)
+	Timestamp string
+	Nonce     string
+	Body      []byte
+	Signature string
+}
+
+// SigningMode config'deki imza modunu döner (varsayılan: off).
+func SigningMode() string {
+	mode := strings.ToLower(config.Get().Auth.Signing.Mode)
+	if mode == "" {
+		return SigningOff
+	}
+	return mode
+}
+
+// Sign isteğin imzasını üretir (istemciler ve logctl sign için).
+func Sign(secret, method, path, timestamp, nonce string, body []byte) string {
+	return signWithKey(signingKey(secret), method, path, timestamp, nonce, body)
+}
+
+// signingKeyLabel imza anahtarını depodaki hash'ten ayıran türetme etiketi.
+const signingKeyLabel = "logserver-signing-v1"
+
+func signingKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingKeyLabel))
+	return mac.Sum(nil)
+}
+
+// secretKeys config secret anahtarlarını (secrets.key_file vb.) yükler; anahtar dosyası
+// değişmedikçe önbellekten döner.
+var secretKeys = struct {
+	mu   sync.Mutex
+	file string
+	ks   *crypto.KeySet
+}{}
+
+func loadSecretKeys() (*crypto.KeySet, error) {
+	file := config.Get().Secrets.KeyFile
+	secretKeys.mu.Lock()
+	defer secretKeys.mu.Unlock()
+	if secretKeys.ks != nil && secretKeys.file == file {
+		return secretKeys.ks, nil
+	}
+	ks, _, err := crypto.LoadKeySet(file)
+	if err != nil {
+		return nil, err
+	}
+	secretKeys.file, secretKeys.ks = file, ks
+	return ks, nil
+}
+
+// sealSigningKey secret'ın imza anahtarını depoya yazılmak üzere ENC(vN:...) olarak şifreler.
+func sealSigningKey(secret string) (string, error) {
+	ks, err := loadSecretKeys()
+	if err != nil {
+		return "", err
+	}
+	return ks.Encrypt(hex.EncodeToString(signingKey(secret)))
+}
+
+// openSigningKey depodaki şifreli imza anahtarını çözer.
+func openSigningKey(sealed string) ([]byte, error) {
+	if !crypto.IsEncrypted(sealed) {
+		return nil, fmt.Errorf("imza anahtarı şifreli değil")
+	}
+	ks, err := loadSecretKeys()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := ks.DecryptIfEncrypted(sealed)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(plain)
+}
+
+func signWithKey(key []byte, method, path, timestamp, nonce string, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", strings.ToUpper(method), path, timestamp, nonce, hex.EncodeToString(bodySum[:]))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature imzalı isteği doğrular ve kimliği döner. Zaman damgası max_skew_sec
+// penceresinde olmalı, nonce aynı anahtarla bu pencerede tekrar kullanılmamış olmalıdır.
+// Nonce'lar instance başına sınırlı bir bellekte tutulur.
+func VerifySignature(req SignedRequest) (*Principal, error) {
+	cfg := config.Get().Auth.Signing
+	maxSkew := defaultMaxSkew
+	if cfg.MaxSkewSec > 0 {
+		maxSkew = time.Duration(cfg.MaxSkewSec) * time.Second
+	}
+
+	ts, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: timestamp unix saniye olmalı", ErrBadSignature)
+	}
+	signedAt := time.Unix(ts, 0)
+	if skew := time.Since(signedAt); skew > maxSkew || skew < -maxSkew {
+		return nil, ErrClockSkew
+	}
+	if req.Nonce == "" || len(req.Nonce) > maxNonceLength {
+		return nil, fmt.Errorf("%w: nonce 1-%d karakter olmalı", ErrBadSignature, maxNonceLength)
+	}
+
+	p, key, err := signingKeyFor(req.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	expected := signWithKey(key, req.Method, req.Path, req.Timestamp, req.Nonce, req.Body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(req.Signature))) {
+		return nil, ErrBadSignature
+	}
+
+	// Nonce yalnızca geçerli imzadan sonra kaydedilir; böylece sahte isteklerle cache doldurulamaz
+	if err := replays.add(req.KeyId+":"+req.Nonce, signedAt.Add(maxSkew), nonceCacheSize(cfg.NonceCacheSize), time.Now()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// signingKeyFor anahtar id'sine ait kimliği ve imza anahtarını döner.
+func signingKeyFor(keyId string) (*Principal, []byte, error) {
+	if keyId == legacyId {
+		legacy := config.Get().Auth.ApiValue
+		if legacy == "" {
+			return nil, nil, ErrInvalidKey
+		}
+		return legacyPrincipal(), signingKey(legacy), nil
+	}
+	if store == nil {
+		return nil, nil, ErrInvalidKey
+	}
+	return store.signingKey(keyId)
+}
+
+func (s *KeyStore) signingKey(id string) (*Principal, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	i := slices.IndexFunc(s.file.Keys, func(k apiKey) bool { return k.ID == id })
+	if i < 0 {
+		return nil, nil, ErrInvalidKey
+	}
+	k := &s.file.Keys[i]
+	if k.SigningKey == "" {
+		return nil, nil, ErrNoSigningKey
+	}
+	key, err := openSigningKey(k.SigningKey)
+	if err != nil {
+		slog.Error("İmza anahtarı çözülemedi", "key_id", k.ID, "error", err)
+		return nil, nil, ErrInvalidKey
+	}
+	p, err := k.principal(time.Now())
+	return p, key, err
+}
+
+// replayCache son kullanılan nonce'ları süreleri dolana kadar tutar. Dolduğunda süresi dolmamış
+// kayıt atılmaz (atılırsa o nonce tekrar oynatılabilir); yeni imzalı istekler en eski kaydın süresi
+// dolana kadar reddedilir. Boyut pencere içindeki en yüksek istek sayısından büyük olmalıdır.
+type replayCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Eklenme sırası (en eski önde)
+}
+
+type replayEntry struct {
+	nonce   string
+	expires time.Time
+}
+
+var replays = newReplayCache()
+
+func newReplayCache() *replayCache {
+	return &replayCache{entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// nonceCacheSize config'deki boyutu varsayılanla tamamlar. Reload'da değişebildiği için cache'e
+// sabitlenmez, her istekte config'den okunur.
+func nonceCacheSize(size int) int {
+	if size > 0 {
+		return size
+	}
+	return defaultNonceCacheSize
+}
+
+// add nonce'u kaydeder. Nonce süresi dolmadan önce görülmüşse ErrReplay, cache süresi dolmamış
+// kayıtlarla doluysa ErrNonceCacheFull döner.
+func (c *replayCache) add(nonce string, expires time.Time, size int, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for e := c.order.Front(); e != nil; e = c.order.Front() {
+		entry := e.Value.(replayEntry)
+		if now.Before(entry.expires) {
+			break
+		}
+		c.order.Remove(e)
+		if c.entries[entry.nonce] == e {
+			delete(c.entries, entry.nonce)
+		}
+	}
+
+	if e, ok := c.entries[nonce]; ok {
+		if now.Before(e.Value.(replayEntry).expires) {
+			return ErrReplay
+		}
+		c.order.Remove(e)
+		delete(c.entries, nonce)
+	}
+	if c.order.Len() >= size {
+		return ErrNonceCacheFull
+	}
+	c.entries[nonce] = c.order.PushBack(replayEntry{nonce: nonce, expires: expires})
+	return nil
+}
+
+// retryAfter cache'te yer açılmasına (en eski kaydın süresinin dolmasına) kalan süreyi döner.
+func (c *replayCache) retryAfter(now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e := c.order.Front(); e != nil {
+		return max(e.Value.(replayEntry).expires.Sub(now), 0)
+	}
+	return 0
+}
+
+// NonceCacheRetryAfter ErrNonceCacheFull sonrasında istemcinin ne kadar beklemesi gerektiğini döner.
+func NonceCacheRetryAfter() time.Duration {
+	return replays.retryAfter(time.Now())
+}
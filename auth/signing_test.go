@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signed legacy anahtarıyla imzalanmış, geçerli bir istek döner.
+func signed(secret, keyId, nonce string, at time.Time) SignedRequest {
+	req := SignedRequest{
+		KeyId:     keyId,
+		Method:    "GET",
+		Path:      "/home-logs?home_id=a",
+		Timestamp: strconv.FormatInt(at.Unix(), 10),
+		Nonce:     nonce,
+		Body:      []byte(`{"x":1}`),
+	}
+	req.Signature = Sign(secret, req.Method, req.Path, req.Timestamp, req.Nonce, req.Body)
+	return req
+}
+
+func TestSigningKeyIsNotStoredHash(t *testing.T) {
+	const secret = "lsk_secret"
+	if key := signingKey(secret); hex.EncodeToString(key) == hashSecret(secret) || len(key) != 32 {
+		t.Fatal("imza anahtarı depodaki hash'ten türetilmemeli")
+	}
+	if Sign(secret, "GET", "/", "1", "n", nil) == Sign(secret+"x", "GET", "/", "1", "n", nil) {
+		t.Fatal("farklı secret'lar aynı imzayı üretti")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	const legacy = "legacysecret"
+	loadConfig(t, "auth: {api_value: "+legacy+", signing: {mode: required, max_skew_sec: 60}}\n")
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		req     func(nonce string) SignedRequest
+		wantErr error
+	}{
+		{"geçerli", func(n string) SignedRequest { return signed(legacy, legacyId, n, now) }, nil},
+		{"büyük harfli imza", func(n string) SignedRequest {
+			r := signed(legacy, legacyId, n, now)
+			r.Signature = strings.ToUpper(r.Signature)
+			return r
+		}, nil},
+		{"yanlış secret", func(n string) SignedRequest { return signed("baska", legacyId, n, now) }, ErrBadSignature},
+		{"değiştirilmiş body", func(n string) SignedRequest {
+			r := signed(legacy, legacyId, n, now)
+			r.Body = []byte(`{"x":2}`)
+			return r
+		}, ErrBadSignature},
+		{"değiştirilmiş yol", func(n string) SignedRequest {
+			r := signed(legacy, legacyId, n, now)
+			r.Path = "/home-logs?home_id=b"
+			return r
+		}, ErrBadSignature},
+		{"değiştirilmiş method", func(n string) SignedRequest {
+			r := signed(legacy, legacyId, n, now)
+			r.Method = "DELETE"
+			return r
+		}, ErrBadSignature},
+		{"eski zaman", func(n string) SignedRequest { return signed(legacy, legacyId, n, now.Add(-2*time.Minute)) }, ErrClockSkew},
+		{"gelecek zaman", func(n string) SignedRequest { return signed(legacy, legacyId, n, now.Add(2*time.Minute)) }, ErrClockSkew},
+		{"geçersiz zaman", func(n string) SignedRequest {
+			r := signed(legacy, legacyId, n, now)
+			r.Timestamp = "dün"
+			return r
+		}, ErrBadSignature},
+		{"boş nonce", func(string) SignedRequest { return signed(legacy, legacyId, "", now) }, ErrBadSignature},
+		{"uzun nonce", func(string) SignedRequest {
+			return signed(legacy, legacyId, strings.Repeat("n", maxNonceLength+1), now)
+		}, ErrBadSignature},
+		{"bilinmeyen anahtar", func(n string) SignedRequest { return signed(legacy, "ak_yok", n, now) }, ErrInvalidKey},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce := "nonce-" + strconv.Itoa(i) + "-" + strconv.FormatInt(now.UnixNano(), 36)
+			p, err := VerifySignature(tt.req(nonce))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("hata = %v, %v bekleniyordu", err, tt.wantErr)
+			}
+			if err == nil && p.ID != legacyId {
+				t.Errorf("kimlik = %s", p.ID)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureReplay(t *testing.T) {
+	const legacy = "legacysecret"
+	loadConfig(t, "auth: {api_value: "+legacy+", signing: {mode: optional}}\n")
+	req := signed(legacy, legacyId, "replay-"+strconv.FormatInt(time.Now().UnixNano(), 36), time.Now())
+
+	if _, err := VerifySignature(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifySignature(req); !errors.Is(err, ErrReplay) {
+		t.Fatalf("tekrar: hata = %v, ErrReplay bekleniyordu", err)
+	}
+}
+
+// TestStoredKeySigning depodaki anahtarların imza anahtarının secret anahtarıyla şifrelenip
+// saklandığını ve secret anahtarı yokken oluşturulan anahtarların imzalayamadığını doğrular.
+func TestStoredKeySigning(t *testing.T) {
+	loadConfig(t, "auth: {signing: {mode: required}}\n")
+	saved := store
+	t.Cleanup(func() { store = saved })
+
+	tests := []struct {
+		name      string
+		secretKey string // LOGSERVER_SECRET_KEY
+		wantErr   error
+	}{
+		{"secret anahtarı var", "testpass", nil},
+		{"secret anahtarı yok", "", ErrNoSigningKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LOGSERVER_SECRET_KEY", tt.secretKey)
+			secretKeys.ks = nil
+
+			s, err := OpenKeyStore(filepath.Join(t.TempDir(), "api_keys.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			store = s
+			info, secret, err := s.Create(CreateKeyRequest{Name: "test", Scopes: []string{ScopeReadAll}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sealed := s.file.Keys[0].SigningKey; strings.Contains(sealed, secret) || (tt.secretKey != "" && !strings.HasPrefix(sealed, "ENC(")) {
+				t.Fatalf("imza anahtarı şifreli saklanmadı: %q", sealed)
+			}
+
+			p, err := VerifySignature(signed(secret, info.ID, "stored-"+strconv.FormatInt(time.Now().UnixNano(), 36), time.Now()))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("hata = %v, %v bekleniyordu", err, tt.wantErr)
+			}
+			if err == nil && p.ID != info.ID {
+				t.Errorf("kimlik = %s, %s bekleniyordu", p.ID, info.ID)
+			}
+		})
+	}
+}
+
+func TestReplayCache(t *testing.T) {
+	now := time.Now()
+	type entry struct {
+		nonce   string
+		expires time.Duration // now'a göre
+	}
+	tests := []struct {
+		name      string
+		size      int
+		existing  []entry // Eklenme sırasıyla
+		nonce     string
+		wantErr   error
+		wantRetry time.Duration
+	}{
+		{"yeni nonce", 2, nil, "a", nil, 0},
+		{"tekrar", 2, []entry{{"a", time.Minute}}, "a", ErrReplay, 0},
+		{"süresi dolmuş nonce tekrar kullanılabilir", 1, []entry{{"a", -time.Second}}, "a", nil, 0},
+		{"dolu, süresi dolmamış kayıt atılmaz", 1, []entry{{"a", time.Minute}}, "b", ErrNonceCacheFull, time.Minute},
+		{"dolu ama en eskinin süresi dolmuş", 1, []entry{{"a", -time.Second}}, "b", nil, 0},
+		{"boyut küçültülmüş", 1, []entry{{"a", time.Minute}, {"b", 2 * time.Minute}}, "c", ErrNonceCacheFull, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newReplayCache()
+			for _, e := range tt.existing {
+				c.entries[e.nonce] = c.order.PushBack(replayEntry{nonce: e.nonce, expires: now.Add(e.expires)})
+			}
+
+			err := c.add(tt.nonce, now.Add(time.Minute), tt.size, now)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("add hata = %v, %v bekleniyordu", err, tt.wantErr)
+			}
+			if tt.wantErr == ErrNonceCacheFull {
+				if got := c.retryAfter(now); got != tt.wantRetry {
+					t.Errorf("retryAfter = %v, %v bekleniyordu", got, tt.wantRetry)
+				}
+				if c.order.Len() != len(tt.existing) {
+					t.Errorf("süresi dolmamış kayıtlar atıldı: %d kayıt kaldı", c.order.Len())
+				}
+			}
+		})
+	}
+}
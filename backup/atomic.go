@@ -0,0 +1,467 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log-server/archive"
+	"log-server/config"
+	"log-server/lock"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	yzip "github.com/yeka/zip"
+)
+
+// Arşiv oluşturma protokolü (crash-safe):
+//  1. Intent journal'a "writing" durumunda kayıt yazılır (hangi zip'ler, hangi kaynaklar).
+//  2. Her arşiv (zip veya lsa) önce <final>.partial olarak yazılır, fsync edilir ve açılıp doğrulanır.
+//  3. Kısmen arşivlenen kaynakların kalan event'leri <kaynak>.rest olarak yazılır ve fsync edilir.
+//  4. Intent "committing" durumuna geçer; bu noktadan sonra işlem her durumda tamamlanır.
+//  5. .partial dosyalar nihai adlarına rename edilir, dizin fsync edilir.
+//  6. Kaynaklar silinir veya .rest ile değiştirilir, intent silinir.
+//
+// Başlangıçta RecoverArchives yarım kalan intent'leri işler: "writing" durumundakiler
+// geri alınır (kaynaklar yerinde kalır), "committing" durumundakiler tamamlanır.
+
+const (
+	intentWriting    = "writing"
+	intentCommitting = "committing"
+
+	partialSuffix = ".partial"
+	restSuffix    = ".rest"
+)
+
+// archiveIntent tek bir home_id için yapılan arşivleme işleminin niyet kaydı.
+type archiveIntent struct {
+	ID        string          `json:"id"`
+	Job       string          `json:"job"`
+	HomeIdDir string          `json:"home_id_dir"`
+	CreatedAt time.Time       `json:"created_at"`
+	State     string          `json:"state"`
+	Archives  []intentArchive `json:"archives"`
+	Sources   []intentSource  `json:"sources"`
+}
+
+type intentArchive struct {
+	Day       string `json:"day"`
+	TmpPath   string `json:"tmp_path"`
+	FinalPath string `json:"final_path"`
+	Events    int    `json:"events"`
+}
+
+type intentSource struct {
+	Path     string `json:"path"`
+	RestPath string `json:"rest_path,omitempty"` // Boşsa kaynak tamamen arşivlendi, silinecek
+	Archive  string `json:"archive"`             // Deletion journal için ilgili zip
+}
+
+// eventWriter bir arşive veya kaynağa yazılacak event'leri NDJSON olarak w'ye yazar,
+// yazılan event sayısını döner.
+type eventWriter func(w io.Writer) (int, error)
+
+// ctxWriter ctx iptal edildiğinde (ör: home kilidi kaybedildiğinde) yazmayı keser.
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw ctxWriter) Write(p []byte) (int, error) {
+	if err := context.Cause(cw.ctx); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
+
+// withContext eventWriter'ı ctx iptal edildiğinde duracak şekilde sarar.
+func (ew eventWriter) withContext(ctx context.Context) eventWriter {
+	return func(w io.Writer) (int, error) {
+		return ew(ctxWriter{ctx: ctx, w: w})
+	}
+}
+
+// pendingArchive commitArchives'a verilen, oluşturulacak bir günlük arşiv.
+type pendingArchive struct {
+	day   string
+	write eventWriter
+}
+
+// pendingSource arşivlemeden etkilenen bir kaynak dosya. remaining nil ise dosya silinir.
+type pendingSource struct {
+	path      string
+	day       string // Deletion journal'da ilişkilendirilecek arşivin günü
+	remaining eventWriter
+}
+
+func intentDir() string {
+	return filepath.Join(config.Get().KettasLog.Backup.BackupDir, ".intents")
+}
+
+func newIntentId() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405"), hex.EncodeToString(b))
+}
+
+// commitArchives arşivleri ve kaynak değişikliklerini yukarıdaki protokolle uygular.
+// ctx home kilidinin context'idir; commit noktasından önce iptal edilirse (kilit kaybedildiyse)
+// yazılanlar geri alınır ve kaynaklara dokunulmaz.
+// Dönen değerler: oluşan zip yolları, silinen kaynak sayısı, hata
+func commitArchives(ctx context.Context, job, backupDir, homeIdDir, password string, archives []pendingArchive, sources []pendingSource) ([]string, int, error) {
+	targetBackupDir := filepath.Join(backupDir, homeIdDir)
+	if err := os.MkdirAll(targetBackupDir, 0755); err != nil {
+		return nil, 0, fmt.Errorf("backup dizini oluşturulamadı: %w", err)
+	}
+
+	intent := &archiveIntent{
+		ID:        newIntentId(),
+		Job:       job,
+		HomeIdDir: homeIdDir,
+		CreatedAt: time.Now(),
+		State:     intentWriting,
+	}
+
+	ext := archiveExt()
+	finalByDay := make(map[string]string)
+	for _, a := range archives {
+		baseName := fmt.Sprintf("%s_%s_all_event_log", homeIdDir, a.day)
+		finalPath := getNextArchiveName(targetBackupDir, baseName, ext)
+		finalByDay[a.day] = finalPath
+		intent.Archives = append(intent.Archives, intentArchive{
+			Day:       a.day,
+			TmpPath:   finalPath + partialSuffix,
+			FinalPath: finalPath,
+		})
+	}
+	for _, src := range sources {
+		is := intentSource{Path: src.path, Archive: finalByDay[src.day]}
+		if src.remaining != nil {
+			is.RestPath = src.path + restSuffix
+		}
+		intent.Sources = append(intent.Sources, is)
+	}
+
+	if err := writeIntent(intent); err != nil {
+		return nil, 0, err
+	}
+
+	// 2. Zip'leri geçici adlarıyla yaz ve doğrula
+	for i, a := range archives {
+		ia := &intent.Archives[i]
+		n, err := writeArchiveDurable(ia.TmpPath, ia.FinalPath, homeIdDir, password, a.write.withContext(ctx))
+		if err == nil {
+			ia.Events = n
+			err = verifyArchive(ia.TmpPath, n)
+		}
+		if err != nil {
+			rollbackIntent(intent)
+			return nil, 0, fmt.Errorf("%s arşivi oluşturulamadı: %w", a.day, err)
+		}
+	}
+
+	// 3. Kısmen arşivlenen kaynakların kalanını yaz
+	for i, src := range sources {
+		if src.remaining == nil {
+			continue
+		}
+		if _, err := writeFileDurable(intent.Sources[i].RestPath, src.remaining.withContext(ctx)); err != nil {
+			rollbackIntent(intent)
+			return nil, 0, fmt.Errorf("kalan event'ler yazılamadı (%s): %w", src.path, err)
+		}
+	}
+
+	// 4. Commit noktası. Kilit kaybedildiyse başka bir instance aynı kaynakları arşivliyor
+	// olabilir; commit edilmez.
+	if err := context.Cause(ctx); err != nil {
+		rollbackIntent(intent)
+		return nil, 0, fmt.Errorf("arşivleme durduruldu: %w", err)
+	}
+	intent.State = intentCommitting
+	if err := writeIntent(intent); err != nil {
+		rollbackIntent(intent)
+		return nil, 0, err
+	}
+
+	// 5-6. Tamamla
+	return finishIntent(intent)
+}
+
+// finishIntent "committing" durumundaki bir intent'i tamamlar. İdempotenttir;
+// yarıda kesilmiş bir finishIntent'in devamı olarak da çağrılabilir.
+func finishIntent(intent *archiveIntent) ([]string, int, error) {
+	var zipPaths []string
+	for _, ia := range intent.Archives {
+		if _, err := os.Stat(ia.TmpPath); err == nil {
+			if err := os.Rename(ia.TmpPath, ia.FinalPath); err != nil {
+				return nil, 0, fmt.Errorf("arşiv yerine taşınamadı: %w", err)
+			}
+		}
+		// Kaynakları silmeden önce nihai arşivi tekrar doğrula
+		if err := verifyArchive(ia.FinalPath, ia.Events); err != nil {
+			return nil, 0, fmt.Errorf("arşiv doğrulanamadı, kaynaklar korunuyor (%s): %w", ia.FinalPath, err)
+		}
+		zipPaths = append(zipPaths, ia.FinalPath)
+	}
+	if len(intent.Archives) > 0 {
+		syncDir(filepath.Dir(intent.Archives[0].FinalPath))
+	}
+
+	deletedCount := 0
+	for _, src := range intent.Sources {
+		if src.RestPath != "" {
+			if _, err := os.Stat(src.RestPath); err == nil {
+				if err := os.Rename(src.RestPath, src.Path); err != nil {
+					slog.Error("Kısmen arşivlenen dosya yeniden yazılamadı", "file", src.Path, "error", err)
+					continue
+				}
+				slog.Info("Birden fazla güne ait dosya bölündü", "file", src.Path)
+			}
+			continue
+		}
+
+		if err := removeAndJournal(src.Path, nil, intent.Job, reasonArchived, intent.HomeIdDir, src.Archive); err == nil {
+			deletedCount++
+		} else if !os.IsNotExist(err) {
+			slog.Error("Arşivlenen kaynak silinemedi", "file", src.Path, "error", err)
+		}
+	}
+	if len(intent.Sources) > 0 {
+		syncDir(filepath.Dir(intent.Sources[0].Path))
+	}
+
+	if err := os.Remove(intentPath(intent.ID)); err != nil && !os.IsNotExist(err) {
+		slog.Error("Intent kaydı silinemedi", "id", intent.ID, "error", err)
+	}
+	return zipPaths, deletedCount, nil
+}
+
+// rollbackIntent "writing" durumundaki bir intent'in geçici dosyalarını siler.
+// Kaynak dosyalara dokunulmadığı için veri kaybı olmaz.
+func rollbackIntent(intent *archiveIntent) {
+	for _, ia := range intent.Archives {
+		os.Remove(ia.TmpPath)
+	}
+	for _, src := range intent.Sources {
+		if src.RestPath != "" {
+			os.Remove(src.RestPath)
+		}
+	}
+	if err := os.Remove(intentPath(intent.ID)); err != nil && !os.IsNotExist(err) {
+		slog.Error("Intent kaydı silinemedi", "id", intent.ID, "error", err)
+	}
+}
+
+// RecoverArchives önceki çalışmadan yarım kalan arşivleme işlemlerini geri alır veya tamamlar.
+// İşler başlamadan önce çağrılmalıdır.
+func RecoverArchives() {
+	entries, err := os.ReadDir(intentDir())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("Intent dizini okunamadı", "error", err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(intentDir(), entry.Name()))
+		if err != nil {
+			slog.Error("Intent okunamadı", "file", entry.Name(), "error", err)
+			continue
+		}
+		var intent archiveIntent
+		if err := json.Unmarshal(data, &intent); err != nil {
+			slog.Error("Intent çözülemedi", "file", entry.Name(), "error", err)
+			continue
+		}
+
+		release, ok, err := lock.Get().TryLock(lock.HomeKey(intent.HomeIdDir))
+		if err != nil || !ok {
+			slog.Warn("Intent kurtarma atlandı, home kilitli", "id", intent.ID, "home_id_dir", intent.HomeIdDir)
+			continue
+		}
+
+		switch intent.State {
+		case intentCommitting:
+			zipPaths, deleted, err := finishIntent(&intent)
+			if err != nil {
+				slog.Error("Yarım kalan arşivleme tamamlanamadı", "id", intent.ID, "error", err)
+			} else {
+				slog.Info("Yarım kalan arşivleme tamamlandı", "id", intent.ID, "zips", zipPaths, "deleted_count", deleted)
+			}
+		default:
+			rollbackIntent(&intent)
+			slog.Info("Yarım kalan arşivleme geri alındı", "id", intent.ID, "home_id_dir", intent.HomeIdDir)
+		}
+		release()
+	}
+}
+
+func intentPath(id string) string {
+	return filepath.Join(intentDir(), id+".json")
+}
+
+// writeIntent intent'i geçici dosyaya yazıp fsync ettikten sonra atomik olarak yerine koyar.
+func writeIntent(intent *archiveIntent) error {
+	if err := os.MkdirAll(intentDir(), 0755); err != nil {
+		return fmt.Errorf("intent dizini oluşturulamadı: %w", err)
+	}
+	data, err := json.MarshalIndent(intent, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := intentPath(intent.ID)
+	if _, err := writeFileDurable(path+".tmp", func(w io.Writer) (int, error) {
+		_, err := w.Write(data)
+		return 0, err
+	}); err != nil {
+		return fmt.Errorf("intent yazılamadı: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("intent yazılamadı: %w", err)
+	}
+	syncDir(intentDir())
+	return nil
+}
+
+// writeFileDurable dosyayı write ile oluşturur ve kapatmadan önce fsync eder.
+func writeFileDurable(path string, write eventWriter) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(f)
+	n, err := write(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return n, nil
+}
+
+// writeZipDurable tek entry'li şifreli zip'i path'e yazar ve fsync eder.
+func writeZipDurable(path, entryName, password, comment string, write eventWriter) (int, error) {
+	return writeFileDurable(path, func(w io.Writer) (int, error) {
+		archive := yzip.NewWriter(w)
+
+		var entry io.Writer
+		var err error
+		if password != "" {
+			fh := &yzip.FileHeader{Name: entryName, Method: yzip.Deflate, Comment: comment}
+			fh.SetPassword(password)
+			fh.SetEncryptionMethod(yzip.AES256Encryption)
+			entry, err = archive.CreateHeader(fh)
+		} else {
+			entry, err = archive.CreateHeader(&yzip.FileHeader{Name: entryName, Method: yzip.Deflate})
+		}
+		if err != nil {
+			return 0, fmt.Errorf("zip entry oluşturulamadı: %w", err)
+		}
+
+		n, err := write(entry)
+		if err != nil {
+			return 0, fmt.Errorf("zip yazma hatası: %w", err)
+		}
+		if err := archive.Close(); err != nil {
+			return 0, fmt.Errorf("zip kapatılamadı: %w", err)
+		}
+		return n, nil
+	})
+}
+
+// verifyArchive arşivi açıp (şifre ve CRC/AES-GCM doğrulamasıyla) okur ve
+// içindeki event sayısının beklenenle aynı olduğunu kontrol eder.
+func verifyArchive(path string, expectedEvents int) error {
+	creds := ArchiveCredentials()
+	if archive.IsLSA(strings.TrimSuffix(path, partialSuffix)) {
+		count, err := archive.Verify(path, creds)
+		if err != nil {
+			return err
+		}
+		if count != expectedEvents {
+			return fmt.Errorf("event sayısı uyuşmuyor: beklenen %d, bulunan %d", expectedEvents, count)
+		}
+		return nil
+	}
+	return verifyZipEvents(path, creds, expectedEvents)
+}
+
+// verifyZipEvents tek entry'li zip için verifyArchive.
+func verifyZipEvents(zipPath string, creds archive.Credentials, expectedEvents int) error {
+	r, err := yzip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("zip açılamadı: %w", err)
+	}
+	defer r.Close()
+
+	if len(r.File) != 1 {
+		return fmt.Errorf("zip'te 1 entry bekleniyordu, %d var", len(r.File))
+	}
+
+	f := r.File[0]
+	if err := archive.OpenZipEntry(f, creds); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("zip entry açılamadı: %w", err)
+	}
+	defer rc.Close()
+
+	count, err := countLines(rc)
+	if err != nil {
+		return fmt.Errorf("zip entry okunamadı: %w", err)
+	}
+	if count != expectedEvents {
+		return fmt.Errorf("event sayısı uyuşmuyor: beklenen %d, bulunan %d", expectedEvents, count)
+	}
+	return nil
+}
+
+// countLines boş olmayan satırları sayar (NDJSON event sayısı).
+func countLines(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	count := 0
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
+
+// syncDir rename/silme işlemlerinin kalıcı olması için dizini fsync eder.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		slog.Warn("Dizin fsync edilemedi", "dir", dir, "error", err)
+	}
+}
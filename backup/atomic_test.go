@@ -0,0 +1,160 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRecoverArchives yarıda kesilen arşivlemenin her aşamasından sonra RecoverArchives'ın her
+// event'i kaynaklarda veya arşivde tam olarak bir kez bıraktığını doğrular.
+//
+// Kaynaklar: a.json (tamamı 14_03'e arşivlenir) ve b.json (b1 arşivlenir, b2 .rest ile kalır).
+func TestRecoverArchives(t *testing.T) {
+	tests := []struct {
+		name        string
+		state       string
+		renamed     bool // Kesilmeden önce .partial nihai adına taşınmıştı
+		restApplied bool // .rest kaynağın yerine konmuştu
+		fullDeleted bool // Tamamen arşivlenen kaynak silinmişti
+		archiveLost bool // Arşiv hiç yazılamamış (ne .partial ne nihai dosya var)
+		wantArchive bool
+		wantIntent  bool
+	}{
+		{name: "writing: geri alınır", state: intentWriting},
+		{name: "writing, arşiv yok", state: intentWriting, archiveLost: true},
+		{name: "committing: rename öncesi", state: intentCommitting, wantArchive: true},
+		{name: "committing: rename sonrası", state: intentCommitting, renamed: true, wantArchive: true},
+		{name: "committing: .rest uygulanmış", state: intentCommitting, renamed: true, restApplied: true, wantArchive: true},
+		{name: "committing: kaynak silinmiş", state: intentCommitting, renamed: true, restApplied: true, fullDeleted: true, wantArchive: true},
+		{name: "committing: arşiv kayıp, kaynaklar korunur", state: intentCommitting, archiveLost: true, wantIntent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			loadConfig(t, dir)
+			homeIdDir := "home_id_h1"
+			logsDir := filepath.Join(dir, "logs", homeIdDir)
+			archiveDir := filepath.Join(dir, "backups", homeIdDir)
+			for _, d := range []string{logsDir, archiveDir} {
+				if err := os.MkdirAll(d, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			srcA, srcB := filepath.Join(logsDir, "a.json"), filepath.Join(logsDir, "b.json")
+			final := filepath.Join(archiveDir, homeIdDir+"_14_03_2024_all_event_log.zip")
+			intent := &archiveIntent{
+				ID:        "test",
+				Job:       "test",
+				HomeIdDir: homeIdDir,
+				State:     tt.state,
+				Archives:  []intentArchive{{Day: "14_03_2024", TmpPath: final + partialSuffix, FinalPath: final, Events: 3}},
+				Sources: []intentSource{
+					{Path: srcA, Archive: final},
+					{Path: srcB, RestPath: srcB + restSuffix, Archive: final},
+				},
+			}
+
+			writeEvents(t, srcA, "a1", "a2")
+			writeEvents(t, srcB, "b1", "b2")
+			writeEvents(t, srcB+restSuffix, "b2")
+			if !tt.archiveLost {
+				path := final + partialSuffix
+				if tt.renamed {
+					path = final
+				}
+				if _, err := writeArchiveDurable(path, final, homeIdDir, "test", func(w io.Writer) (int, error) {
+					_, err := io.WriteString(w, event("a1")+event("a2")+event("b1"))
+					return 3, err
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.restApplied {
+				if err := os.Rename(srcB+restSuffix, srcB); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.fullDeleted {
+				if err := os.Remove(srcA); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writeIntent(intent); err != nil {
+				t.Fatal(err)
+			}
+
+			RecoverArchives()
+
+			counts := make(map[string]int)
+			if _, err := os.Stat(final); err == nil {
+				if !tt.wantArchive {
+					t.Fatal("geri alınan arşiv nihai adında")
+				}
+				var buf bytes.Buffer
+				if _, err := CatArchive(&buf, final, time.Time{}, time.Time{}); err != nil {
+					t.Fatal(err)
+				}
+				countEvents(t, buf.String(), counts)
+			} else if tt.wantArchive {
+				t.Fatal("arşiv oluşmadı")
+			}
+			for _, path := range []string{srcA, srcB} {
+				if data, err := os.ReadFile(path); err == nil {
+					countEvents(t, string(data), counts)
+				}
+			}
+			for _, id := range []string{"a1", "a2", "b1", "b2"} {
+				if counts[id] != 1 {
+					t.Errorf("%s %d kez bulundu, 1 bekleniyordu (%v)", id, counts[id], counts)
+				}
+			}
+
+			for _, path := range []string{final + partialSuffix, srcB + restSuffix} {
+				if _, err := os.Stat(path); err == nil && !tt.wantIntent {
+					t.Errorf("%s kaldı", filepath.Base(path))
+				}
+			}
+			if _, err := os.Stat(intentPath(intent.ID)); (err == nil) != tt.wantIntent {
+				t.Errorf("intent kaldı = %v, %v bekleniyordu", err == nil, tt.wantIntent)
+			}
+		})
+	}
+}
+
+func event(id string) string {
+	return fmt.Sprintf("{\"id\":%q,\"ts\":\"2024-03-14T10:00:00Z\"}\n", id)
+}
+
+func writeEvents(t *testing.T, path string, ids ...string) {
+	t.Helper()
+	var sb strings.Builder
+	for _, id := range ids {
+		sb.WriteString(event(id))
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// countEvents NDJSON'daki event id'lerini counts'a ekler.
+func countEvents(t *testing.T, ndjson string, counts map[string]int) {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(ndjson), "\n") {
+		if line == "" {
+			continue
+		}
+		var e struct{ Id string }
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		counts[e.Id]++
+	}
+}
@@ -1,27 +1,29 @@
 package backup
 
 import (
+	"errors"
+	"fmt"
 	"log-server/config"
+	"log-server/scheduler"
 	"log/slog"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
 type BackupManager struct {
-	stopChan chan struct{}
-	wg       sync.WaitGroup
+	wg sync.WaitGroup
 }
 
 func NewBackupManager() *BackupManager {
-	return &BackupManager{
-		stopChan: make(chan struct{}),
-	}
+	return &BackupManager{}
 }
 
+// Start rotation, cleanup ve scrub işlerini scheduler'a kaydeder.
+// Zamanlamalar kettas_log.backup.jobs altından okunur; rotation ve cleanup
+// için varsayılan check_interval_min aralığıdır.
 func (bm *BackupManager) Start() {
 	slog.Info("Backup manager starting")
 	cfg := config.Get()
@@ -30,39 +32,43 @@ func (bm *BackupManager) Start() {
 		return
 	}
 
+	jobs := cfg.KettasLog.Backup.Jobs
+	registerJob("rotation", jobGroupLogs, jobs.Rotation, checkIntervalSpec(), func(time.Time) (scheduler.Result, error) {
+		return result(bm.checkAndRotate())
+	})
+	registerJob("cleanup", jobGroupBackups, jobs.Cleanup, checkIntervalSpec(), func(time.Time) (scheduler.Result, error) {
+		return result(bm.cleanupBackups())
+	})
+	registerJob("scrub", jobGroupBackups, jobs.Scrub, "@weekly", func(time.Time) (scheduler.Result, error) {
+		return result(bm.scrubBackups())
+	})
+	// Varsayılan olarak sadece manuel; jobs.export.cron verilirse zamanlanmış çalışmada
+	// bir önceki günün arşivleri tüm evler için export edilir.
+	registerJob("parquet_export", jobGroupBackups, jobs.Export, scheduler.SpecManual, func(scheduledAt time.Time) (scheduler.Result, error) {
+		files, err := ExportParquet(ExportRequest{StartDate: scheduledAt.AddDate(0, 0, -1).Format(dayLayout)})
+		return result(len(files), err)
+	})
+
 	bm.wg.Add(1)
 	go func() {
 		defer bm.wg.Done()
 		slog.Info("Backup manager started", "interval_min", cfg.KettasLog.Backup.CheckIntervalMin)
 
-		ticker := time.NewTicker(time.Duration(cfg.KettasLog.Backup.CheckIntervalMin) * time.Minute)
-		defer ticker.Stop()
-
 		// İlk başlangıçta bir kez çalıştır
-		bm.checkAndRotate()
-		bm.cleanupBackups()
-
-		for {
-			select {
-			case <-ticker.C:
-				bm.checkAndRotate()
-				bm.cleanupBackups()
-			case <-bm.stopChan:
-				slog.Info("Backup manager stopping")
-				return
-			}
-		}
+		scheduler.Get().RunNow("rotation", scheduler.RunOptions{Trigger: scheduler.TriggerStartup})
+		scheduler.Get().RunNow("cleanup", scheduler.RunOptions{Trigger: scheduler.TriggerStartup})
 	}()
 }
 
+// Stop başlangıç çalışmasının bitmesini bekler. Zamanlanmış işler scheduler ile durdurulur.
 func (bm *BackupManager) Stop() {
-	close(bm.stopChan)
 	bm.wg.Wait()
 }
 
 // checkAndRotate logs klasörü boyutunu kontrol eder, limit aşılmışsa
 // her home_id için tüm JSON'ları birleştirip zipleyerek backups'a taşır.
-func (bm *BackupManager) checkAndRotate() {
+// Arşivlenip silinen dosya sayısını döner.
+func (bm *BackupManager) checkAndRotate() (int, error) {
 	slog.Info("checkAndRotate")
 	cfg := config.Get()
 	logsDir := cfg.KettasLog.LogsDir
@@ -71,7 +77,7 @@ func (bm *BackupManager) checkAndRotate() {
 	size, err := getDirSize(logsDir)
 	if err != nil {
 		slog.Error("Failed to calculate logs dir size", "error", err)
-		return
+		return 0, fmt.Errorf("logs dizini boyutu hesaplanamadı: %w", err)
 	}
 
 	maxSizeBytes := cfg.KettasLog.MaxFolderSizeMB * 1024 * 1024
@@ -82,21 +88,24 @@ func (bm *BackupManager) checkAndRotate() {
 			"current_size_mb", size/1024/1024,
 			"max_size_mb", cfg.KettasLog.MaxFolderSizeMB,
 		)
-		bm.rotateLogsPerHomeId(logsDir, cfg.KettasLog.Backup.BackupDir, cfg.KettasLog.ZipPassword)
+		return bm.rotateLogsPerHomeId(logsDir, cfg.KettasLog.Backup.BackupDir, cfg.KettasLog.ZipPassword)
 	}
+	return 0, nil
 }
 
 // rotateLogsPerHomeId her home_id klasörü için ayrı ayrı:
-// tüm JSON'ları birleştirip backups/{home_id}/full_DD_MM_YYYY.zip olarak zipleyip siler.
-func (bm *BackupManager) rotateLogsPerHomeId(logsDir, backupDir, password string) {
-	today := time.Now().Format("02_01_2006")
-
+// tüm event'leri günlerine göre backups/{home_id}/{home_id}_DD_MM_YYYY_all_event_log.zip
+// olarak zipleyip kaynak dosyaları siler.
+func (bm *BackupManager) rotateLogsPerHomeId(logsDir, backupDir, password string) (int, error) {
 	entries, err := os.ReadDir(logsDir)
 	if err != nil {
 		slog.Error("Logs dizini okunamadı", "error", err)
-		return
+		return 0, fmt.Errorf("logs dizini okunamadı: %w", err)
 	}
 
+	var totalDeleted int
+	var errs []error
+
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -116,13 +125,15 @@ func (bm *BackupManager) rotateLogsPerHomeId(logsDir, backupDir, password string
 			"file_count", len(allJSONFiles),
 		)
 
-		zipPath, deletedCount, err := mergeAndZipFiles(allJSONFiles, homeIdPath, backupDir, homeIdDir, today, password)
+		zipPaths, deletedCount, err := archiveHomeDays("rotation", homeIdPath, backupDir, homeIdDir, password, func(string) bool { return true })
+		totalDeleted += deletedCount
 		if err != nil {
 			slog.Error("Size rotation hatası", "home_id_dir", homeIdDir, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", homeIdDir, err))
 			continue
 		}
 
-		if zipPath != "" {
+		for _, zipPath := range zipPaths {
 			slog.Info("Size rotation zip oluşturuldu",
 				"zip_path", zipPath,
 				"home_id_dir", homeIdDir,
@@ -136,76 +147,47 @@ func (bm *BackupManager) rotateLogsPerHomeId(logsDir, backupDir, password string
 	cleanEmptyDirs(logsDir)
 
 	slog.Info("Size rotation tamamlandı")
+	return totalDeleted, errors.Join(errs...)
 }
 
 // cleanupBackups, yedekleme klasöründe saklama kurallarını uygular.
-func (bm *BackupManager) cleanupBackups() {
+// Silinecek dosyalar planCleanup ile hesaplanır, her silme deletion journal'a yazılır.
+// Silinen dosya sayısını döner.
+func (bm *BackupManager) cleanupBackups() (int, error) {
 	slog.Info("cleanupBackups")
 	cfg := config.Get()
 	backupDir := cfg.KettasLog.Backup.BackupDir
 
-	// backups/ altındaki tüm zip dosyalarını recursive bul
-	var backupFiles []backupFileInfo
-	var totalSize int64
-
-	err := filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".zip") {
-			return nil
-		}
-		backupFiles = append(backupFiles, backupFileInfo{path: path, info: info})
-		totalSize += info.Size()
-		return nil
-	})
+	plan, err := planCleanup()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return
-		}
-		slog.Error("Failed to walk backup dir", "error", err)
-		return
-	}
-
-	// 1. Time Retention Check
-	for i := len(backupFiles) - 1; i >= 0; i-- {
-		f := backupFiles[i]
-		age := time.Since(f.info.ModTime())
-		if age.Hours() > float64(cfg.KettasLog.Backup.RetentionDays*24) {
-			slog.Info("Deleting old backup due to retention time", "file", f.path, "age_days", int(age.Hours()/24))
-			if err := os.Remove(f.path); err == nil {
-				totalSize -= f.info.Size()
-			}
-			backupFiles = append(backupFiles[:i], backupFiles[i+1:]...)
-		}
+		slog.Error("Failed to plan backup cleanup", "error", err)
+		return 0, err
 	}
 
-	// 2. Size Retention Check
-	maxSizeBytes := cfg.KettasLog.Backup.MaxBackupSizeMB * 1024 * 1024
-	if totalSize > maxSizeBytes {
-		slog.Info("Backup dir size exceeded limit, cleaning old backups",
-			"current_mb", totalSize/1024/1024,
+	if len(plan.Actions) > 0 {
+		slog.Info("Backup cleanup planı uygulanıyor",
+			"file_count", len(plan.Actions),
+			"total_mb", plan.TotalBytes/1024/1024,
+			"current_mb", plan.CurrentBytes/1024/1024,
 			"max_mb", cfg.KettasLog.Backup.MaxBackupSizeMB,
 		)
+	}
 
-		// Dosyaları eskiden yeniye sırala
-		sort.Slice(backupFiles, func(i, j int) bool {
-			return backupFiles[i].info.ModTime().Before(backupFiles[j].info.ModTime())
-		})
-
-		for _, f := range backupFiles {
-			if totalSize <= maxSizeBytes {
-				break
-			}
-			slog.Info("Deleting backup to free space", "file", f.path)
-			if err := os.Remove(f.path); err == nil {
-				totalSize -= f.info.Size()
-			}
+	var deleted int
+	var errs []error
+	for _, a := range plan.Actions {
+		slog.Info("Deleting backup", "file", a.Path, "reason", a.Reason)
+		if err := removeAndJournal(a.Path, nil, "cleanup", a.Reason, a.HomeIdDir, ""); err != nil {
+			slog.Error("Backup silinemedi", "file", a.Path, "error", err)
+			errs = append(errs, err)
+			continue
 		}
+		deleted++
 	}
 
 	// Boş backup alt klasörlerini temizle
 	cleanEmptyDirs(backupDir)
+	return deleted, errors.Join(errs...)
 }
 
 // Helpers
@@ -253,7 +235,8 @@ func cleanEmptyDirs(parentDir string) {
 		return
 	}
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		// .intents, .locks gibi sistem klasörlerine dokunma
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
 		subDir := filepath.Join(parentDir, entry.Name())
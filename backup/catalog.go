@@ -0,0 +1,280 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log-server/archive"
+	"log-server/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CatalogEntry tek bir arşivin katalog kaydı.
+type CatalogEntry struct {
+	HomeIdDir string     `json:"home_id_dir"`
+	Day       string     `json:"day"` // DD_MM_YYYY
+	Path      string     `json:"path"`
+	Format    string     `json:"format"` // "zip", "lsa"
+	SizeBytes int64      `json:"size_bytes"`
+	ModTime   time.Time  `json:"mod_time"`
+	Events    int        `json:"events"`
+	KeyId     string     `json:"key_id,omitempty"`
+	MinTime   *time.Time `json:"min_time,omitempty"` // Yalnızca .lsa (index'ten)
+	MaxTime   *time.Time `json:"max_time,omitempty"`
+	Error     string     `json:"error,omitempty"` // Arşiv okunamadıysa
+}
+
+// Catalog backup klasöründeki tüm arşivlerin envanteri.
+type Catalog struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Archives    []CatalogEntry `json:"archives"`
+	Corrupt     int            `json:"corrupt"`
+}
+
+// HomeSummary bir evin canlı log ve arşiv özeti.
+type HomeSummary struct {
+	HomeId       string `json:"home_id"`
+	LiveFiles    int    `json:"live_files"`
+	LiveBytes    int64  `json:"live_bytes"`
+	Archives     int    `json:"archives"`
+	ArchiveBytes int64  `json:"archive_bytes"`
+	FirstDay     string `json:"first_day,omitempty"`
+	LastDay      string `json:"last_day,omitempty"`
+}
+
+// catalogPath katalog dosyasının yolu (backup_dir/catalog.json).
+func catalogPath() string {
+	return filepath.Join(config.Get().KettasLog.Backup.BackupDir, "catalog.json")
+}
+
+// ListHomes logs_dir ve backup_dir'deki tüm evleri özetleriyle döner.
+func ListHomes() ([]HomeSummary, error) {
+	cfg := config.Get()
+	homes := make(map[string]*HomeSummary)
+	get := func(homeIdDir string) *HomeSummary {
+		if h, ok := homes[homeIdDir]; ok {
+			return h
+		}
+		h := &HomeSummary{HomeId: strings.TrimPrefix(homeIdDir, "home_id_")}
+		homes[homeIdDir] = h
+		return h
+	}
+
+	for _, root := range []string{cfg.KettasLog.LogsDir, cfg.KettasLog.Backup.BackupDir} {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "home_id_") {
+				continue
+			}
+			h := get(entry.Name())
+			if root == cfg.KettasLog.LogsDir {
+				dir := filepath.Join(root, entry.Name())
+				for _, name := range findAllJSONFiles(dir) {
+					if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+						h.LiveFiles++
+						h.LiveBytes += info.Size()
+					}
+				}
+				continue
+			}
+
+			paths, err := homeArchives(entry.Name(), nil)
+			if err != nil {
+				return nil, err
+			}
+			for i, path := range paths {
+				if info, err := os.Stat(path); err == nil {
+					h.Archives++
+					h.ArchiveBytes += info.Size()
+				}
+				day, _ := dayFromFileName(filepath.Base(path))
+				if i == 0 {
+					h.FirstDay = day
+				}
+				h.LastDay = day
+			}
+		}
+	}
+
+	out := make([]HomeSummary, 0, len(homes))
+	for _, h := range homes {
+		out = append(out, *h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].HomeId < out[j].HomeId })
+	return out, nil
+}
+
+// HomeArchives evin arşivlerinin yollarını gün sırasıyla döner.
+func HomeArchives(homeId string) ([]string, error) {
+	return homeArchives("home_id_"+homeId, nil)
+}
+
+// VerifyArchive arşivi tamamen okuyup şifre ve bütünlük (CRC/AES-GCM) doğrulaması yapar,
+// event sayısını döner.
+func VerifyArchive(path string) (int, error) {
+	creds := ArchiveCredentials()
+	if archive.IsLSA(path) {
+		return archive.Verify(path, creds)
+	}
+	if err := verifyZip(path, creds); err != nil {
+		return 0, err
+	}
+	count := 0
+	err := archive.ForEachLine(path, creds, time.Time{}, time.Time{}, func([]byte) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// CatArchive arşivdeki event'leri NDJSON olarak w'ye yazar. .lsa arşivlerde yalnızca
+// [from, to] ile kesişen chunk'lar okunur; sıfır zamanlar sınırsızdır.
+func CatArchive(w io.Writer, path string, from, to time.Time) (int, error) {
+	count := 0
+	err := archive.ForEachLine(path, ArchiveCredentials(), from, to, func(line []byte) error {
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte{'\n'}); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// catalogEntry tek bir arşivin katalog kaydını oluşturur; arşiv okunamazsa Error doldurulur.
+func catalogEntry(homeIdDir, path string) CatalogEntry {
+	e := CatalogEntry{HomeIdDir: homeIdDir, Path: path, Format: "zip"}
+	e.Day, _ = dayFromFileName(filepath.Base(path))
+	if info, err := os.Stat(path); err == nil {
+		e.SizeBytes = info.Size()
+		e.ModTime = info.ModTime()
+	}
+	e.KeyId, _ = archive.KeyID(path)
+
+	if archive.IsLSA(path) {
+		e.Format = "lsa"
+		if r, err := archive.Open(path, ArchiveCredentials()); err == nil {
+			for _, ci := range r.Index().Chunks {
+				if ci.Events == ci.Untimed {
+					continue
+				}
+				min, max := time.UnixMilli(ci.MinTime).UTC(), time.UnixMilli(ci.MaxTime).UTC()
+				if e.MinTime == nil || min.Before(*e.MinTime) {
+					e.MinTime = &min
+				}
+				if e.MaxTime == nil || max.After(*e.MaxTime) {
+					e.MaxTime = &max
+				}
+			}
+			r.Close()
+		}
+	}
+
+	events, err := VerifyArchive(path)
+	if err != nil {
+		e.Error = err.Error()
+	}
+	e.Events = events
+	return e
+}
+
+// RebuildCatalog tüm arşivleri okuyup doğrulayarak kataloğu yeniden oluşturur ve
+// backup_dir/catalog.json'a atomik olarak yazar.
+func RebuildCatalog() (*Catalog, error) {
+	backupDir := config.Get().KettasLog.Backup.BackupDir
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return nil, fmt.Errorf("backup dizini okunamadı: %w", err)
+	}
+
+	cat := &Catalog{GeneratedAt: time.Now(), Archives: []CatalogEntry{}}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "home_id_") {
+			continue
+		}
+		paths, err := homeArchives(entry.Name(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			e := catalogEntry(entry.Name(), path)
+			if e.Error != "" {
+				cat.Corrupt++
+				slog.Error("Katalog: arşiv okunamadı", "file", path, "error", e.Error)
+			}
+			cat.Archives = append(cat.Archives, e)
+		}
+	}
+
+	if err := writeCatalog(cat); err != nil {
+		return nil, err
+	}
+	return cat, nil
+}
+
+// dropHomeFromCatalog evin kayıtlarını catalog.json'dan çıkarır ve çıkarılan kayıt sayısını
+// döner. Katalog yoksa bir şey yapmaz.
+func dropHomeFromCatalog(homeIdDir string) (int, error) {
+	data, err := os.ReadFile(catalogPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var cat Catalog
+	if err := json.Unmarshal(data, &cat); err != nil {
+		return 0, fmt.Errorf("katalog okunamadı: %w", err)
+	}
+
+	kept := cat.Archives[:0]
+	removed := 0
+	for _, e := range cat.Archives {
+		if e.HomeIdDir == homeIdDir {
+			removed++
+			if e.Error != "" {
+				cat.Corrupt--
+			}
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	cat.Archives = kept
+	return removed, writeCatalog(&cat)
+}
+
+// writeCatalog kataloğu backup_dir/catalog.json'a atomik olarak yazar.
+func writeCatalog(cat *Catalog) error {
+	data, err := json.MarshalIndent(cat, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := catalogPath()
+	if _, err := writeFileDurable(path+partialSuffix, func(w io.Writer) (int, error) {
+		_, err := w.Write(data)
+		return 0, err
+	}); err != nil {
+		return fmt.Errorf("katalog yazılamadı: %w", err)
+	}
+	if err := os.Rename(path+partialSuffix, path); err != nil {
+		return fmt.Errorf("katalog yazılamadı: %w", err)
+	}
+	return nil
+}
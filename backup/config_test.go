@@ -0,0 +1,30 @@
+package backup
+
+import (
+	"fmt"
+	"log-server/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadConfig logs_dir, backup_dir ve export/erasure dizinleri dir altında olan bir config yükler.
+func loadConfig(t *testing.T, dir string) {
+	t.Helper()
+	yaml := fmt.Sprintf(`internal_log: {log_file: %q}
+kettas_log:
+  zip_password: test
+  logs_dir: %q
+  backup: {backup_dir: %q}
+  export: {dir: %q}
+  erasure: {certificate_dir: %q}
+`, filepath.Join(dir, "app.log"), filepath.Join(dir, "logs"), filepath.Join(dir, "backups"),
+		filepath.Join(dir, "exports"), filepath.Join(dir, "erasures"))
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+}
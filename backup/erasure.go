@@ -0,0 +1,475 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log-server/archive"
+	"log-server/config"
+	"log-server/db"
+	"log-server/keys"
+	"log-server/lock"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	reasonErased = "erasure"
+
+	erasureJob = "erasure"
+)
+
+// ErrInvalidHomeId home_id dosya yolu olarak kullanılamayacak karakterler içeriyorsa döner.
+var ErrInvalidHomeId = errors.New("geçersiz home_id")
+
+// validHomeId home_id'nin backup_dir altında bir dizin adı olarak güvenle kullanılabileceğini
+// kontrol eder.
+func validHomeId(homeId string) bool {
+	return homeId != "" && !strings.ContainsAny(homeId, `/\`) && !strings.Contains(homeId, "..")
+}
+
+// ErasedFile silinen tek bir dosyanın kaydı.
+type ErasedFile struct {
+	Kind      string `json:"kind"` // "live", "archive", "export"
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+	SHA256    string `json:"sha256"`
+	KeyId     string `json:"key_id,omitempty"` // Arşiv veri anahtarıyla şifrelendiyse
+}
+
+// ErasureRecord bir evin silinmesinde neyin kaldırıldığını listeler; sertifikanın imzalanan kısmıdır.
+type ErasureRecord struct {
+	Id               string       `json:"id"`
+	HomeId           string       `json:"home_id"`
+	Instance         string       `json:"instance"`
+	StartedAt        time.Time    `json:"started_at"`
+	CompletedAt      time.Time    `json:"completed_at"`
+	Complete         bool         `json:"complete"`
+	Files            []ErasedFile `json:"files"`
+	MongoDeleted     int64        `json:"mongo_deleted"`
+	MongoEnabled     bool         `json:"mongo_enabled"`
+	KeysDestroyed    []string     `json:"keys_destroyed"`
+	PasswordArchives int          `json:"password_archives"` // zip_password ile şifreli arşivler (kopyaları crypto-shredding kapsamında değil)
+	CatalogEntries   int          `json:"catalog_entries"`   // catalog.json'dan çıkarılan arşiv kayıtları
+	Errors           []string     `json:"errors,omitempty"`
+
+	// Keyring yedeklerinde silinen anahtarlar saklama süresi dolana kadar kalır; crypto-shredding
+	// ancak KeyringBackupsExpireAt'te (keys.keyring_backup_retention_days) tamamlanmış sayılır.
+	KeyringBackupsExpireAt *time.Time `json:"keyring_backups_expire_at,omitempty"`
+	KeyringFile            string     `json:"keyring_file,omitempty"`  // Yedekleri anahtarları hâlâ içeren keyring dosyası
+	MasterKeyId            string     `json:"master_key_id,omitempty"` // Bu yedeklerdeki anahtarları saran master key
+	Notice                 string     `json:"notice,omitempty"`
+}
+
+// ErasureCertificate Ed25519 ile imzalanmış silme kaydı. İmza, Record'un JSON kodlamasının üzerindedir.
+type ErasureCertificate struct {
+	Record    json.RawMessage `json:"record"`
+	Algorithm string          `json:"algorithm"`
+	PublicKey string          `json:"public_key"` // base64, ham Ed25519 public key
+	Signature string          `json:"signature"`  // base64
+}
+
+var erasureMu sync.Mutex
+
+// erasureDir config'deki sertifika dizinini döner (varsayılan: ./erasures).
+func erasureDir() string {
+	if dir := config.Get().KettasLog.Erasure.CertificateDir; dir != "" {
+		return dir
+	}
+	return "./erasures"
+}
+
+func signingKeyPath() string {
+	if path := config.Get().KettasLog.Erasure.SigningKeyFile; path != "" {
+		return path
+	}
+	return filepath.Join(erasureDir(), "signing_key.pem")
+}
+
+// EraseHome evin canlı log dosyalarını, arşivlerini, export'larını ve MongoDB kayıtlarını siler,
+// envelope encryption aktifse evin veri anahtarlarını yok eder ve imzalı bir silme sertifikası
+// üretir. Bir adım başarısız olsa bile diğer adımlar denenir; bu durumda Complete false olur
+// ve hatalar sertifikada listelenir. İşlem idempotenttir, tekrar çağrılabilir.
+func EraseHome(homeId string) (*ErasureCertificate, *ErasureRecord, error) {
+	if !validHomeId(homeId) {
+		return nil, nil, ErrInvalidHomeId
+	}
+
+	erasureMu.Lock()
+	defer erasureMu.Unlock()
+
+	cfg := config.Get()
+	homeIdDir := fmt.Sprintf("home_id_%s", homeId)
+	instance, _ := os.Hostname()
+	rec := &ErasureRecord{
+		Id:            newErasureId(),
+		HomeId:        homeId,
+		Instance:      instance,
+		StartedAt:     time.Now().UTC(),
+		MongoEnabled:  cfg.DB.Enabled,
+		Files:         []ErasedFile{},
+		KeysDestroyed: []string{},
+	}
+
+	// Arşivleme ve upload'larla çakışmamak için evin kilidini al
+	ctx, cancel := context.WithTimeout(context.Background(), lock.HomeLockTimeout())
+	release, err := lock.Get().Lock(ctx, lock.HomeKey(homeIdDir))
+	cancel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("home kilidi alınamadı: %w", err)
+	}
+	defer release()
+
+	fail := func(step string, err error) {
+		rec.Errors = append(rec.Errors, fmt.Sprintf("%s: %v", step, err))
+		slog.Error("Ev silme adımı başarısız", "home_id", homeId, "step", step, "error", err)
+	}
+
+	dirs := []struct{ kind, path string }{
+		{"live", filepath.Join(cfg.KettasLog.LogsDir, homeIdDir)},
+		{"archive", filepath.Join(cfg.KettasLog.Backup.BackupDir, homeIdDir)},
+		{"export", filepath.Join(exportDir(), homeIdDir)},
+	}
+	for _, d := range dirs {
+		if err := eraseDir(rec, d.kind, d.path, homeIdDir); err != nil {
+			fail(d.kind, err)
+		}
+	}
+
+	// Yarım kalmış arşivlemelerin geçici dosyaları yukarıda silindi; intent'leri de kaldır
+	if err := dropHomeIntents(homeIdDir); err != nil {
+		fail("intents", err)
+	}
+
+	// Katalog arşiv yollarını, boyutlarını ve event zaman aralıklarını tutar
+	if n, err := dropHomeFromCatalog(homeIdDir); err != nil {
+		fail("catalog", err)
+	} else {
+		rec.CatalogEntries = n
+	}
+
+	if cfg.DB.Enabled {
+		if n, err := eraseMongo(homeId); err != nil {
+			fail("mongo", err)
+		} else {
+			rec.MongoDeleted = n
+		}
+	}
+
+	kr := keys.Get()
+	if kr != nil {
+		if ids, err := kr.DestroyHome(homeIdDir); err != nil {
+			fail("keys", err)
+		} else {
+			rec.KeysDestroyed = append(rec.KeysDestroyed, ids...)
+		}
+	}
+
+	rec.Complete = len(rec.Errors) == 0
+	rec.CompletedAt = time.Now().UTC()
+	if len(rec.KeysDestroyed) > 0 {
+		rec.KeyringFile, rec.MasterKeyId = kr.Path(), kr.MasterKeyID()
+		until := "saklama süreleri dolana kadar"
+		if days := cfg.KettasLog.Keys.KeyringBackupRetentionDays; days > 0 {
+			expire := rec.CompletedAt.AddDate(0, 0, days)
+			rec.KeyringBackupsExpireAt = &expire
+			until = expire.Format(time.DateOnly) + " tarihine kadar"
+		}
+		rec.Notice = fmt.Sprintf("Veri anahtarları (%s) aktif keyring'den (%s) silindi. Bu dosyanın %s öncesinde "+
+			"alınmış yedekleri anahtarları %s içerir; şifreli arşiv kopyaları o zamana kadar bu yedekler ve %s master "+
+			"key'iyle açılabilir. Beklemeden tamamlamak için master key döndürülüp eskisi imha edilmelidir.",
+			strings.Join(rec.KeysDestroyed, ", "), rec.KeyringFile, rec.CompletedAt.Format(time.RFC3339), until, rec.MasterKeyId)
+	}
+
+	cert, err := signErasure(rec)
+	if err != nil {
+		return nil, rec, fmt.Errorf("sertifika imzalanamadı: %w", err)
+	}
+	if err := saveErasureCertificate(rec, cert); err != nil {
+		return cert, rec, fmt.Errorf("sertifika kaydedilemedi: %w", err)
+	}
+
+	slog.Info("Ev verileri silindi", "home_id", homeId, "erasure_id", rec.Id, "files", len(rec.Files),
+		"mongo_deleted", rec.MongoDeleted, "keys_destroyed", len(rec.KeysDestroyed), "complete", rec.Complete)
+	return cert, rec, nil
+}
+
+// eraseDir klasördeki tüm dosyaları özetlerini kaydederek siler, ardından klasörü kaldırır.
+func eraseDir(rec *ErasureRecord, kind, dir, homeIdDir string) error {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	sort.Strings(paths)
+
+	var errs []error
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ef := ErasedFile{Kind: kind, Path: path, SizeBytes: info.Size()}
+		if ef.SHA256, err = fileSHA256(path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if kind == "archive" && archive.IsArchive(strings.TrimSuffix(path, partialSuffix)) {
+			ef.KeyId, _ = archive.KeyID(path)
+			if ef.KeyId == "" {
+				rec.PasswordArchives++
+			}
+		}
+
+		if err := removeAndJournal(path, info, erasureJob, reasonErased, homeIdDir, ""); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rec.Files = append(rec.Files, ef)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// dropHomeIntents eve ait arşivleme intent'lerini siler.
+func dropHomeIntents(homeIdDir string) error {
+	entries, err := os.ReadDir(intentDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(intentDir(), entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var intent archiveIntent
+		if err := json.Unmarshal(data, &intent); err != nil || intent.HomeIdDir != homeIdDir {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// eraseMongo evin upload sırasında eklenen (db_home_id) veya event içinde home_id taşıyan kayıtlarını siler.
+func eraseMongo(homeId string) (int64, error) {
+	coll := db.GetCollection()
+	if coll == nil {
+		return 0, fmt.Errorf("MongoDB koleksiyonu başlatılmamış")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	filter := bson.M{"$or": bson.A{
+		bson.M{"db_home_id": homeId},
+		bson.M{"home_id": homeId},
+	}}
+	res, err := coll.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func newErasureId() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("er_%s_%s", time.Now().UTC().Format("20060102T150405"), hex.EncodeToString(b))
+}
+
+func signErasure(rec *ErasureRecord) (*ErasureCertificate, error) {
+	priv, err := loadSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return &ErasureCertificate{
+		Record:    payload,
+		Algorithm: "ed25519",
+		PublicKey: base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload)),
+	}, nil
+}
+
+// VerifyErasureCertificate sertifikanın imzasını doğrular. publicKey verilirse sertifikadaki
+// anahtarın onunla aynı olması da beklenir; boşsa sertifikadaki anahtar kullanılır.
+func VerifyErasureCertificate(cert *ErasureCertificate, publicKey ed25519.PublicKey) (*ErasureRecord, error) {
+	if cert.Algorithm != "ed25519" {
+		return nil, fmt.Errorf("desteklenmeyen imza algoritması: %s", cert.Algorithm)
+	}
+	pub, err := base64.StdEncoding.DecodeString(cert.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("sertifikadaki public key geçersiz")
+	}
+	if publicKey != nil && !publicKey.Equal(ed25519.PublicKey(pub)) {
+		return nil, fmt.Errorf("sertifika beklenen anahtarla imzalanmamış")
+	}
+	sig, err := base64.StdEncoding.DecodeString(cert.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("imza çözülemedi: %w", err)
+	}
+	// Kaydedilen sertifika girintili yazıldığı için imza, kaydın kompakt hali üzerinden doğrulanır
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, cert.Record); err != nil {
+		return nil, fmt.Errorf("sertifika kaydı çözülemedi: %w", err)
+	}
+	if !ed25519.Verify(pub, payload.Bytes(), sig) {
+		return nil, fmt.Errorf("imza geçersiz")
+	}
+
+	var rec ErasureRecord
+	if err := json.Unmarshal(cert.Record, &rec); err != nil {
+		return nil, fmt.Errorf("sertifika kaydı çözülemedi: %w", err)
+	}
+	return &rec, nil
+}
+
+// ErasurePublicKey sertifikaları doğrulamak için imza anahtarının public kısmını döner.
+func ErasurePublicKey() (ed25519.PublicKey, error) {
+	priv, err := loadSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	return priv.Public().(ed25519.PublicKey), nil
+}
+
+// loadSigningKey Ed25519 imza anahtarını okur; dosya yoksa yeni bir anahtar üretip kaydeder.
+func loadSigningKey() (ed25519.PrivateKey, error) {
+	path := signingKeyPath()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return createSigningKey(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s PEM formatında değil", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("imza anahtarı çözülemedi: %w", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s bir Ed25519 anahtarı değil", path)
+	}
+	return priv, nil
+}
+
+func createSigningKey(path string) (ed25519.PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	// O_EXCL: başka bir instance aynı anda oluşturduysa onun anahtarı kullanılır
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) {
+		return loadSigningKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	slog.Info("Silme sertifikaları için yeni imza anahtarı oluşturuldu", "file", path)
+	return priv, nil
+}
+
+// saveErasureCertificate sertifikayı <certificate_dir>/<home_id_dir>_<erasure_id>.json olarak yazar.
+func saveErasureCertificate(rec *ErasureRecord, cert *ErasureCertificate) error {
+	data, err := json.MarshalIndent(cert, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := erasureDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("home_id_%s_%s.json", rec.HomeId, rec.Id))
+	_, err = writeFileDurable(path, func(w io.Writer) (int, error) {
+		_, err := w.Write(data)
+		return 0, err
+	})
+	return err
+}
@@ -0,0 +1,45 @@
+package backup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEraseHomeDropsCatalogEntries silinen evin arşiv kayıtlarının catalog.json'dan
+// çıkarıldığını ve sertifikada sayıldığını doğrular.
+func TestEraseHomeDropsCatalogEntries(t *testing.T) {
+	dir := t.TempDir()
+	loadConfig(t, dir)
+	if err := os.MkdirAll(filepath.Join(dir, "backups"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCatalog(&Catalog{Corrupt: 1, Archives: []CatalogEntry{
+		{HomeIdDir: "home_id_a", Day: "14_03_2024", Path: "a1.lsa"},
+		{HomeIdDir: "home_id_b", Day: "14_03_2024", Path: "b1.lsa"},
+		{HomeIdDir: "home_id_a", Day: "15_03_2024", Path: "a2.lsa", Error: "bozuk"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, rec, err := EraseHome("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Complete || rec.CatalogEntries != 2 {
+		t.Fatalf("kayıt = %+v; 2 katalog kaydı silinmiş olmalı", rec)
+	}
+
+	data, err := os.ReadFile(catalogPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cat Catalog
+	if err := json.Unmarshal(data, &cat); err != nil {
+		t.Fatal(err)
+	}
+	if len(cat.Archives) != 1 || cat.Archives[0].HomeIdDir != "home_id_b" || cat.Corrupt != 0 {
+		t.Errorf("katalog = %+v; yalnızca home_id_b kalmalı", cat)
+	}
+}
@@ -0,0 +1,465 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log-server/archive"
+	"log-server/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+// Parquet kolon tipleri (config'deki export.schema[].type değerleri)
+const (
+	colString    = "string"
+	colInt       = "int"
+	colDouble    = "double"
+	colBool      = "bool"
+	colTimestamp = "timestamp"
+	colJSON      = "json" // Obje/dizi veya karışık tipli alanlar JSON string olarak yazılır
+)
+
+// Her dosyada bulunan sabit kolonlar. Event alanlarıyla çakışmaması için "_" ile başlar.
+const (
+	colHomeId    = "_home_id"
+	colEventTime = "_event_time"
+	colExtra     = "_extra" // Kolona karşılık gelmeyen veya kolon tipine uymayan alanlar (JSON)
+)
+
+// Çıkarımla oluşturulan en fazla kolon sayısı; fazlası _extra'ya yazılır
+const maxInferredColumns = 500
+
+const exportBatchSize = 1000
+
+// ExportRequest bir ev (veya tüm evler) ve gün aralığı için Parquet export isteği.
+type ExportRequest struct {
+	HomeId    string // Boşsa tüm evler
+	StartDate string // DD_MM_YYYY
+	EndDate   string // DD_MM_YYYY, boşsa StartDate
+}
+
+// ExportFile oluşturulmuş bir Parquet dosyası.
+type ExportFile struct {
+	HomeIdDir string    `json:"home_id_dir"`
+	Path      string    `json:"path"`
+	Rows      int       `json:"rows,omitempty"`
+	Columns   int       `json:"columns,omitempty"`
+	SizeBytes int64     `json:"size_bytes"`
+	ModTime   time.Time `json:"mod_time"`
+}
+
+type exportColumn struct {
+	name  string
+	field string
+	kind  string
+}
+
+// exportDir config'deki export dizinini döner (varsayılan: ./exports).
+func exportDir() string {
+	if dir := config.Get().KettasLog.Export.Dir; dir != "" {
+		return dir
+	}
+	return "./exports"
+}
+
+// ExportParquet istenen evlerin gün aralığındaki arşivlenmiş event'lerini ev başına
+// tek bir Parquet dosyasına yazar: <export_dir>/<home_id_dir>/<home_id_dir>_<başlangıç>[_to_<bitiş>].parquet
+// Aynı aralık tekrar export edilirse dosya atomik olarak yenisiyle değiştirilir.
+func ExportParquet(req ExportRequest) ([]ExportFile, error) {
+	if req.EndDate == "" {
+		req.EndDate = req.StartDate
+	}
+	start, err := time.Parse(dayLayout, req.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("geçersiz start_date: %s", req.StartDate)
+	}
+	end, err := time.Parse(dayLayout, req.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("geçersiz end_date: %s", req.EndDate)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end_date, start_date'den önce olamaz")
+	}
+
+	var homeIdDirs []string
+	if req.HomeId != "" {
+		// home_id yola eklenir; backup_dir ve export dizini dışına çıkılmasın
+		if !validHomeId(req.HomeId) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidHomeId, req.HomeId)
+		}
+		homeIdDirs = []string{"home_id_" + req.HomeId}
+	} else {
+		entries, err := os.ReadDir(config.Get().KettasLog.Backup.BackupDir)
+		if err != nil {
+			return nil, fmt.Errorf("backup dizini okunamadı: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && strings.HasPrefix(entry.Name(), "home_id_") {
+				homeIdDirs = append(homeIdDirs, entry.Name())
+			}
+		}
+	}
+
+	var files []ExportFile
+	var failed int
+	for _, homeIdDir := range homeIdDirs {
+		file, err := exportHome(homeIdDir, req.StartDate, req.EndDate)
+		if err != nil {
+			if req.HomeId != "" {
+				return nil, err
+			}
+			failed++
+			slog.Error("Ev export edilemedi", "home_id_dir", homeIdDir, "error", err)
+			continue
+		}
+		if file != nil {
+			files = append(files, *file)
+		}
+	}
+	if failed > 0 {
+		return files, fmt.Errorf("%d ev export edilemedi", failed)
+	}
+	return files, nil
+}
+
+// exportHome tek bir evin arşivlerini Parquet'e yazar. Aralıkta arşiv yoksa nil döner.
+func exportHome(homeIdDir, startDate, endDate string) (*ExportFile, error) {
+	loc := homeLocation(homeIdDir)
+	start, _ := time.ParseInLocation(dayLayout, startDate, loc)
+	end, _ := time.ParseInLocation(dayLayout, endDate, loc)
+
+	paths, err := homeArchives(homeIdDir, func(day time.Time) bool {
+		return !day.Before(start) && !day.After(end)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	creds := ArchiveCredentials()
+	columns := configuredColumns()
+	if columns == nil {
+		// Schema verilmemiş: önce arşivleri tarayıp kolonları çıkar
+		if columns, err = inferColumns(paths, creds); err != nil {
+			return nil, err
+		}
+	}
+
+	name := fmt.Sprintf("%s_%s", homeIdDir, startDate)
+	if endDate != startDate {
+		name = fmt.Sprintf("%s_%s_to_%s", homeIdDir, startDate, endDate)
+	}
+	targetDir := filepath.Join(exportDir(), homeIdDir)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("export dizini oluşturulamadı: %w", err)
+	}
+	finalPath := filepath.Join(targetDir, name+".parquet")
+	tmpPath := finalPath + partialSuffix
+
+	homeId := strings.TrimPrefix(homeIdDir, "home_id_")
+	fields := timestampFields()
+	rows, err := writeFileDurable(tmpPath, func(w io.Writer) (int, error) {
+		pw := parquet.NewGenericWriter[map[string]any](w, exportSchema(columns), parquet.Compression(exportCodec()))
+
+		batch := make([]map[string]any, 0, exportBatchSize)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			_, err := pw.Write(batch)
+			batch = batch[:0]
+			return err
+		}
+
+		n := 0
+		for _, path := range paths {
+			err := archive.ForEachLine(path, creds, time.Time{}, time.Time{}, func(line []byte) error {
+				obj, ok := decodeObject(line)
+				if !ok {
+					return nil
+				}
+				row := exportRow(obj, columns, loc)
+				row[colHomeId] = homeId
+				if t, ok := eventTime(line, fields, loc); ok {
+					row[colEventTime] = t
+				}
+
+				batch = append(batch, row)
+				n++
+				if len(batch) >= exportBatchSize {
+					return flush()
+				}
+				return nil
+			})
+			if err != nil {
+				return 0, fmt.Errorf("%s okunamadı: %w", filepath.Base(path), err)
+			}
+		}
+		if err := flush(); err != nil {
+			return 0, err
+		}
+		return n, pw.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, finalPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	info, err := os.Stat(finalPath)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Parquet export oluşturuldu", "file", finalPath, "rows", rows, "columns", len(columns)+3, "archives", len(paths))
+	return &ExportFile{
+		HomeIdDir: homeIdDir,
+		Path:      finalPath,
+		Rows:      rows,
+		Columns:   len(columns) + 3,
+		SizeBytes: info.Size(),
+		ModTime:   info.ModTime(),
+	}, nil
+}
+
+// ListExports export dizinindeki Parquet dosyalarını döner.
+func ListExports() ([]ExportFile, error) {
+	files := []ExportFile{}
+	err := filepath.Walk(exportDir(), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(info.Name(), ".parquet") {
+			return nil
+		}
+		files = append(files, ExportFile{
+			HomeIdDir: filepath.Base(filepath.Dir(path)),
+			Path:      path,
+			SizeBytes: info.Size(),
+			ModTime:   info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return files, nil
+}
+
+// configuredColumns config'deki schema'yı döner; schema yoksa nil.
+func configuredColumns() []exportColumn {
+	schema := config.Get().KettasLog.Export.Schema
+	if len(schema) == 0 {
+		return nil
+	}
+
+	columns := make([]exportColumn, 0, len(schema))
+	for _, c := range schema {
+		col := exportColumn{name: c.Name, field: c.Field, kind: strings.ToLower(c.Type)}
+		if col.field == "" {
+			col.field = col.name
+		}
+		switch col.kind {
+		case colString, colInt, colDouble, colBool, colTimestamp, colJSON:
+		default:
+			slog.Warn("Bilinmeyen export kolon tipi, json kullanılıyor", "column", c.Name, "type", c.Type)
+			col.kind = colJSON
+		}
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+// inferColumns event'lerin üst seviye alanlarından kolonları ve tiplerini çıkarır.
+// Aynı alanda int ve double görülürse double, başka tip karışımlarında json kullanılır.
+func inferColumns(paths []string, creds archive.Credentials) ([]exportColumn, error) {
+	kinds := make(map[string]string)
+	for _, path := range paths {
+		err := archive.ForEachLine(path, creds, time.Time{}, time.Time{}, func(line []byte) error {
+			obj, ok := decodeObject(line)
+			if !ok {
+				return nil
+			}
+			for key, value := range obj {
+				if _, seen := kinds[key]; !seen && len(kinds) >= maxInferredColumns {
+					continue
+				}
+				kinds[key] = mergeKinds(kinds[key], kindOf(value))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s okunamadı: %w", filepath.Base(path), err)
+		}
+	}
+
+	columns := make([]exportColumn, 0, len(kinds))
+	for key, kind := range kinds {
+		if key == colHomeId || key == colEventTime || key == colExtra {
+			continue
+		}
+		if kind == "" {
+			kind = colString // Hep null
+		}
+		columns = append(columns, exportColumn{name: key, field: key, kind: kind})
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].name < columns[j].name })
+	return columns, nil
+}
+
+func kindOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return colBool
+	case string:
+		return colString
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return colInt
+		}
+		return colDouble
+	default:
+		return colJSON
+	}
+}
+
+func mergeKinds(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case b == "":
+		return a
+	case (a == colInt && b == colDouble) || (a == colDouble && b == colInt):
+		return colDouble
+	default:
+		return colJSON
+	}
+}
+
+func exportSchema(columns []exportColumn) *parquet.Schema {
+	group := parquet.Group{
+		colHomeId:    parquet.String(),
+		colEventTime: parquet.Optional(parquet.Timestamp(parquet.Millisecond)),
+		colExtra:     parquet.Optional(parquet.String()),
+	}
+	for _, col := range columns {
+		var node parquet.Node
+		switch col.kind {
+		case colInt:
+			node = parquet.Int(64)
+		case colDouble:
+			node = parquet.Leaf(parquet.DoubleType)
+		case colBool:
+			node = parquet.Leaf(parquet.BooleanType)
+		case colTimestamp:
+			node = parquet.Timestamp(parquet.Millisecond)
+		default:
+			node = parquet.String()
+		}
+		group[col.name] = parquet.Optional(node)
+	}
+	return parquet.NewSchema("event", group)
+}
+
+// exportCodec config'deki export.compression değerine göre Parquet sıkıştırmasını döner.
+func exportCodec() compress.Codec {
+	switch c := strings.ToLower(config.Get().KettasLog.Export.Compression); c {
+	case "", "zstd":
+		return &parquet.Zstd
+	case "snappy":
+		return &parquet.Snappy
+	case "gzip":
+		return &parquet.Gzip
+	case "none":
+		return &parquet.Uncompressed
+	default:
+		slog.Warn("Bilinmeyen export sıkıştırması, zstd kullanılıyor", "compression", c)
+		return &parquet.Zstd
+	}
+}
+
+// exportRow event'i kolonlara dağıtır. Kolona karşılık gelmeyen üst seviye alanlar ve
+// kolon tipine dönüştürülemeyen değerler kaybolmaması için _extra'ya JSON olarak yazılır.
+func exportRow(obj map[string]interface{}, columns []exportColumn, loc *time.Location) map[string]any {
+	row := make(map[string]any, len(columns)+3)
+	used := make(map[string]bool, len(columns))
+	extra := make(map[string]interface{})
+
+	for _, col := range columns {
+		top := strings.SplitN(col.field, ".", 2)[0]
+		used[top] = true
+
+		value := lookupField(obj, col.field)
+		if value == nil {
+			continue
+		}
+		if converted, ok := convertValue(value, col.kind, loc); ok {
+			row[col.name] = converted
+		} else {
+			extra[col.field] = value
+		}
+	}
+	for key, value := range obj {
+		if !used[key] {
+			extra[key] = value
+		}
+	}
+
+	if len(extra) > 0 {
+		if data, err := json.Marshal(extra); err == nil {
+			row[colExtra] = string(data)
+		}
+	}
+	return row
+}
+
+func convertValue(value interface{}, kind string, loc *time.Location) (any, bool) {
+	switch kind {
+	case colString:
+		s, ok := value.(string)
+		return s, ok
+	case colInt:
+		if n, ok := value.(json.Number); ok {
+			i, err := n.Int64()
+			return i, err == nil
+		}
+	case colDouble:
+		if n, ok := value.(json.Number); ok {
+			f, err := n.Float64()
+			return f, err == nil
+		}
+	case colBool:
+		b, ok := value.(bool)
+		return b, ok
+	case colTimestamp:
+		return parseTimestamp(value, loc)
+	case colJSON:
+		if s, ok := value.(string); ok {
+			return s, true
+		}
+		data, err := json.Marshal(value)
+		return string(data), err == nil
+	}
+	return nil, false
+}
+
+func decodeObject(line []byte) (map[string]interface{}, bool) {
+	var obj map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&obj); err != nil || obj == nil {
+		return nil, false
+	}
+	return obj, true
+}
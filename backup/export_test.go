@@ -0,0 +1,17 @@
+package backup
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExportParquetRejectsInvalidHomeId(t *testing.T) {
+	for _, homeId := range []string{"../../tmp", "x/../../etc", `a\b`, ".."} {
+		t.Run(homeId, func(t *testing.T) {
+			_, err := ExportParquet(ExportRequest{HomeId: homeId, StartDate: "14_03_2024"})
+			if !errors.Is(err, ErrInvalidHomeId) {
+				t.Errorf("ExportParquet hata = %v, %v bekleniyordu", err, ErrInvalidHomeId)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"log-server/archive"
+	"log-server/config"
+	"log-server/keys"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const formatZstd = "zstd"
+
+// archiveExt config'deki arşiv formatına göre yeni arşivlerin uzantısını döner.
+func archiveExt() string {
+	switch strings.ToLower(config.Get().KettasLog.Archive.Format) {
+	case formatZstd:
+		return archive.Ext
+	case "", "zip":
+		return ".zip"
+	default:
+		slog.Warn("Bilinmeyen arşiv formatı, zip kullanılıyor", "format", config.Get().KettasLog.Archive.Format)
+		return ".zip"
+	}
+}
+
+// ArchiveCredentials arşivleri okumak için zip_password'ü ve (envelope encryption aktifse)
+// keyring'den veri anahtarı çözücüyü döner.
+func ArchiveCredentials() archive.Credentials {
+	creds := archive.Credentials{Password: config.Get().KettasLog.ZipPassword}
+	if kr := keys.Get(); kr != nil {
+		creds.Keys = kr.Key
+	}
+	return creds
+}
+
+// writeArchiveDurable arşivi finalPath'in uzantısındaki formatta path'e yazar ve fsync eder.
+// Envelope encryption aktifse arşiv, password yerine evin aktif veri anahtarıyla şifrelenir
+// ve anahtarın id'si arşive kaydedilir.
+func writeArchiveDurable(path, finalPath, homeIdDir, password string, write eventWriter) (int, error) {
+	var keyId string
+	var key []byte
+	if kr := keys.Get(); kr != nil {
+		var err error
+		if keyId, key, err = kr.ActiveKey(homeIdDir); err != nil {
+			return 0, fmt.Errorf("veri anahtarı alınamadı: %w", err)
+		}
+	}
+
+	if !archive.IsLSA(finalPath) {
+		comment := ""
+		if key != nil {
+			password = archive.KeyPassword(key)
+			comment = archive.KeyComment(keyId)
+		}
+		return writeZipDurable(path, archive.EntryName(finalPath), password, comment, write)
+	}
+
+	loc := homeLocation(homeIdDir)
+	fields := timestampFields()
+	opts := archive.Options{
+		Password:    password,
+		KeyID:       keyId,
+		Key:         key,
+		ChunkEvents: config.Get().KettasLog.Archive.ChunkEvents,
+		TimeOf: func(line []byte) (time.Time, bool) {
+			return eventTime(line, fields, loc)
+		},
+	}
+
+	return writeFileDurable(path, func(w io.Writer) (int, error) {
+		aw, err := archive.NewWriter(w, opts)
+		if err != nil {
+			return 0, err
+		}
+		n, err := write(aw)
+		if err != nil {
+			return 0, err
+		}
+		return n, aw.Close()
+	})
+}
@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestForEachEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr error
+	}{
+		{"ndjson", "{\"a\":1}\n{\"a\":2}\n", 2, nil},
+		{"array", "  [{\"a\":1}, {\"a\":2}, {\"a\":3}]", 3, nil},
+		{"boş", "\n", 0, nil},
+		{"ortada bozuk satır", "{\"a\":1}\n{\"a\":\n{\"a\":3}\n", 1, errCorruptEvent},
+		{"bozuk array elemanı", "[{\"a\":1}, {a:2}, {\"a\":3}]", 1, errCorruptEvent},
+		{"yarım son satır", "{\"a\":1}\n{\"a\":2", 1, errCorruptEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "events.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			n := 0
+			err := forEachEvent(path, func(json.RawMessage) error {
+				n++
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("forEachEvent hata = %v, %v bekleniyordu", err, tt.wantErr)
+			}
+			if n != tt.want {
+				t.Errorf("%d event okundu, %d bekleniyordu", n, tt.want)
+			}
+		})
+	}
+}
+
+// TestScanHomeSourcesSkipsCorrupt bozuk event içeren dosyanın arşivlenecek kaynaklara
+// alınmadığını (dolayısıyla silinmediğini) doğrular.
+func TestScanHomeSourcesSkipsCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"home-1_14_03_2024.json": "{\"ts\":\"2024-03-14T10:00:00Z\"}\n",
+		"home-1_15_03_2024.json": "{\"ts\":\"2024-03-15T10:00:00Z\"}\n{bozuk\n{\"ts\":\"2024-03-15T11:00:00Z\"}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sources := scanHomeSources(dir, "home_id_home-1", newDayResolver("home_id_home-1"))
+	if len(sources) != 1 || sources[0].name != "home-1_14_03_2024.json" {
+		t.Fatalf("kaynaklar = %+v; yalnızca bozuk olmayan dosya bekleniyordu", sources)
+	}
+}
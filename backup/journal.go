@@ -0,0 +1,148 @@
+package backup
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log-server/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DeletionRecord silme journal'ındaki tek bir kaydı temsil eder.
+// Journal NDJSON formatındadır, her satır bir DeletionRecord.
+type DeletionRecord struct {
+	Time      time.Time `json:"time"`
+	Job       string    `json:"job"`    // "cleanup", "rotation", "daily_archive", "erasure"
+	Reason    string    `json:"reason"` // "retention", "size_limit", "archived", "erasure"
+	Path      string    `json:"path"`
+	HomeIdDir string    `json:"home_id_dir,omitempty"`
+	SizeBytes int64     `json:"size_bytes"`
+	ModTime   time.Time `json:"mod_time"`
+	Archive   string    `json:"archive,omitempty"` // Kaynak dosya arşivlendiyse oluşan zip yolu
+}
+
+// DeletionQuery journal sorgusu için filtreler. Boş alanlar filtre uygulanmaz.
+type DeletionQuery struct {
+	Since     time.Time
+	Until     time.Time
+	HomeIdDir string
+	Job       string
+	Limit     int
+}
+
+var journalMu sync.Mutex
+
+// deletionJournalPath config'deki journal yolunu döner (varsayılan: backup_dir/deletion_journal.ndjson).
+func deletionJournalPath() string {
+	cfg := config.Get()
+	if cfg.KettasLog.Backup.DeletionJournalFile != "" {
+		return cfg.KettasLog.Backup.DeletionJournalFile
+	}
+	return filepath.Join(cfg.KettasLog.Backup.BackupDir, "deletion_journal.ndjson")
+}
+
+// removeAndJournal dosyayı siler ve başarılı olursa journal'a kaydeder.
+// info nil ise dosya bilgisi silmeden önce okunur.
+func removeAndJournal(path string, info os.FileInfo, job, reason, homeIdDir, archive string) error {
+	if info == nil {
+		var err error
+		if info, err = os.Stat(path); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+
+	rec := DeletionRecord{
+		Time:      time.Now(),
+		Job:       job,
+		Reason:    reason,
+		Path:      path,
+		HomeIdDir: homeIdDir,
+		SizeBytes: info.Size(),
+		ModTime:   info.ModTime(),
+		Archive:   archive,
+	}
+	if err := appendDeletionRecord(rec); err != nil {
+		// Silme gerçekleşti, journal yazılamadıysa en azından internal log'da kalsın
+		slog.Error("Deletion journal yazılamadı", "error", err, "path", path, "job", job)
+	}
+	return nil
+}
+
+func appendDeletionRecord(rec DeletionRecord) error {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+
+	path := deletionJournalPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("journal dizini oluşturulamadı: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("journal dosyası açılamadı: %w", err)
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ReadDeletionJournal journal'daki kayıtları filtreleyerek döner.
+// Limit verilmişse en yeni Limit kadar kayıt döner.
+func ReadDeletionJournal(q DeletionQuery) ([]DeletionRecord, error) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+
+	f, err := os.Open(deletionJournalPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []DeletionRecord{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	records := []DeletionRecord{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec DeletionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// Yarım yazılmış satırları atla
+			continue
+		}
+		if !q.Since.IsZero() && rec.Time.Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && rec.Time.After(q.Until) {
+			continue
+		}
+		if q.HomeIdDir != "" && rec.HomeIdDir != q.HomeIdDir {
+			continue
+		}
+		if q.Job != "" && rec.Job != q.Job {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[len(records)-q.Limit:]
+	}
+	return records, nil
+}
@@ -0,0 +1,329 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log-server/config"
+	"log-server/lock"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dayLayout = "02_01_2006" // DD_MM_YYYY
+
+// Event zamanı alanı config'de verilmemişse sırayla denenecek alanlar
+var defaultTimestampFields = []string{"timestamp", "time", "ts", "created_at", "date"}
+
+// homeLocation home_id klasörünün saat dilimini döner:
+// archive.home_timezones → archive.default_timezone → sunucu saati.
+func homeLocation(homeIdDir string) *time.Location {
+	cfg := config.Get().KettasLog.Archive
+	homeId := strings.TrimPrefix(homeIdDir, "home_id_")
+
+	tz := ""
+	for id, zone := range cfg.HomeTimeZones {
+		// viper map key'lerini küçük harfe çevirir
+		if strings.EqualFold(id, homeId) {
+			tz = zone
+			break
+		}
+	}
+	if tz == "" {
+		tz = cfg.DefaultTimeZone
+	}
+	if tz == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		slog.Error("Geçersiz ev saat dilimi, sunucu saati kullanılıyor", "home_id_dir", homeIdDir, "timezone", tz, "error", err)
+		return time.Local
+	}
+	return loc
+}
+
+func timestampFields() []string {
+	if fields := config.Get().KettasLog.Archive.TimestampFields; len(fields) > 0 {
+		return fields
+	}
+	return defaultTimestampFields
+}
+
+// eventTime event'in kendi zaman damgasını okur. Alanlar sırayla denenir,
+// "meta.ts" gibi noktalı yollar iç içe objeler için kullanılabilir.
+// Zaman dilimi içermeyen string değerler loc'a göre yorumlanır.
+func eventTime(raw json.RawMessage, fields []string, loc *time.Location) (time.Time, bool) {
+	var obj map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&obj); err != nil {
+		return time.Time{}, false
+	}
+
+	for _, field := range fields {
+		if t, ok := parseTimestamp(lookupField(obj, field), loc); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// lookupField "meta.ts" gibi noktalı bir yolu iç içe objelerde takip eder; yoksa nil döner.
+func lookupField(obj map[string]interface{}, path string) interface{} {
+	var value interface{} = obj
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.000",
+	"02.01.2006 15:04:05",
+}
+
+// parseTimestamp unix saniye/milisaniye sayılarını ve yaygın string formatlarını çözer.
+func parseTimestamp(value interface{}, loc *time.Location) (time.Time, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		n, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil || n <= 0 {
+			return time.Time{}, false
+		}
+		// 1e12'den büyük değerler milisaniye kabul edilir
+		if n > 1e12 {
+			return time.UnixMilli(int64(n)), true
+		}
+		return time.Unix(int64(n), 0), true
+	case string:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return parseTimestamp(json.Number(strconv.FormatInt(n, 10)), loc)
+		}
+		for _, layout := range timestampLayouts {
+			if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// dayFromFileName dosya adındaki DD_MM_YYYY parçasını döner (zaman damgası olmayan event'ler için).
+func dayFromFileName(name string) (string, bool) {
+	parts := strings.Split(strings.TrimSuffix(name, filepath.Ext(name)), "_")
+	for i := 0; i < len(parts)-2; i++ {
+		if len(parts[i]) == 2 && len(parts[i+1]) == 2 && len(parts[i+2]) == 4 {
+			possibleDate := fmt.Sprintf("%s_%s_%s", parts[i], parts[i+1], parts[i+2])
+			if _, err := time.Parse(dayLayout, possibleDate); err == nil {
+				return possibleDate, true
+			}
+		}
+	}
+	return "", false
+}
+
+// sourceFile bir kaynak dosyanın özetini tutar. Event'lerin kendisi bellekte tutulmaz;
+// arşivleme sırasında dosya tekrar akış halinde okunur.
+type sourceFile struct {
+	name        string
+	path        string
+	fallbackDay string         // Zaman damgası okunamayan event'lerin günü
+	dayCounts   map[string]int // gün → event sayısı
+	days        []string       // Günler, dosyada ilk görüldükleri sırayla
+}
+
+// dayResolver bir event'in evin saat dilimindeki gününü bulur. Zaman damgası okunamayan
+// event'ler dosya adındaki tarihe, o da yoksa dosyanın değiştirilme zamanına düşer.
+type dayResolver func(ev json.RawMessage, fallbackDay string) string
+
+func newDayResolver(homeIdDir string) dayResolver {
+	loc := homeLocation(homeIdDir)
+	fields := timestampFields()
+	return func(ev json.RawMessage, fallbackDay string) string {
+		if t, ok := eventTime(ev, fields, loc); ok {
+			return t.In(loc).Format(dayLayout)
+		}
+		return fallbackDay
+	}
+}
+
+// scanHomeSources bir home_id klasöründeki JSON dosyalarını akış halinde okuyup
+// her dosyada hangi günden kaç event olduğunu çıkarır.
+func scanHomeSources(homeIdPath, homeIdDir string, dayOf dayResolver) []sourceFile {
+	loc := homeLocation(homeIdDir)
+
+	var result []sourceFile
+	for _, fileName := range findAllJSONFiles(homeIdPath) {
+		filePath := filepath.Join(homeIdPath, fileName)
+
+		fallbackDay, ok := dayFromFileName(fileName)
+		if !ok {
+			if info, err := os.Stat(filePath); err == nil {
+				fallbackDay = info.ModTime().In(loc).Format(dayLayout)
+			}
+		}
+
+		src := sourceFile{name: fileName, path: filePath, fallbackDay: fallbackDay, dayCounts: make(map[string]int)}
+		err := forEachEvent(filePath, func(ev json.RawMessage) error {
+			day := dayOf(ev, fallbackDay)
+			if _, seen := src.dayCounts[day]; !seen {
+				src.days = append(src.days, day)
+			}
+			src.dayCounts[day]++
+			return nil
+		})
+		if err != nil {
+			// Kısmen okunan dosya arşivlenirse commit'te silinir ve okunamayan event'ler kaybolur
+			slog.Warn("Log dosyası okunamadı, arşivlenmedi", "file", filePath, "error", err)
+			continue
+		}
+		result = append(result, src)
+	}
+	return result
+}
+
+// homeLocations bir çalışma boyunca evlerin saat dilimlerini bir kez yükler.
+type homeLocations map[string]*time.Location
+
+func (m homeLocations) get(homeIdDir string) *time.Location {
+	loc, ok := m[homeIdDir]
+	if !ok {
+		loc = homeLocation(homeIdDir)
+		m[homeIdDir] = loc
+	}
+	return loc
+}
+
+// dayEnded gün (DD_MM_YYYY) loc saat diliminde now itibarıyla tamamen bittiyse true döner.
+func dayEnded(day string, loc *time.Location, now time.Time) bool {
+	start, err := time.ParseInLocation(dayLayout, day, loc)
+	if err != nil {
+		return false
+	}
+	return !now.Before(start.AddDate(0, 0, 1))
+}
+
+// archiveHomeDays bir home_id klasöründeki event'leri günlere ayırır ve include'un kabul ettiği
+// her gün için ayrı bir şifreli zip oluşturur. Tüm event'leri arşivlenen kaynak dosyalar silinir,
+// başka günlere ait event'ler de içeren dosyalar yalnızca kalan event'lerle yeniden yazılır.
+// Dönen değerler: oluşan zip yolları, silinen dosya sayısı, hata
+func archiveHomeDays(job, homeIdPath, backupDir, homeIdDir, password string, include func(day string) bool) ([]string, int, error) {
+	// Upload aynı klasöre yazıyorsa bu evi atla, bir sonraki çalışmada arşivlenir. Kilit
+	// arşivleme sırasında kaybedilirse ctx iptal edilir ve commit yapılmaz.
+	ctx, release, ok, err := lock.Get().TryLockContext(context.Background(), lock.HomeKey(homeIdDir))
+	if err != nil {
+		return nil, 0, fmt.Errorf("home kilidi alınamadı: %w", err)
+	}
+	if !ok {
+		slog.Info("Home klasörü kilitli, arşivleme atlandı", "home_id_dir", homeIdDir, "job", job)
+		return nil, 0, nil
+	}
+	defer release()
+
+	dayOf := newDayResolver(homeIdDir)
+	sources := scanHomeSources(homeIdPath, homeIdDir, dayOf)
+
+	// Arşivlenecek günler (ilk görüldükleri sırayla) ve her günün beklenen event sayısı
+	dayTotals := make(map[string]int)
+	var dayOrder []string
+	for _, src := range sources {
+		for _, day := range src.days {
+			if day == "" || !include(day) {
+				continue
+			}
+			if _, ok := dayTotals[day]; !ok {
+				dayOrder = append(dayOrder, day)
+			}
+			dayTotals[day] += src.dayCounts[day]
+		}
+	}
+	if len(dayOrder) == 0 {
+		return nil, 0, nil
+	}
+	archived := func(day string) bool {
+		_, ok := dayTotals[day]
+		return ok
+	}
+
+	// Her gün, kaynaklardan doğrudan şifreli zip entry'sine akıtılır; yazma, doğrulama ve
+	// kaynakların silinmesi commitArchives içinde crash-safe şekilde yapılır
+	var archives []pendingArchive
+	for _, day := range dayOrder {
+		day := day
+		archives = append(archives, pendingArchive{
+			day:   day,
+			write: streamEvents(sources, dayOf, dayTotals[day], func(d string) bool { return d == day }),
+		})
+	}
+
+	var pending []pendingSource
+	for _, src := range sources {
+		var archivedDay string
+		remaining := 0
+		for _, day := range src.days {
+			if archived(day) {
+				archivedDay = day
+			} else {
+				remaining += src.dayCounts[day]
+			}
+		}
+		if archivedDay == "" {
+			continue
+		}
+
+		ps := pendingSource{path: src.path, day: archivedDay}
+		if remaining > 0 {
+			ps.remaining = streamEvents([]sourceFile{src}, dayOf, remaining, func(d string) bool { return !archived(d) })
+		}
+		pending = append(pending, ps)
+	}
+
+	return commitArchives(ctx, job, backupDir, homeIdDir, password, archives, pending)
+}
+
+// streamEvents kaynak dosyaları sırayla okuyup günü keep'e uyan event'leri satır başına bir
+// (tek satıra sıkıştırılmış) JSON objesi olarak yazar. Bellekte aynı anda tek event tutulur.
+// Yazılan sayı taramadaki beklenen sayıdan farklıysa kaynaklar arada değişmiş demektir.
+func streamEvents(sources []sourceFile, dayOf dayResolver, expected int, keep func(day string) bool) eventWriter {
+	return func(w io.Writer) (int, error) {
+		var buf bytes.Buffer
+		n := 0
+		for _, src := range sources {
+			err := forEachEvent(src.path, func(ev json.RawMessage) error {
+				if !keep(dayOf(ev, src.fallbackDay)) {
+					return nil
+				}
+				buf.Reset()
+				if err := json.Compact(&buf, ev); err != nil {
+					return err
+				}
+				buf.WriteByte('\n')
+				n++
+				_, err := w.Write(buf.Bytes())
+				return err
+			})
+			if err != nil {
+				return n, fmt.Errorf("%s okunamadı: %w", src.name, err)
+			}
+		}
+		if n != expected {
+			return n, fmt.Errorf("kaynak dosyalar arşivleme sırasında değişti: beklenen %d event, okunan %d", expected, n)
+		}
+		return n, nil
+	}
+}
@@ -0,0 +1,42 @@
+package backup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDayEnded(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	if err != nil {
+		t.Skip("tzdata yok:", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata yok:", err)
+	}
+
+	// 15 Mart 2024 23:58 UTC: İstanbul'da 16 Mart 02:58, New York'ta 15 Mart 19:58
+	now := time.Date(2024, 3, 15, 23, 58, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		day  string
+		loc  *time.Location
+		want bool
+	}{
+		{"utc bugün", "15_03_2024", time.UTC, false},
+		{"utc dün", "14_03_2024", time.UTC, true},
+		{"istanbul dün", "15_03_2024", istanbul, true},
+		{"istanbul bugün", "16_03_2024", istanbul, false},
+		{"new york bugün", "15_03_2024", newYork, false},
+		{"new york dün", "14_03_2024", newYork, true},
+		{"gelecek", "20_03_2024", time.UTC, false},
+		{"geçersiz", "2024-03-14", time.UTC, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dayEnded(tt.day, tt.loc, now); got != tt.want {
+				t.Errorf("dayEnded(%s, %s) = %v, %v bekleniyordu", tt.day, tt.loc, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package backup
+
+import (
+	"fmt"
+	"log-server/archive"
+	"log-server/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PlannedAction bir sonraki cleanup/rotation çalışmasında tek bir dosya için yapılacak işlemi tanımlar.
+type PlannedAction struct {
+	Action    string    `json:"action"` // "delete" (cleanup) veya "archive" (rotation: zip'lenip silinir)
+	Path      string    `json:"path"`
+	HomeIdDir string    `json:"home_id_dir,omitempty"`
+	SizeBytes int64     `json:"size_bytes"`
+	ModTime   time.Time `json:"mod_time"`
+	Reason    string    `json:"reason"`
+}
+
+// RotationPlan checkAndRotate'in bir sonraki çalışmasında ne yapacağını özetler.
+type RotationPlan struct {
+	Triggered    bool            `json:"triggered"`
+	CurrentBytes int64           `json:"current_bytes"`
+	MaxBytes     int64           `json:"max_bytes"`
+	Actions      []PlannedAction `json:"actions"`
+	TotalBytes   int64           `json:"total_bytes"`
+}
+
+// CleanupPlan cleanupBackups'ın bir sonraki çalışmasında ne sileceğini özetler.
+type CleanupPlan struct {
+	CurrentBytes  int64           `json:"current_bytes"`
+	MaxBytes      int64           `json:"max_bytes"`
+	RetentionDays int             `json:"retention_days"`
+	Actions       []PlannedAction `json:"actions"`
+	TotalBytes    int64           `json:"total_bytes"`
+}
+
+// Plan dry-run raporu: hiçbir dosyaya dokunmadan rotation ve cleanup'ın ne yapacağını döner.
+type Plan struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Rotation    RotationPlan `json:"rotation"`
+	Cleanup     CleanupPlan  `json:"cleanup"`
+}
+
+const (
+	reasonRetention = "retention"
+	reasonSizeLimit = "size_limit"
+	reasonArchived  = "archived"
+)
+
+// BuildPlan mevcut config'e göre bir sonraki rotation ve cleanup çalışmasının planını hesaplar.
+func BuildPlan() (*Plan, error) {
+	rotation, err := planRotation()
+	if err != nil {
+		return nil, err
+	}
+	cleanup, err := planCleanup()
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{
+		GeneratedAt: time.Now(),
+		Rotation:    *rotation,
+		Cleanup:     *cleanup,
+	}, nil
+}
+
+// planRotation logs klasörü limiti aşıyorsa zip'lenip silinecek JSON dosyalarını listeler.
+func planRotation() (*RotationPlan, error) {
+	cfg := config.Get()
+	logsDir := cfg.KettasLog.LogsDir
+
+	size, err := getDirSize(logsDir)
+	if err != nil {
+		return nil, fmt.Errorf("logs dizini boyutu hesaplanamadı: %w", err)
+	}
+
+	plan := &RotationPlan{
+		CurrentBytes: size,
+		MaxBytes:     cfg.KettasLog.MaxFolderSizeMB * 1024 * 1024,
+		Actions:      []PlannedAction{},
+	}
+	if plan.CurrentBytes <= plan.MaxBytes {
+		return plan, nil
+	}
+	plan.Triggered = true
+
+	entries, err := os.ReadDir(logsDir)
+	if err != nil {
+		return nil, fmt.Errorf("logs dizini okunamadı: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		homeIdDir := entry.Name()
+		homeIdPath := filepath.Join(logsDir, homeIdDir)
+
+		for _, fileName := range findAllJSONFiles(homeIdPath) {
+			info, err := os.Stat(filepath.Join(homeIdPath, fileName))
+			if err != nil {
+				continue
+			}
+			plan.Actions = append(plan.Actions, PlannedAction{
+				Action:    "archive",
+				Path:      filepath.Join(homeIdPath, fileName),
+				HomeIdDir: homeIdDir,
+				SizeBytes: info.Size(),
+				ModTime:   info.ModTime(),
+				Reason:    reasonSizeLimit,
+			})
+			plan.TotalBytes += info.Size()
+		}
+	}
+
+	return plan, nil
+}
+
+// planCleanup backup klasöründe saklama kurallarına göre silinecek zip'leri hesaplar.
+// Önce retention_days'i aşanlar, ardından boyut limiti sağlanana kadar en eskiler seçilir.
+func planCleanup() (*CleanupPlan, error) {
+	cfg := config.Get()
+	backupDir := cfg.KettasLog.Backup.BackupDir
+
+	plan := &CleanupPlan{
+		MaxBytes:      cfg.KettasLog.Backup.MaxBackupSizeMB * 1024 * 1024,
+		RetentionDays: cfg.KettasLog.Backup.RetentionDays,
+		Actions:       []PlannedAction{},
+	}
+
+	// backups/ altındaki tüm arşivleri (.zip, .lsa) recursive bul
+	var backupFiles []backupFileInfo
+	err := filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() || !archive.IsArchive(info.Name()) {
+			return nil
+		}
+		backupFiles = append(backupFiles, backupFileInfo{path: path, info: info})
+		plan.CurrentBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return plan, nil
+		}
+		return nil, fmt.Errorf("backup dizini taranamadı: %w", err)
+	}
+
+	remaining := plan.CurrentBytes
+
+	// 1. Time Retention Check
+	var kept []backupFileInfo
+	for _, f := range backupFiles {
+		age := time.Since(f.info.ModTime())
+		if age.Hours() > float64(plan.RetentionDays*24) {
+			plan.Actions = append(plan.Actions, newDeleteAction(backupDir, f,
+				fmt.Sprintf("%s: %d gün eski (limit %d gün)", reasonRetention, int(age.Hours()/24), plan.RetentionDays)))
+			remaining -= f.info.Size()
+			continue
+		}
+		kept = append(kept, f)
+	}
+
+	// 2. Size Retention Check — eskiden yeniye silinir
+	if remaining > plan.MaxBytes {
+		sort.Slice(kept, func(i, j int) bool {
+			return kept[i].info.ModTime().Before(kept[j].info.ModTime())
+		})
+		for _, f := range kept {
+			if remaining <= plan.MaxBytes {
+				break
+			}
+			plan.Actions = append(plan.Actions, newDeleteAction(backupDir, f,
+				fmt.Sprintf("%s: backup dizini %d MB (limit %d MB)", reasonSizeLimit, remaining/1024/1024, plan.MaxBytes/1024/1024)))
+			remaining -= f.info.Size()
+		}
+	}
+
+	for _, a := range plan.Actions {
+		plan.TotalBytes += a.SizeBytes
+	}
+	return plan, nil
+}
+
+func newDeleteAction(backupDir string, f backupFileInfo, reason string) PlannedAction {
+	return PlannedAction{
+		Action:    "delete",
+		Path:      f.path,
+		HomeIdDir: homeIdDirOf(backupDir, f.path),
+		SizeBytes: f.info.Size(),
+		ModTime:   f.info.ModTime(),
+		Reason:    reason,
+	}
+}
+
+// homeIdDirOf root altındaki bir dosya yolundan ilk seviye klasör adını (home_id_xxx) çıkarır.
+func homeIdDirOf(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
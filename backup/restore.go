@@ -0,0 +1,80 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"log-server/archive"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RestoreRequest bir evin gün aralığındaki arşivlerinin geri açılması.
+type RestoreRequest struct {
+	HomeId    string
+	StartDate string // DD_MM_YYYY
+	EndDate   string // DD_MM_YYYY, boşsa StartDate
+	TargetDir string // Event'ler <TargetDir>/<home_id_dir>/ altına yazılır
+}
+
+// RestoredFile geri açılan tek bir arşivin sonucu.
+type RestoredFile struct {
+	Archive string `json:"archive"`
+	Path    string `json:"path"`
+	Events  int    `json:"events"`
+}
+
+// RestoreArchives evin aralıktaki arşivlerini çözüp her biri için
+// <target>/<home_id_dir>/<arşiv adı>.json (NDJSON) dosyasına yazar. Arşivlere dokunmaz.
+// Hedef logs_dir ise geri açılan event'ler bir sonraki arşivlemede yeniden arşivlenir.
+func RestoreArchives(req RestoreRequest) ([]RestoredFile, error) {
+	if req.HomeId == "" || req.TargetDir == "" {
+		return nil, fmt.Errorf("home_id ve hedef dizin gerekli")
+	}
+	if req.EndDate == "" {
+		req.EndDate = req.StartDate
+	}
+
+	homeIdDir := "home_id_" + req.HomeId
+	loc := homeLocation(homeIdDir)
+	start, err := time.ParseInLocation(dayLayout, req.StartDate, loc)
+	if err != nil {
+		return nil, fmt.Errorf("geçersiz start_date: %s", req.StartDate)
+	}
+	end, err := time.ParseInLocation(dayLayout, req.EndDate, loc)
+	if err != nil {
+		return nil, fmt.Errorf("geçersiz end_date: %s", req.EndDate)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end_date, start_date'den önce olamaz")
+	}
+
+	paths, err := homeArchives(homeIdDir, func(day time.Time) bool {
+		return !day.Before(start) && !day.After(end)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	targetDir := filepath.Join(req.TargetDir, homeIdDir)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("hedef dizin oluşturulamadı: %w", err)
+	}
+
+	var restored []RestoredFile
+	for _, path := range paths {
+		finalPath := filepath.Join(targetDir, archive.EntryName(path))
+		tmpPath := finalPath + partialSuffix
+		n, err := writeFileDurable(tmpPath, func(w io.Writer) (int, error) {
+			return CatArchive(w, path, time.Time{}, time.Time{})
+		})
+		if err != nil {
+			return restored, fmt.Errorf("%s geri açılamadı: %w", filepath.Base(path), err)
+		}
+		if err := os.Rename(tmpPath, finalPath); err != nil {
+			return restored, err
+		}
+		restored = append(restored, RestoredFile{Archive: path, Path: finalPath, Events: n})
+	}
+	return restored, nil
+}
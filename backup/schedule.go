@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"log-server/config"
+	"log-server/scheduler"
+	"log/slog"
+	"time"
+)
+
+// Aynı gruptaki işler birbirini bekler (manuel tetiklemeler dahil).
+// logs grubundaki işler logs_dir'i, backups grubundakiler backup_dir'i değiştirir.
+const (
+	jobGroupLogs    = "logs"
+	jobGroupBackups = "backups"
+)
+
+func init() {
+	config.RegisterValidator(validateSchedules)
+	config.OnChange(rescheduleJobs)
+}
+
+// validateSchedules jobs altındaki cron ifadelerini scheduler'ın ayrıştırıcısıyla kontrol eder.
+func validateSchedules(cfg *config.Config) []config.Problem {
+	var problems []config.Problem
+	for name, sc := range config.JobSchedules(&cfg.KettasLog.Backup.Jobs) {
+		if sc.Cron == "" {
+			continue
+		}
+		if _, err := scheduler.Parse(sc.Cron); err != nil {
+			problems = append(problems, config.Problem{Key: "kettas_log.backup.jobs." + name + ".cron", Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// registerJob config'deki zamanlamaya göre işi scheduler'a ekler.
+// Cron boşsa veya geçersizse defaultSpec kullanılır.
+func registerJob(name, group string, sc config.ScheduleConfig, defaultSpec string, run scheduler.RunFunc) {
+	job := scheduler.Job{
+		Name:     name,
+		Group:    group,
+		Spec:     sc.Cron,
+		Location: jobLocation(name, sc),
+		Jitter:   time.Duration(sc.JitterSec) * time.Second,
+		Run:      run,
+	}
+	if job.Spec == "" {
+		job.Spec = defaultSpec
+	}
+
+	err := scheduler.Get().Add(job)
+	if err != nil && job.Spec != defaultSpec {
+		slog.Error("İş zamanlaması geçersiz, varsayılan kullanılıyor",
+			"job", name, "spec", job.Spec, "default", defaultSpec, "error", err)
+		job.Spec = defaultSpec
+		err = scheduler.Get().Add(job)
+	}
+	if err != nil {
+		slog.Error("İş scheduler'a eklenemedi", "job", name, "error", err)
+	}
+}
+
+// rescheduleJobs config yeniden yüklendiğinde kayıtlı işlerin zamanlamalarını günceller.
+// Süren çalışmalar kesilmez; kayıtlı olmayan işler (ör: backup kapalıyken rotation) atlanır.
+func rescheduleJobs(old, cfg *config.Config) {
+	jobs := cfg.KettasLog.Backup.Jobs
+	defaults := map[string]struct {
+		sc   config.ScheduleConfig
+		spec string
+	}{
+		"daily_archive":       {jobs.DailyArchive, dailyArchiveDefaultSpec()},
+		"missed_archive_scan": {jobs.MissedArchiveScan, "@every 5h"},
+		"rotation":            {jobs.Rotation, checkIntervalSpec()},
+		"cleanup":             {jobs.Cleanup, checkIntervalSpec()},
+		"scrub":               {jobs.Scrub, "@weekly"},
+		"parquet_export":      {jobs.Export, scheduler.SpecManual},
+	}
+
+	for name, d := range defaults {
+		spec := d.sc.Cron
+		if spec == "" {
+			spec = d.spec
+		}
+		changed, err := scheduler.Get().Reschedule(name, spec, jobLocation(name, d.sc), time.Duration(d.sc.JitterSec)*time.Second)
+		if errors.Is(err, scheduler.ErrJobNotFound) {
+			continue
+		}
+		if err != nil {
+			slog.Error("İş yeniden zamanlanamadı, önceki zamanlama korunuyor", "job", name, "spec", spec, "error", err)
+			continue
+		}
+		if changed {
+			slog.Info("İş yeniden zamanlandı", "job", name, "spec", spec)
+		}
+	}
+}
+
+// jobLocation işin saat dilimini döner: önce işe özel, sonra jobs.timezone, yoksa sunucu saati.
+func jobLocation(name string, sc config.ScheduleConfig) *time.Location {
+	tz := sc.TimeZone
+	if tz == "" {
+		tz = config.Get().KettasLog.Backup.Jobs.TimeZone
+	}
+	if tz == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		slog.Error("Geçersiz saat dilimi, sunucu saati kullanılıyor", "job", name, "timezone", tz, "error", err)
+		return time.Local
+	}
+	return loc
+}
+
+// dailyArchiveDefaultSpec daily_archive_target_time (HH:MM) değerinden cron ifadesi üretir.
+// Değer config yüklenirken doğrulanır; boşsa varsayılan 00:05'tir.
+func dailyArchiveDefaultSpec() string {
+	hour, minute, err := config.ParseClock(config.Get().KettasLog.Backup.DailyArchiveTargetTime)
+	if err != nil {
+		hour, minute, _ = config.ParseClock(config.DefaultDailyArchiveTargetTime)
+	}
+	return fmt.Sprintf("%d %d * * *", minute, hour)
+}
+
+// checkIntervalSpec check_interval_min değerinden @every ifadesi üretir.
+func checkIntervalSpec() string {
+	return fmt.Sprintf("@every %dm", config.Get().KettasLog.Backup.CheckIntervalMin)
+}
+
+// result backup fonksiyonlarının (dosya sayısı, hata) dönüşünü scheduler.Result'a çevirir.
+func result(filesProcessed int, err error) (scheduler.Result, error) {
+	return scheduler.Result{FilesProcessed: filesProcessed}, err
+}
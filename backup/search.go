@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log-server/archive"
+	"log-server/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ErrHomeNotFound home_id için backup klasörü yoksa döner.
+var ErrHomeNotFound = errors.New("bu home_id için backup bulunamadı")
+
+// SearchQuery bir evin arşivlerinde event araması. Sıfır zamanlar sınırsızdır.
+type SearchQuery struct {
+	HomeId   string
+	From     time.Time
+	To       time.Time
+	Contains string // Boş değilse event'in ham JSON'unda geçmesi gereken metin
+	Limit    int    // 0 ise sınırsız
+}
+
+// SearchArchives evin .zip ve .lsa arşivlerinde sorguya uyan event'leri tarih sırasıyla fn'e verir.
+// .lsa arşivlerde yalnızca zaman aralığıyla kesişen chunk'lar çözülür. Zaman aralığı verildiğinde
+// zaman damgası okunamayan event'ler sonuçlara dahil edilmez. Bulunan event sayısını döner.
+func SearchArchives(q SearchQuery, fn func(event []byte) error) (int, error) {
+	creds := ArchiveCredentials()
+	homeIdDir := fmt.Sprintf("home_id_%s", q.HomeId)
+	loc := homeLocation(homeIdDir)
+	fields := timestampFields()
+
+	// Arşiv günü evin saat diliminde [day, day+24h) aralığını kapsar
+	files, err := homeArchives(homeIdDir, func(day time.Time) bool {
+		if !q.From.IsZero() && !day.AddDate(0, 0, 1).After(q.From) {
+			return false
+		}
+		return q.To.IsZero() || !day.After(q.To)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	contains := []byte(q.Contains)
+	hasRange := !q.From.IsZero() || !q.To.IsZero()
+	found := 0
+	for _, path := range files {
+		err := archive.ForEachLine(path, creds, q.From, q.To, func(line []byte) error {
+			if len(contains) > 0 && !bytes.Contains(line, contains) {
+				return nil
+			}
+			if hasRange {
+				t, ok := eventTime(line, fields, loc)
+				if !ok || (!q.From.IsZero() && t.Before(q.From)) || (!q.To.IsZero() && t.After(q.To)) {
+					return nil
+				}
+			}
+			if err := fn(line); err != nil {
+				return err
+			}
+			found++
+			if q.Limit > 0 && found >= q.Limit {
+				return archive.ErrStop
+			}
+			return nil
+		})
+		if err != nil {
+			return found, fmt.Errorf("%s okunamadı: %w", filepath.Base(path), err)
+		}
+		if q.Limit > 0 && found >= q.Limit {
+			break
+		}
+	}
+	return found, nil
+}
+
+// homeArchives evin arşivlerini (.zip, .lsa) gün, sonra ad sırasıyla döner.
+// include, evin saat diliminde günün başlangıcını alır; nil ise tüm arşivler döner.
+func homeArchives(homeIdDir string, include func(day time.Time) bool) ([]string, error) {
+	backupPath := filepath.Join(config.Get().KettasLog.Backup.BackupDir, homeIdDir)
+	entries, err := os.ReadDir(backupPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrHomeNotFound
+		}
+		return nil, err
+	}
+
+	loc := homeLocation(homeIdDir)
+	type candidate struct {
+		path string
+		day  time.Time
+	}
+	var files []candidate
+	for _, entry := range entries {
+		if entry.IsDir() || !archive.IsArchive(entry.Name()) {
+			continue
+		}
+		dayStr, ok := dayFromFileName(entry.Name())
+		if !ok {
+			continue
+		}
+		day, err := time.ParseInLocation(dayLayout, dayStr, loc)
+		if err != nil || (include != nil && !include(day)) {
+			continue
+		}
+		files = append(files, candidate{path: filepath.Join(backupPath, entry.Name()), day: day})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].day.Equal(files[j].day) {
+			return files[i].day.Before(files[j].day)
+		}
+		return files[i].path < files[j].path
+	})
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths, nil
+}
@@ -0,0 +1,180 @@
+// Package certs sunucunun TLS sertifikalarını yükler, dosyalar değiştiğinde yeniden okur
+// ve ACME'ye ihtiyaç duymadan (offline test, kapalı ağlar) self-signed CA ve sertifika üretir.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log-server/config"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dosyalar en fazla bu sıklıkla kontrol edilir
+const reloadInterval = 5 * time.Second
+
+func init() {
+	config.RegisterValidator(validateFiles)
+}
+
+// validateFiles sertifika/anahtar çiftinin ve CA dosyasının okunabildiğini kontrol eder;
+// böylece bozuk bir sertifika hot reload'da çalışan config'in yerine geçmez.
+func validateFiles(cfg *config.Config) []config.Problem {
+	t := cfg.Server.TLS
+	if !t.Enabled {
+		return nil
+	}
+	var problems []config.Problem
+	if _, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		problems = append(problems, config.Problem{Key: "server.tls.cert_file", Message: err.Error()})
+	}
+	if t.ClientCAFile != "" {
+		if _, err := loadCAPool(t.ClientCAFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			problems = append(problems, config.Problem{Key: "server.tls.client_ca_file", Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// settings tls.Config'i etkileyen ayarlar; değiştiğinde config yeniden kurulur.
+type settings struct {
+	certFile, keyFile, caFile string
+	minVersion, clientAuth    string
+}
+
+type server struct {
+	mu        sync.Mutex
+	settings  settings
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+	tls       *tls.Config
+}
+
+var srv server
+
+// Init sertifikaları ilk kez yükler. TLS açıksa sunucu başlamadan önce çağrılmalıdır.
+func Init() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.load(currentSettings())
+}
+
+// ServerTLS listener için tls.Config döner. Her el sıkışmada güncel sertifika, istemci CA'ları,
+// minimum sürüm ve client_auth ayarı kullanılır.
+func ServerTLS() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return srv.config()
+		},
+	}
+}
+
+func currentSettings() settings {
+	t := config.Get().Server.TLS
+	return settings{
+		certFile:   t.CertFile,
+		keyFile:    t.KeyFile,
+		caFile:     t.ClientCAFile,
+		minVersion: t.MinVersion,
+		clientAuth: strings.ToLower(t.ClientAuth),
+	}
+}
+
+func (s *server) config() (*tls.Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := currentSettings()
+	if next != s.settings || (time.Since(s.checkedAt) >= reloadInterval && s.changed()) {
+		if err := s.load(next); err != nil {
+			slog.Error("TLS sertifikaları yeniden yüklenemedi, öncekiler kullanılıyor", "error", err)
+		}
+	}
+	if s.tls == nil {
+		return nil, errors.New("TLS sertifikası yüklenmedi")
+	}
+	return s.tls, nil
+}
+
+// changed dosyalardan birinin değişip değişmediğini döner. mu tutulurken çağrılmalıdır.
+func (s *server) changed() bool {
+	s.checkedAt = time.Now()
+	for path, mod := range s.modTimes {
+		info, err := os.Stat(path)
+		if err == nil && !info.ModTime().Equal(mod) {
+			return true
+		}
+	}
+	return false
+}
+
+// load sertifikayı ve istemci CA'larını okuyup tls.Config'i kurar. Hata durumunda önceki
+// config kullanılmaya devam edilir. mu tutulurken çağrılmalıdır.
+func (s *server) load(st settings) error {
+	s.checkedAt = time.Now()
+	modTimes := make(map[string]time.Time)
+	for _, path := range []string{st.certFile, st.keyFile, st.caFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[path] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(st.certFile, st.keyFile)
+	if err != nil {
+		return fmt.Errorf("sertifika yüklenemedi: %w", err)
+	}
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if st.minVersion == "1.3" {
+		tc.MinVersion = tls.VersionTLS13
+	}
+
+	switch st.clientAuth {
+	case "optional":
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	case "required":
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if tc.ClientAuth != tls.NoClientCert {
+		pool, err := loadCAPool(st.caFile)
+		if err != nil {
+			return err
+		}
+		tc.ClientCAs = pool
+	}
+
+	reloaded := s.tls != nil
+	s.tls = tc
+	s.settings = st
+	s.modTimes = modTimes
+	if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+		slog.Info("TLS sertifikası yüklendi", "subject", leaf.Subject.String(), "not_after", leaf.NotAfter, "client_auth", st.clientAuth, "reloaded", reloaded)
+	}
+	return nil
+}
+
+func loadCAPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: PEM sertifika bulunamadı", path)
+	}
+	return pool, nil
+}
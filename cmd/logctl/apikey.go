@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log-server/auth"
+	"log-server/config"
+	"log-server/lock"
+	"os"
+	"strings"
+	"time"
+)
+
+// runApiKey API anahtarlarını yönetir. create/list/revoke sunucunun kullandığı anahtar
+// dosyasını (auth.keys_file) düzenler; çalışan sunucular değişikliği birkaç saniye içinde görür.
+func runApiKey(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("kullanım: logctl apikey <generate|create|list|revoke>")
+	}
+	cmd, args := args[0], args[1:]
+
+	if cmd == "generate" {
+		return runApiKeyGenerate(args)
+	}
+
+	store, err := openKeyStore()
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "list":
+		return printJSON(store.List(auth.KindApiKey, ""))
+
+	case "create":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		name := fs.String("name", "", "Anahtarın adı (ör: gateway-eu)")
+		scopes := fs.String("scopes", "", "Virgülle ayrılmış scope'lar: "+strings.Join(auth.Scopes, ", "))
+		homeIds := fs.String("home-ids", "", "Virgülle ayrılmış home_id'ler (boşsa tüm evler)")
+		ttl := fs.Duration("ttl", 0, "Geçerlilik süresi (ör: 720h; 0: süresiz)")
+		fs.Parse(args)
+
+		req := auth.CreateKeyRequest{Name: *name, Scopes: splitList(*scopes), HomeIds: splitList(*homeIds), CreatedBy: "logctl"}
+		if *ttl > 0 {
+			expires := time.Now().Add(*ttl).UTC()
+			req.ExpiresAt = &expires
+		}
+		info, secret, err := store.Create(req)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Secret yalnızca bir kez gösterilir, güvenli bir yere kaydedin.\n")
+		return printJSON(map[string]interface{}{"key": info, "secret": secret})
+
+	case "revoke":
+		if len(args) < 1 {
+			return fmt.Errorf("kullanım: logctl apikey revoke <id>")
+		}
+		info, err := store.Revoke(auth.KindApiKey, args[0])
+		if err != nil {
+			return err
+		}
+		return printJSON(info)
+
+	default:
+		return fmt.Errorf("bilinmeyen apikey komutu: %s", cmd)
+	}
+}
+
+// runDevice eve bağlı cihaz token'larını yönetir.
+func runDevice(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("kullanım: logctl device <provision -home-id id [-name n] [-ttl d]|list [-home-id id]|revoke id>")
+	}
+	cmd, args := args[0], args[1:]
+
+	store, err := openKeyStore()
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "list":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		homeId := fs.String("home-id", "", "Yalnızca bu evin cihazları")
+		fs.Parse(args)
+		return printJSON(store.List(auth.KindDevice, *homeId))
+
+	case "provision":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		homeId := fs.String("home-id", "", "Cihazın bağlı olacağı home_id")
+		name := fs.String("name", "", "Cihazın adı (boşsa home_id)")
+		ttl := fs.Duration("ttl", 0, "Geçerlilik süresi (ör: 8760h; 0: süresiz)")
+		fs.Parse(args)
+
+		req := auth.ProvisionDeviceRequest{HomeId: *homeId, Name: *name, CreatedBy: "logctl"}
+		if *ttl > 0 {
+			expires := time.Now().Add(*ttl).UTC()
+			req.ExpiresAt = &expires
+		}
+		info, token, err := store.ProvisionDevice(req)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Token yalnızca bir kez gösterilir, cihaza yükleyin.\n")
+		return printJSON(map[string]interface{}{"device": info, "token": token})
+
+	case "revoke":
+		if len(args) < 1 {
+			return fmt.Errorf("kullanım: logctl device revoke <id>")
+		}
+		info, err := store.Revoke(auth.KindDevice, args[0])
+		if err != nil {
+			return err
+		}
+		return printJSON(info)
+
+	default:
+		return fmt.Errorf("bilinmeyen device komutu: %s", cmd)
+	}
+}
+
+// openKeyStore config'i yükleyip sunucunun kullandığı anahtar deposunu açar.
+func openKeyStore() (*auth.KeyStore, error) {
+	if err := config.LoadFile(configPath); err != nil {
+		return nil, err
+	}
+	if err := lock.Init(); err != nil {
+		return nil, fmt.Errorf("kilit sağlayıcısı başlatılamadı: %w", err)
+	}
+	return auth.OpenKeyStore(auth.KeyStorePath())
+}
+
+// runApiKeyGenerate auth.api_value için rastgele bir API anahtarı üretir; anahtar kaynağı varsa
+// config'e yapıştırılacak ENC(...) halini de yazar.
+func runApiKeyGenerate(args []string) error {
+	fs := flag.NewFlagSet("apikey generate", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "ENC(...) çıktısı için anahtar dosyası")
+	fs.Parse(args)
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	value := base64.RawURLEncoding.EncodeToString(raw)
+	fmt.Printf("API anahtarı: %s\n", value)
+
+	keys, err := loadKeys(*keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ENC(...) üretilmedi: %v\n", err)
+		return nil
+	}
+	encrypted, err := keys.Encrypt(value)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("auth.api_value: %s\n", encrypted)
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log-server/archive"
+	"log-server/audit"
+	"log-server/backup"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+func runValidate(args []string) error {
+	if err := loadConfig(); err != nil {
+		return err
+	}
+	fmt.Println("Config geçerli")
+	return nil
+}
+
+func runHomes(args []string) error {
+	if err := loadConfig(); err != nil {
+		return err
+	}
+	homes, err := backup.ListHomes()
+	if err != nil {
+		return err
+	}
+	return printJSON(homes)
+}
+
+type archiveInfo struct {
+	Path      string    `json:"path"`
+	SizeBytes int64     `json:"size_bytes"`
+	ModTime   time.Time `json:"mod_time"`
+	KeyId     string    `json:"key_id,omitempty"`
+}
+
+func runArchives(args []string) error {
+	fs := flag.NewFlagSet("archives", flag.ExitOnError)
+	homeId := fs.String("home-id", "", "home_id")
+	fs.Parse(args)
+	if *homeId == "" {
+		return fmt.Errorf("-home-id gerekli")
+	}
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	paths, err := backup.HomeArchives(*homeId)
+	if err != nil {
+		return err
+	}
+	out := make([]archiveInfo, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		keyId, _ := archive.KeyID(path)
+		out = append(out, archiveInfo{Path: path, SizeBytes: info.Size(), ModTime: info.ModTime(), KeyId: keyId})
+	}
+	return printJSON(out)
+}
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	homeId := fs.String("home-id", "", "Evin tüm arşivlerini doğrula")
+	fs.Parse(args)
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	paths := fs.Args()
+	if *homeId != "" {
+		homePaths, err := backup.HomeArchives(*homeId)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, homePaths...)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("-home-id veya arşiv yolu gerekli")
+	}
+
+	corrupt := 0
+	for _, path := range paths {
+		events, err := backup.VerifyArchive(path)
+		if err != nil {
+			corrupt++
+			fmt.Printf("BOZUK  %s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("OK     %s (%d event)\n", path, events)
+	}
+	if corrupt > 0 {
+		return fmt.Errorf("%d/%d arşiv doğrulanamadı", corrupt, len(paths))
+	}
+	return nil
+}
+
+func runCat(args []string) error {
+	fs := flag.NewFlagSet("cat", flag.ExitOnError)
+	fromStr := fs.String("from", "", "Başlangıç zamanı (RFC3339, .lsa'da chunk seçimi için)")
+	toStr := fs.String("to", "", "Bitiş zamanı (RFC3339)")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		return fmt.Errorf("arşiv yolu gerekli")
+	}
+
+	var from, to time.Time
+	var err error
+	if *fromStr != "" {
+		if from, err = time.Parse(time.RFC3339, *fromStr); err != nil {
+			return fmt.Errorf("geçersiz -from: %w", err)
+		}
+	}
+	if *toStr != "" {
+		if to, err = time.Parse(time.RFC3339, *toStr); err != nil {
+			return fmt.Errorf("geçersiz -to: %w", err)
+		}
+	}
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	out := &countingWriter{w: os.Stdout}
+	w := bufio.NewWriterSize(out, 64*1024)
+	ev := audit.Event{Action: audit.ActionCat, From: *fromStr, To: *toStr}
+	defer func() {
+		ev.Bytes = out.n
+		recordAudit(ev)
+	}()
+	for _, path := range fs.Args() {
+		if !archive.IsArchive(filepath.Base(path)) {
+			return fmt.Errorf("%s bir arşiv değil", path)
+		}
+		if homeId, ok := archiveHomeId(path); ok && !slices.Contains(ev.HomeIds, homeId) {
+			ev.HomeIds = append(ev.HomeIds, homeId)
+		}
+		if _, err := backup.CatArchive(w, path, from, to); err != nil {
+			w.Flush()
+			ev.Error = err.Error()
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return w.Flush()
+}
+
+// archiveHomeId arşivin bulunduğu home_id_<id> klasöründen home_id'yi çıkarır.
+func archiveHomeId(path string) (string, bool) {
+	return strings.CutPrefix(filepath.Base(filepath.Dir(path)), "home_id_")
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	homeId := fs.String("home-id", "", "home_id")
+	startDate := fs.String("start-date", "", "DD_MM_YYYY")
+	endDate := fs.String("end-date", "", "DD_MM_YYYY (varsayılan: start-date)")
+	target := fs.String("to", "./restores", "Hedef dizin (logs_dir verilirse event'ler yeniden arşivlenir)")
+	fs.Parse(args)
+	if *homeId == "" || *startDate == "" {
+		return fmt.Errorf("-home-id ve -start-date gerekli")
+	}
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	files, err := backup.RestoreArchives(backup.RestoreRequest{
+		HomeId:    *homeId,
+		StartDate: *startDate,
+		EndDate:   *endDate,
+		TargetDir: *target,
+	})
+	ev := audit.Event{Action: audit.ActionRestore, HomeIds: []string{*homeId}, From: *startDate, To: *endDate}
+	for _, f := range files {
+		if info, err := os.Stat(f.Path); err == nil {
+			ev.Bytes += info.Size()
+		}
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	recordAudit(ev)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("aralıkta arşiv bulunamadı")
+	}
+	return printJSON(files)
+}
+
+func runCatalog(args []string) error {
+	if len(args) < 1 || args[0] != "rebuild" {
+		return fmt.Errorf("kullanım: logctl catalog rebuild")
+	}
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	cat, err := backup.RebuildCatalog()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Katalog yeniden oluşturuldu: %d arşiv, %d okunamadı\n", len(cat.Archives), cat.Corrupt)
+	if cat.Corrupt > 0 {
+		return fmt.Errorf("%d arşiv okunamadı", cat.Corrupt)
+	}
+	return nil
+}
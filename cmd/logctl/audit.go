@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log-server/audit"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// runAudit denetim kaydını doğrular veya listeler.
+func runAudit(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("kullanım: logctl audit <verify [-file f] [-anchor seq:hash]...|list [filtreler]>")
+	}
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case "verify":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		file := fs.String("file", "", "Denetim dosyası (varsayılan: config'deki audit.file)")
+		var anchors []audit.Anchor
+		fs.Func("anchor", "Daha önce kaydedilmiş seq:hash (tekrar edilebilir)", func(s string) error {
+			a, err := audit.ParseAnchor(s)
+			if err != nil {
+				return err
+			}
+			anchors = append(anchors, a)
+			return nil
+		})
+		fs.Parse(args)
+
+		// audit.hmac_key config'ten okunur; -file yalnızca dosya yolunu değiştirir
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		if *file == "" {
+			*file = audit.Path()
+		}
+		key := audit.Key()
+		res, err := audit.Verify(*file, key, anchors...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Zincir geçerli. head değerini ayrı bir yerde saklayıp sonraki doğrulamalarda -anchor ile verin.\n")
+		if key == nil {
+			fmt.Fprintf(os.Stderr, "Uyarı: audit.hmac_key yok; dosyaya yazabilen biri zinciri yeniden hesaplayabilir, yalnızca anchor'lar bunu tespit eder.\n")
+		} else if unkeyed := res.Entries - res.Keyed; unkeyed > 0 {
+			fmt.Fprintf(os.Stderr, "Uyarı: %d kayıt anahtarsız (audit.hmac_key'den önce yazılmış); bunlar yalnızca anchor'larla korunur.\n", unkeyed)
+		}
+		if len(res.Skipped) > 0 {
+			fmt.Fprintf(os.Stderr, "Uyarı: çözülemeyen satırlar atlandı (çökme sırasında yarım kalmış): %v\n", res.Skipped)
+		}
+		return printJSON(res)
+
+	case "list":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		since := fs.String("since", "", "RFC3339")
+		action := fs.String("action", "", "home_logs, all_logs, search, restore, cat, admin")
+		actor := fs.String("actor", "", "Kimlik id'si (ör: ak_..., jwt:alice)")
+		homeId := fs.String("home-id", "", "Yalnızca bu eve erişimler")
+		limit := fs.Int("limit", 100, "En yeni N kayıt (0: tümü)")
+		fs.Parse(args)
+
+		q := audit.Query{Action: *action, Actor: *actor, HomeId: *homeId, Limit: *limit}
+		if *since != "" {
+			t, err := time.Parse(time.RFC3339, *since)
+			if err != nil {
+				return fmt.Errorf("geçersiz -since: %w", err)
+			}
+			q.Since = t
+		}
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		records, err := audit.Read(q)
+		if err != nil {
+			return err
+		}
+		return printJSON(records)
+
+	default:
+		return fmt.Errorf("bilinmeyen audit komutu: %s", cmd)
+	}
+}
+
+// recordAudit logctl ile yapılan veri erişimini denetim kaydına yazar. Kimlik, komutu
+// çalıştıran işletim sistemi kullanıcısıdır.
+func recordAudit(ev audit.Event) {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	ev.Actor = "logctl:" + name
+	ev.AuthMethod = "cli"
+	ev.Path = "logctl " + strings.Join(os.Args[1:], " ")
+	if err := audit.Record(ev); err != nil {
+		fmt.Fprintf(os.Stderr, "Uyarı: denetim kaydı yazılamadı: %v\n", err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log-server/backup"
+	"log-server/config"
+	"os"
+	"time"
+)
+
+func runPlan(args []string) error {
+	if err := config.LoadFile(configPath); err != nil {
+		return err
+	}
+	plan, err := backup.BuildPlan()
+	if err != nil {
+		return fmt.Errorf("plan hesaplanamadı: %w", err)
+	}
+	return printJSON(plan)
+}
+
+func runJournal(args []string) error {
+	fs := flag.NewFlagSet("journal", flag.ExitOnError)
+	since := fs.String("since", "", "Başlangıç zamanı (RFC3339)")
+	homeId := fs.String("home-id", "", "home_id'ye göre filtrele")
+	limit := fs.Int("limit", 0, "En fazla bu kadar kayıt (0: hepsi)")
+	fs.Parse(args)
+	if err := config.LoadFile(configPath); err != nil {
+		return err
+	}
+
+	q := backup.DeletionQuery{Limit: *limit}
+	if *homeId != "" {
+		q.HomeIdDir = "home_id_" + *homeId
+	}
+	if *since != "" {
+		t, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			return fmt.Errorf("geçersiz -since değeri: %w", err)
+		}
+		q.Since = t
+	}
+
+	records, err := backup.ReadDeletionJournal(q)
+	if err != nil {
+		return fmt.Errorf("journal okunamadı: %w", err)
+	}
+	return printJSON(records)
+}
+
+func runErasureVerify(args []string) error {
+	fs := flag.NewFlagSet("erasure-verify", flag.ExitOnError)
+	publicKey := fs.String("public-key", "", "Beklenen Ed25519 public key (base64; GET /v1/erasures/public-key)")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		return fmt.Errorf("sertifika dosyası gerekli")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	// Dosya doğrudan sertifika veya DELETE /v1/homes/:id yanıtı olabilir
+	var resp struct {
+		Certificate *backup.ErasureCertificate `json:"certificate"`
+	}
+	var cert backup.ErasureCertificate
+	if err := json.Unmarshal(data, &resp); err == nil && resp.Certificate != nil {
+		cert = *resp.Certificate
+	} else if err := json.Unmarshal(data, &cert); err != nil {
+		return fmt.Errorf("sertifika çözülemedi: %w", err)
+	}
+
+	var expected ed25519.PublicKey
+	if *publicKey != "" {
+		pub, err := base64.StdEncoding.DecodeString(*publicKey)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return fmt.Errorf("geçersiz -public-key")
+		}
+		expected = pub
+	}
+
+	rec, err := backup.VerifyErasureCertificate(&cert, expected)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("İmza geçerli: %s (home_id %s, %d dosya, %d anahtar, complete=%t)\n",
+		rec.Id, rec.HomeId, len(rec.Files), len(rec.KeysDestroyed), rec.Complete)
+	if expected == nil {
+		fmt.Println("Uyarı: -public-key verilmedi, sertifikadaki anahtar kullanıldı.")
+	}
+	return nil
+}
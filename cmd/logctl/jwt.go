@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log-server/auth"
+	"os"
+	"time"
+)
+
+// runJWT kimlik sağlayıcısı olmadan auth.jwt'yi denemek için yerel bir Ed25519 anahtarı
+// ve JWKS üretir, bu anahtarla test token'ları imzalar. Config okunmaz.
+func runJWT(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("kullanım: logctl jwt <keygen|mint>")
+	}
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case "keygen":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		keyOut := fs.String("key-out", "jwt_signing.pem", "Özel anahtarın yazılacağı dosya")
+		jwksOut := fs.String("jwks-out", "jwks.json", "auth.jwt.jwks_file olarak kullanılacak JWKS")
+		kid := fs.String("kid", "local-1", "Anahtar id'si")
+		fs.Parse(args)
+
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*keyOut, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+			return err
+		}
+		set := auth.JWKS{Keys: []auth.JWK{{
+			Kty: "OKP", Crv: "Ed25519", Use: "sig", Alg: "EdDSA", Kid: *kid,
+			X: base64.RawURLEncoding.EncodeToString(pub),
+		}}}
+		data, err := json.MarshalIndent(set, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*jwksOut, data, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Özel anahtar: %s\nJWKS: %s\n", *keyOut, *jwksOut)
+		return nil
+
+	case "mint":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		keyFile := fs.String("key", "jwt_signing.pem", "keygen'in ürettiği özel anahtar")
+		kid := fs.String("kid", "local-1", "Anahtar id'si")
+		sub := fs.String("sub", "", "Kullanıcı (sub claim'i)")
+		email := fs.String("email", "", "email claim'i")
+		roles := fs.String("roles", "", "Virgülle ayrılmış roller")
+		homeIds := fs.String("home-ids", "", "Virgülle ayrılmış home_id'ler (* tüm evler)")
+		iss := fs.String("iss", "", "iss claim'i")
+		aud := fs.String("aud", "", "aud claim'i")
+		ttl := fs.Duration("ttl", time.Hour, "Geçerlilik süresi")
+		fs.Parse(args)
+
+		if *sub == "" {
+			return fmt.Errorf("-sub gerekli")
+		}
+		priv, err := readEd25519Key(*keyFile)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		claims := map[string]interface{}{"sub": *sub, "iat": now.Unix(), "exp": now.Add(*ttl).Unix()}
+		for name, value := range map[string]string{"email": *email, "iss": *iss, "aud": *aud} {
+			if value != "" {
+				claims[name] = value
+			}
+		}
+		if list := splitList(*roles); len(list) > 0 {
+			claims["roles"] = list
+		}
+		if list := splitList(*homeIds); len(list) > 0 {
+			claims["home_ids"] = list
+		}
+
+		header, _ := json.Marshal(map[string]string{"alg": "EdDSA", "typ": "JWT", "kid": *kid})
+		payload, err := json.Marshal(claims)
+		if err != nil {
+			return err
+		}
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		fmt.Println(signed + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(priv, []byte(signed))))
+		return nil
+
+	default:
+		return fmt.Errorf("bilinmeyen jwt komutu: %s", cmd)
+	}
+}
+
+func readEd25519Key(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: PEM bulunamadı", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: Ed25519 anahtarı değil", path)
+	}
+	return priv, nil
+}
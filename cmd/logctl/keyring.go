@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log-server/config"
+	"log-server/keys"
+	"log-server/lock"
+	"os"
+)
+
+// runKeyring envelope encryption anahtarlarını yönetir. rotate-master sonrası sunucular
+// yeni master key ile yeniden başlatılmalıdır; arşivler yeniden yazılmaz.
+func runKeyring(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("kullanım: logctl keyring <generate|list|rotate-home -home-id id|rotate-master -new-key-file f>")
+	}
+	cmd, args := args[0], args[1:]
+
+	if cmd == "generate" {
+		key, err := keys.GenerateMasterKey()
+		if err != nil {
+			return fmt.Errorf("anahtar üretilemedi: %w", err)
+		}
+		fmt.Println(key)
+		return nil
+	}
+
+	// Keyring keys.enabled kapalıyken de yönetilebilsin diye keys.Init yerine doğrudan açılır
+	if err := config.LoadFile(configPath); err != nil {
+		return err
+	}
+	if err := lock.Init(); err != nil {
+		return fmt.Errorf("kilit sağlayıcısı başlatılamadı: %w", err)
+	}
+	master, err := keys.LoadMasterKey()
+	if err != nil {
+		return fmt.Errorf("master key okunamadı: %w", err)
+	}
+	kr, err := keys.Open(keys.KeyringPath(), master)
+	if err != nil {
+		return fmt.Errorf("keyring açılamadı: %w", err)
+	}
+
+	switch cmd {
+	case "list":
+		return printJSON(map[string]interface{}{
+			"master_key_id": kr.MasterKeyID(),
+			"homes":         kr.List(),
+		})
+
+	case "rotate-home":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		homeId := fs.String("home-id", "", "Anahtarı yenilenecek home_id")
+		fs.Parse(args)
+		if *homeId == "" {
+			return fmt.Errorf("-home-id gerekli")
+		}
+
+		id, err := kr.RotateHomeKey("home_id_" + *homeId)
+		if err != nil {
+			return fmt.Errorf("anahtar yenilenemedi: %w", err)
+		}
+		fmt.Printf("home_id_%s için yeni aktif anahtar: %s\n", *homeId, id)
+		fmt.Println("Eski arşivler önceki anahtarlarla okunmaya devam eder.")
+
+	case "rotate-master":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		newKeyFile := fs.String("new-key-file", "", "Yeni master key dosyası (base64 veya hex)")
+		fs.Parse(args)
+		if *newKeyFile == "" {
+			return fmt.Errorf("-new-key-file gerekli")
+		}
+
+		data, err := os.ReadFile(*newKeyFile)
+		if err != nil {
+			return fmt.Errorf("yeni master key okunamadı: %w", err)
+		}
+		newMaster, err := keys.ParseMasterKey(string(data))
+		if err != nil {
+			return fmt.Errorf("yeni master key geçersiz: %w", err)
+		}
+
+		oldId := kr.MasterKeyID()
+		count, err := kr.Rewrap(newMaster)
+		if err != nil {
+			return fmt.Errorf("anahtarlar yeniden sarılamadı: %w", err)
+		}
+		fmt.Printf("%d veri anahtarı yeniden sarıldı: %s → %s\n", count, oldId, kr.MasterKeyID())
+		fmt.Println("Sunucuları yeni master key ile yeniden başlatın.")
+
+	default:
+		return fmt.Errorf("bilinmeyen keyring komutu: %s", cmd)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// logctl log-server için yönetim aracıdır. Sunucuyla aynı config, crypto, keys ve
+// backup paketlerini kullanır; böylece arşiv okuma, doğrulama ve şifreleme davranışı
+// sunucuyla birebir aynıdır.
+//
+// Önceki ayrı araçların yerini alır; komutlar ve flag'leri aynıdır:
+//
+//	encrypt, encrypt rekey              → logctl encrypt, logctl rekey
+//	keyring <generate|list|rotate-...>  → logctl keyring
+//	backup-plan, backup-plan -journal   → logctl plan, logctl journal
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log-server/config"
+	"log-server/keys"
+	"log-server/lock"
+	"os"
+	"sort"
+)
+
+type command struct {
+	usage string
+	help  string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"encrypt":        {"encrypt [-key-file f] <değer>", "Config değerini ENC(vN:...) olarak şifrele", runEncrypt},
+	"decrypt":        {"decrypt [-key-file f] <ENC(...)>", "ENC(...) değerini çöz", runDecrypt},
+	"rekey":          {"rekey [-key-file f] [-version N] [-out f] <config.yaml>", "Config'deki ENC değerlerini yeni anahtar sürümüyle yeniden şifrele", runRekey},
+	"validate":       {"validate", "Config dosyasını yükle ve doğrula", runValidate},
+	"homes":          {"homes", "Evleri canlı log ve arşiv özetleriyle listele", runHomes},
+	"archives":       {"archives -home-id <id>", "Evin arşivlerini listele", runArchives},
+	"verify":         {"verify [-home-id <id>] [arşiv...]", "Arşivleri şifre ve bütünlük açısından doğrula", runVerify},
+	"cat":            {"cat [-from RFC3339] [-to RFC3339] <arşiv>", "Arşivi çözüp event'leri stdout'a NDJSON olarak yaz", runCat},
+	"restore":        {"restore -home-id <id> -start-date DD_MM_YYYY [-end-date ...] [-to dizin]", "Arşivleri NDJSON dosyalarına geri aç", runRestore},
+	"catalog":        {"catalog rebuild", "backup_dir/catalog.json'ı arşivleri okuyarak yeniden oluştur", runCatalog},
+	"plan":           {"plan", "Bir sonraki cleanup/rotation'ın dry-run planı", runPlan},
+	"journal":        {"journal [-since RFC3339] [-home-id id] [-limit N]", "Gerçekleşmiş silmelerin journal'ı", runJournal},
+	"keyring":        {"keyring <generate|list|rotate-home|rotate-master>", "Envelope encryption anahtarlarını yönet", runKeyring},
+	"apikey":         {"apikey <generate|create|list|revoke> [seçenekler]", "Scope'lu API anahtarlarını yönet", runApiKey},
+	"device":         {"device <provision|list|revoke> [seçenekler]", "Eve bağlı cihaz token'larını yönet", runDevice},
+	"sign":           {"sign -key-id id -secret s -method M -path /yol [-body-file f]", "İmzalı istek header'larını üret", runSign},
+	"jwt":            {"jwt <keygen|mint> [seçenekler]", "Yerel JWKS ve test için JWT üret", runJWT},
+	"tls":            {"tls <ca|issue> [seçenekler]", "Self-signed CA ve sunucu/istemci sertifikaları üret", runTLS},
+	"audit":          {"audit <verify [-file f] [-anchor seq:hash]|list [-since t] [-action a] [-actor id] [-home-id id]>", "Denetim kaydının hash zincirini doğrula veya kayıtları listele", runAudit},
+	"erasure-verify": {"erasure-verify [-public-key base64] <sertifika.json>", "Silme sertifikasının imzasını doğrula", runErasureVerify},
+}
+
+var configPath string
+
+func usage() {
+	fmt.Println("Kullanım: logctl [-config config.yaml] [-set key=value]... <komut> [seçenekler]")
+	fmt.Println()
+	fmt.Println("Komutlar:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-70s %s\n", commands[name].usage, commands[name].help)
+	}
+}
+
+func main() {
+	flag.StringVar(&configPath, "config", "", "Config dosyası (varsayılan: $"+config.ConfigFileEnv+" veya ./config.yaml)")
+	flag.Func("set", "Config anahtarını ayarla, tekrar edilebilir", func(s string) error {
+		key, value, err := config.ParseOverride(s)
+		if err != nil {
+			return err
+		}
+		return config.SetOverride(key, value)
+	})
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(1)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Printf("Bilinmeyen komut: %s\n\n", flag.Arg(0))
+		usage()
+		os.Exit(1)
+	}
+	if err := cmd.run(flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Hata: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadConfig config'i yükler ve arşivleri okuyabilmek için keyring'i açar.
+func loadConfig() error {
+	if err := config.LoadFile(configPath); err != nil {
+		return err
+	}
+	if err := lock.Init(); err != nil {
+		return fmt.Errorf("kilit sağlayıcısı başlatılamadı: %w", err)
+	}
+	if err := keys.Init(); err != nil {
+		return fmt.Errorf("keyring başlatılamadı: %w", err)
+	}
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
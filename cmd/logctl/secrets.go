@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log-server/crypto"
+	"os"
+)
+
+func runEncrypt(args []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "Anahtar dosyası")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		return fmt.Errorf("şifrelenecek değer gerekli")
+	}
+
+	keys, err := loadKeys(*keyFile)
+	if err != nil {
+		return err
+	}
+	encrypted, err := keys.Encrypt(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("şifreleme hatası: %w", err)
+	}
+
+	fmt.Println(encrypted)
+	fmt.Fprintln(os.Stderr, "Bu değeri config.yaml'a yapıştırabilirsiniz.")
+	return nil
+}
+
+func runDecrypt(args []string) error {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "Anahtar dosyası")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		return fmt.Errorf("çözülecek ENC(...) değeri gerekli")
+	}
+	if !crypto.IsEncrypted(fs.Arg(0)) {
+		return fmt.Errorf("değer ENC(...) biçiminde değil")
+	}
+
+	keys, err := loadKeys(*keyFile)
+	if err != nil {
+		return err
+	}
+	plain, err := keys.DecryptIfEncrypted(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Println(plain)
+	return nil
+}
+
+// runRekey config dosyasındaki tüm ENC(...) değerlerini hedef sürümün anahtarıyla yeniden şifreler.
+func runRekey(args []string) error {
+	fs := flag.NewFlagSet("rekey", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "Anahtar dosyası (eski ve yeni sürümleri içermeli)")
+	version := fs.Int("version", 0, "Hedef anahtar sürümü (varsayılan: en yüksek sürüm)")
+	out := fs.String("out", "", "Çıktı dosyası (varsayılan: girdinin üzerine yazılır)")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		return fmt.Errorf("config dosyası gerekli")
+	}
+
+	keys, err := loadKeys(*keyFile)
+	if err != nil {
+		return err
+	}
+	if *version == 0 {
+		*version = keys.Current()
+	}
+
+	in := fs.Arg(0)
+	info, err := os.Stat(in)
+	if err != nil {
+		return fmt.Errorf("config okunamadı: %w", err)
+	}
+	data, err := os.ReadFile(in)
+	if err != nil {
+		return fmt.Errorf("config okunamadı: %w", err)
+	}
+
+	text, count, err := keys.Rekey(string(data), *version)
+	if err != nil {
+		return fmt.Errorf("yeniden şifreleme hatası: %w", err)
+	}
+
+	target := *out
+	if target == "" {
+		target = in
+	}
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, []byte(text), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("config yazılamadı: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("config yazılamadı: %w", err)
+	}
+
+	fmt.Printf("%d değer v%d anahtarıyla yeniden şifrelendi: %s\n", count, *version, target)
+	return nil
+}
+
+// loadKeys secret anahtarlarını sunucuyla aynı kaynaklardan okur.
+func loadKeys(keyFile string) (*crypto.KeySet, error) {
+	keys, source, err := crypto.LoadKeySet(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("anahtar yüklenemedi: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Anahtar kaynağı: %s (sürümler: %v)\n", source, keys.Versions())
+	return keys, nil
+}
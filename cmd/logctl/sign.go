@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log-server/auth"
+	"os"
+	"strconv"
+	"time"
+)
+
+// runSign imzalı istek header'larını üretir; curl ile denemek ve istemci
+// implementasyonlarını doğrulamak için. Config okunmaz.
+func runSign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyId := fs.String("key-id", "", "Anahtar id'si (ak_..., dev_... veya auth.api_value için legacy)")
+	secret := fs.String("secret", "", "Anahtarın secret'ı (boşsa LOGSERVER_SIGNING_SECRET)")
+	method := fs.String("method", "GET", "HTTP method")
+	path := fs.String("path", "", "Query dahil istek yolu (ör: /home-logs)")
+	bodyFile := fs.String("body-file", "", "Gönderilecek body'nin birebir kopyası (boşsa body yok)")
+	fs.Parse(args)
+
+	if *secret == "" {
+		*secret = os.Getenv("LOGSERVER_SIGNING_SECRET")
+	}
+	if *keyId == "" || *secret == "" || *path == "" {
+		return fmt.Errorf("-key-id, -secret ve -path gerekli")
+	}
+
+	var body []byte
+	if *bodyFile != "" {
+		data, err := os.ReadFile(*bodyFile)
+		if err != nil {
+			return err
+		}
+		body = data
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(raw)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	fmt.Printf("%s: %s\n", auth.HeaderKeyId, *keyId)
+	fmt.Printf("%s: %s\n", auth.HeaderTimestamp, timestamp)
+	fmt.Printf("%s: %s\n", auth.HeaderNonce, nonce)
+	fmt.Printf("%s: %s\n", auth.HeaderSignature, auth.Sign(*secret, *method, *path, timestamp, nonce, body))
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log-server/certs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runTLS ACME olmadan (offline test, kapalı ağ) kullanılacak self-signed bir CA ve bu CA ile
+// imzalanmış sunucu/istemci sertifikaları üretir. Config okunmaz.
+func runTLS(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("kullanım: logctl tls <ca|issue>")
+	}
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case "ca":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		dir := fs.String("dir", ".", "ca.pem ve ca-key.pem'in yazılacağı dizin")
+		cn := fs.String("cn", "log-server CA", "CA'nın adı")
+		ttl := fs.Duration("ttl", 10*365*24*time.Hour, "Geçerlilik süresi")
+		fs.Parse(args)
+
+		certPEM, keyPEM, err := certs.NewCA(*cn, *ttl)
+		if err != nil {
+			return err
+		}
+		return writePair(filepath.Join(*dir, "ca"), certPEM, keyPEM)
+
+	case "issue":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		caDir := fs.String("ca-dir", ".", "ca.pem ve ca-key.pem'in bulunduğu dizin")
+		cn := fs.String("cn", "", "Sertifikanın CN'i (istemci sertifikalarında kurala göre home_id veya rol)")
+		ou := fs.String("ou", "", "Sertifikanın OU'su (opsiyonel)")
+		hosts := fs.String("hosts", "localhost,127.0.0.1", "Sunucu sertifikası için virgülle ayrılmış DNS adları/IP'ler")
+		client := fs.Bool("client", false, "İstemci (mTLS) sertifikası üret")
+		ttl := fs.Duration("ttl", 365*24*time.Hour, "Geçerlilik süresi")
+		out := fs.String("out", "", "Çıktı dosyalarının öneki (varsayılan: CN; <out>.pem ve <out>-key.pem)")
+		fs.Parse(args)
+
+		caCert, err := os.ReadFile(filepath.Join(*caDir, "ca.pem"))
+		if err != nil {
+			return err
+		}
+		caKey, err := os.ReadFile(filepath.Join(*caDir, "ca-key.pem"))
+		if err != nil {
+			return err
+		}
+		certPEM, keyPEM, err := certs.Issue(caCert, caKey, certs.IssueRequest{
+			CommonName: *cn,
+			OrgUnit:    *ou,
+			Hosts:      splitList(*hosts),
+			Client:     *client,
+			TTL:        *ttl,
+		})
+		if err != nil {
+			return err
+		}
+		if *out == "" {
+			*out = *cn
+		}
+		return writePair(*out, certPEM, keyPEM)
+
+	default:
+		return fmt.Errorf("bilinmeyen tls komutu: %s", cmd)
+	}
+}
+
+func writePair(prefix string, certPEM, keyPEM []byte) error {
+	if err := os.WriteFile(prefix+"-key.pem", keyPEM, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(prefix+".pem", certPEM, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Sertifika: %s.pem\nAnahtar: %s-key.pem\n", prefix, prefix)
+	return nil
+}
@@ -1,16 +1,14 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"log-server/crypto"
 	"os"
+	"sync/atomic"
 
 	"github.com/spf13/viper"
 )
 
-// Şifrelenmiş config değerlerini çözmek için kullanılan sabit anahtar
-const magicKey = "!n0h0m-53cr3t-K3y-L0g-53rv3r"
-
 type Config struct {
 	Server      ServerConfig      `mapstructure:"server"`
 	Auth        AuthConfig        `mapstructure:"auth"`
@@ -18,6 +16,51 @@ type Config struct {
 	InternalLog InternalLogConfig `mapstructure:"internal_log"`
 	KettasLog   KettasLogConfig   `mapstructure:"kettas_log"`
 	AiService   AiServiceConfig   `mapstructure:"ai_service"`
+	Cluster     ClusterConfig     `mapstructure:"cluster"`
+	Secrets     SecretsConfig     `mapstructure:"secrets"`
+	RateLimit   RateLimitConfig   `mapstructure:"rate_limit"`
+	Audit       AuditConfig       `mapstructure:"audit"`
+}
+
+// AuditConfig veri erişimlerinin hash zincirli denetim kaydı. Internal log'dan ayrıdır,
+// rotate edilmez ve silinmemelidir; bütünlüğü logctl audit verify ile kontrol edilir.
+type AuditConfig struct {
+	File    string `mapstructure:"file"`     // Varsayılan: backup_dir/audit.ndjson
+	HMACKey string `mapstructure:"hmac_key"` // Zincir anahtarı (ENC(...) veya file: ile); verilmezse zincir yalnızca dış anchor'larla korunur
+}
+
+// RateLimitConfig istek sınırları ve kötüye kullanım korumaları. Sayaçlar instance başına
+// bellekte tutulur; sıfır değerler o sınırı kapatır. Yeniden başlatmadan değiştirilebilir.
+type RateLimitConfig struct {
+	PerKey  BucketConfig `mapstructure:"per_key"`  // Kimlik (API anahtarı, cihaz, JWT kullanıcısı) başına
+	PerIP   BucketConfig `mapstructure:"per_ip"`   // Kimlik doğrulamadan önce, istemci IP'si başına
+	PerHome BucketConfig `mapstructure:"per_home"` // /upload'da dosya adındaki home_id başına
+
+	// /all-logs, /home-logs ve arama için aynı anda süren indirme sayısı
+	MaxConcurrentDownloads       int `mapstructure:"max_concurrent_downloads"`         // Toplam
+	MaxConcurrentDownloadsPerKey int `mapstructure:"max_concurrent_downloads_per_key"` // Kimlik başına
+
+	AuthFailures AuthFailuresConfig `mapstructure:"auth_failures"`
+}
+
+// BucketConfig token bucket: saniyede rate istek, en fazla burst istek birikebilir.
+type BucketConfig struct {
+	Rate  float64 `mapstructure:"rate"`  // Saniyedeki istek (ör: 0.5 → iki saniyede bir)
+	Burst int     `mapstructure:"burst"` // Varsayılan: rate'in yukarı yuvarlanmışı (en az 1)
+}
+
+// AuthFailuresConfig tekrarlanan kimlik doğrulama hatalarından sonra IP'yi geçici olarak engeller.
+type AuthFailuresConfig struct {
+	MaxFailures int `mapstructure:"max_failures"` // window_sec içinde bu kadar hata → engel (0: kapalı)
+	WindowSec   int `mapstructure:"window_sec"`   // Varsayılan 300
+	BanSec      int `mapstructure:"ban_sec"`      // Varsayılan 900
+}
+
+// SecretsConfig ENC(...) değerlerini çözen anahtarın nereden okunacağı.
+// Anahtar sırasıyla LOGSERVER_SECRET_KEY, LOGSERVER_SECRET_KEY_FILE, key_file ve
+// systemd credential'ından ($CREDENTIALS_DIRECTORY/logserver-secret-key) aranır.
+type SecretsConfig struct {
+	KeyFile string `mapstructure:"key_file"` // "v1:<anahtar>" satırları; base64/hex 32 byte veya parola. Ortam değişkeni: LOGSERVER_SECRET_KEY_FILE
 }
 
 type DBConfig struct {
@@ -30,17 +73,108 @@ type DBConfig struct {
 	CollectionName string `mapstructure:"collection_name"`
 }
 
+// AuthConfig API anahtarlarıyla kimlik doğrulama. Scope'lu anahtarlar keys_file'da tutulur
+// (/admin/api-keys veya logctl apikey ile yönetilir); api_value verilirse tüm yetkilere sahip
+// tek bir anahtar olarak kabul edilir.
 type AuthConfig struct {
-	ApiKey   string `mapstructure:"api_key"`   // Header adı (ör: "inohom-api-key")
-	ApiValue string `mapstructure:"api_value"` // API secret değeri
+	ApiKey   string        `mapstructure:"api_key"`   // Header adı (ör: "inohom-api-key")
+	ApiValue string        `mapstructure:"api_value"` // Tüm yetkilere sahip anahtar (opsiyonel)
+	KeysFile string        `mapstructure:"keys_file"` // Varsayılan: backup_dir/api_keys.json
+	Signing  SigningConfig `mapstructure:"signing"`
+	JWT      JWTConfig     `mapstructure:"jwt"`
+	RBAC     RBACConfig    `mapstructure:"rbac"`
+}
+
+// RBACConfig log indirme endpoint'leri için rol tabanlı yetkilendirme. Açıkken scope'a ek
+// olarak kimliğin rollerinden birinin endpoint'e ve istenen eve izin vermesi gerekir;
+// /all-logs yalnızca izin verilen evleri döner. Roller JWT'deki rol claim'inden ve
+// bindings'ten gelir. admin scope'una sahip kimlikler bu kontrole tabi değildir.
+type RBACConfig struct {
+	Enabled      bool                  `mapstructure:"enabled"`
+	HomeSets     map[string][]string   `mapstructure:"home_sets"`     // ad → home_id'ler; "ist-*" gibi desen olabilir (ör: region_x: [ist-*])
+	Roles        map[string]RoleConfig `mapstructure:"roles"`         // rol adı → izinler (büyük/küçük harf duyarsız)
+	Bindings     []RoleBinding         `mapstructure:"bindings"`      // API anahtarı, sertifika gibi kimliklerin rolleri
+	DefaultRoles []string              `mapstructure:"default_roles"` // Hiç rolü olmayan kimliklere verilen roller
+}
+
+// RoleConfig bir rolün izinleri. İzinlerin birleşimi geçerlidir.
+type RoleConfig struct {
+	Permissions []PermissionConfig `mapstructure:"permissions"`
+}
+
+// PermissionConfig endpoint'lere verilen erişimin hangi evleri kapsadığı.
+type PermissionConfig struct {
+	Endpoints []string `mapstructure:"endpoints"` // all_logs, home_logs, search; "*" hepsi
+	HomeSets  []string `mapstructure:"home_sets"` // home_sets adları; "*" tüm evler
+}
+
+// RoleBinding bir kimliğe rol verir.
+type RoleBinding struct {
+	Principal string   `mapstructure:"principal"` // Kimlik id'si: ak_..., jwt:<sub>, cert:<cn>, legacy; "cert:support-*" gibi desen olabilir
+	Roles     []string `mapstructure:"roles"`
+}
+
+// JWTConfig kimlik sağlayıcısından (OIDC) alınan bearer token'larla giriş. Token'lar
+// JWKS'teki anahtarlarla doğrulanır; roller role_scopes ile scope'lara, home_ids_claim
+// ise erişilebilecek evlere çevrilir. Header anahtarlarıyla birlikte kullanılabilir.
+type JWTConfig struct {
+	Enabled        bool                `mapstructure:"enabled"`
+	JWKSFile       string              `mapstructure:"jwks_file"`        // Yerel JWKS (test ve offline kurulumlar için)
+	JWKSUrl        string              `mapstructure:"jwks_url"`         // ör: https://idp.example.com/.well-known/jwks.json
+	JWKSRefreshMin int                 `mapstructure:"jwks_refresh_min"` // Varsayılan 60
+	Issuer         string              `mapstructure:"issuer"`           // Beklenen iss (enabled ise zorunlu)
+	Audience       string              `mapstructure:"audience"`         // Beklenen aud (enabled ise zorunlu)
+	LeewaySec      int                 `mapstructure:"leeway_sec"`       // exp/nbf için tolerans (varsayılan 60)
+	RolesClaim     string              `mapstructure:"roles_claim"`      // Noktalı yol olabilir (varsayılan: roles, ör: realm_access.roles)
+	HomeIdsClaim   string              `mapstructure:"home_ids_claim"`   // Varsayılan: home_ids; "*" tüm evler
+	NameClaim      string              `mapstructure:"name_claim"`       // Varsayılan: email, yoksa sub
+	RoleScopes     map[string][]string `mapstructure:"role_scopes"`      // rol → scope'lar (ör: support: [read:home])
+}
+
+// SigningConfig HMAC ile imzalı istekler. İstemci method, yol, zaman damgası, nonce ve
+// body özetini anahtarıyla imzalar; yakalanan istekler tekrar oynatılamaz.
+//
+// İmza yalnızca header'la gönderilen anahtarlar (API anahtarları, cihaz token'ları ve
+// auth.api_value) için geçerlidir. required modunda düz header anahtarı reddedilir; JWT bearer
+// token'ları (auth.jwt) ve doğrulanmış istemci sertifikaları (server.tls.client_certs) imzasız
+// kabul edilmeye devam eder. Bu yöntemlerin de kapatılması isteniyorsa ayrıca devre dışı
+// bırakılmalıdır.
+type SigningConfig struct {
+	Mode           string `mapstructure:"mode"`             // off (varsayılan), optional, required (yalnızca header anahtarları)
+	MaxSkewSec     int    `mapstructure:"max_skew_sec"`     // İzin verilen saat farkı (varsayılan 300)
+	NonceCacheSize int    `mapstructure:"nonce_cache_size"` // Hatırlanan nonce sayısı (varsayılan 100000); doluysa imzalı istekler 503 alır
 }
 
 type ServerConfig struct {
-	Port string `mapstructure:"port"`
+	Port string    `mapstructure:"port"`
+	TLS  TLSConfig `mapstructure:"tls"`
+}
+
+// TLSConfig sunucunun HTTPS ve istemci sertifikası (mTLS) ayarları. Sertifika, anahtar ve
+// CA dosyaları değiştiğinde yeniden başlatmadan yüklenir; yalnızca enabled değişikliği
+// yeniden başlatma gerektirir.
+type TLSConfig struct {
+	Enabled      bool             `mapstructure:"enabled"`
+	CertFile     string           `mapstructure:"cert_file"`      // PEM; ara sertifikalar dahil
+	KeyFile      string           `mapstructure:"key_file"`       // PEM
+	MinVersion   string           `mapstructure:"min_version"`    // 1.2 (varsayılan) veya 1.3
+	ClientAuth   string           `mapstructure:"client_auth"`    // off (varsayılan), optional, required
+	ClientCAFile string           `mapstructure:"client_ca_file"` // İstemci sertifikalarını imzalayan CA'lar (PEM)
+	ClientCerts  []ClientCertRule `mapstructure:"client_certs"`
+}
+
+// ClientCertRule doğrulanmış istemci sertifikasının subject'ini kimliğe çevirir. İlk eşleşen
+// kural kullanılır; hiçbir kurala uymayan sertifika kimlik sağlamaz.
+type ClientCertRule struct {
+	CommonName string   `mapstructure:"common_name"` // "*" tüm CN'ler
+	OrgUnit    string   `mapstructure:"org_unit"`    // Boşsa OU kontrol edilmez
+	HomeId     string   `mapstructure:"home_id"`     // "$cn": CN home_id olarak kullanılır (cihaz sertifikaları)
+	Scopes     []string `mapstructure:"scopes"`      // Boşsa ve home_id verilmişse upload
 }
 
 type InternalLogConfig struct {
 	LogFile string `mapstructure:"log_file"`
+	Level   string `mapstructure:"level"` // debug, info (varsayılan), warn, error; yeniden başlatmadan değiştirilebilir
 }
 
 type KettasLogConfig struct {
@@ -50,6 +184,54 @@ type KettasLogConfig struct {
 	MaxFileSizeMB int64        `mapstructure:"max_file_size_mb"`
 	MaxFolderSizeMB int64        `mapstructure:"max_folder_size_mb"`
 	Backup      BackupConfig `mapstructure:"backup"`
+	Archive     ArchiveConfig `mapstructure:"archive"`
+	Export      ExportConfig  `mapstructure:"export"`
+	Keys        KeysConfig    `mapstructure:"keys"`
+	Erasure     ErasureConfig `mapstructure:"erasure"`
+}
+
+// ErasureConfig bir evin tüm verilerinin silinmesi (GDPR) ve imzalı silme sertifikaları için ayarlar.
+type ErasureConfig struct {
+	CertificateDir string `mapstructure:"certificate_dir"`  // Varsayılan: ./erasures
+	SigningKeyFile string `mapstructure:"signing_key_file"` // Ed25519 PKCS#8 PEM; yoksa oluşturulur. Varsayılan: certificate_dir/signing_key.pem
+}
+
+// KeysConfig envelope encryption: her evin arşivleri kendi veri anahtarıyla şifrelenir,
+// veri anahtarları master key ile sarılıp (wrap) keyring dosyasında saklanır.
+// Kapalıysa tüm arşivler zip_password ile şifrelenir.
+type KeysConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	MasterKeyFile string `mapstructure:"master_key_file"` // Base64 32 byte; boşsa master_key_env okunur
+	MasterKeyEnv  string `mapstructure:"master_key_env"`  // Varsayılan: LOGSERVER_MASTER_KEY
+	KeyringFile   string `mapstructure:"keyring_file"`    // Varsayılan: ./keyring/keyring.json; backup_dir ile aynı ağaçta olamaz
+	// Keyring dosyasının yedeklerinin saklandığı gün sayısı. Silinen veri anahtarları yedeklerden
+	// ancak bu süre dolunca kalkar; silme sertifikasına bu tarih yazılır (0: bilinmiyor).
+	KeyringBackupRetentionDays int `mapstructure:"keyring_backup_retention_days"`
+}
+
+// ArchiveConfig arşivlerin gün bazlı bölünmesi için ayarlar.
+// Her event kendi zaman damgasına göre, evin saat dilimindeki gününe arşivlenir.
+type ArchiveConfig struct {
+	TimestampFields []string          `mapstructure:"timestamp_fields"` // Event zamanının okunacağı alanlar, sırayla denenir (ör: timestamp, meta.ts)
+	DefaultTimeZone string            `mapstructure:"default_timezone"` // Boşsa sunucu saati
+	HomeTimeZones   map[string]string `mapstructure:"home_timezones"`   // home_id → saat dilimi (ör: Europe/Istanbul)
+	Format          string            `mapstructure:"format"`           // Yeni arşivlerin formatı: zip (varsayılan) veya zstd (.lsa, chunk'lı ve zaman indeksli)
+	ChunkEvents     int               `mapstructure:"chunk_events"`     // zstd formatında bir chunk'taki en fazla event sayısı
+}
+
+// ExportConfig arşivlenmiş event'lerin analiz için Parquet'e aktarılması.
+// Schema boşsa kolonlar event'lerin üst seviye alanlarından çıkarılır.
+type ExportConfig struct {
+	Dir         string         `mapstructure:"dir"`         // Varsayılan: ./exports
+	Compression string         `mapstructure:"compression"` // zstd (varsayılan), snappy, gzip, none
+	Schema      []ExportColumn `mapstructure:"schema"`
+}
+
+// ExportColumn Parquet dosyasındaki tek bir kolon.
+type ExportColumn struct {
+	Name  string `mapstructure:"name"`
+	Field string `mapstructure:"field"` // Event'teki alan, "meta.ts" gibi noktalı yol olabilir (boşsa name)
+	Type  string `mapstructure:"type"`  // string, int, double, bool, timestamp, json
 }
 
 type BackupConfig struct {
@@ -59,6 +241,38 @@ type BackupConfig struct {
 	MaxBackupSizeMB  int64  `mapstructure:"max_backup_size_mb"`
 	RetentionDays    int    `mapstructure:"retention_days"`
 	DailyArchiveTargetTime string `mapstructure:"daily_archive_target_time"`
+	DeletionJournalFile    string `mapstructure:"deletion_journal_file"` // Boşsa backup_dir/deletion_journal.ndjson
+	Jobs                   JobsConfig `mapstructure:"jobs"`
+}
+
+// JobsConfig arşiv ve backup işlerinin zamanlamaları.
+// Boş bırakılan cron değerleri eski ayarlardan türetilir
+// (daily_archive_target_time, check_interval_min).
+type JobsConfig struct {
+	TimeZone          string         `mapstructure:"timezone"` // Tüm işler için varsayılan saat dilimi (ör: Europe/Istanbul)
+	HistoryFile       string         `mapstructure:"history_file"` // Boşsa backup_dir/job_history.ndjson
+	DailyArchive      ScheduleConfig `mapstructure:"daily_archive"`
+	MissedArchiveScan ScheduleConfig `mapstructure:"missed_archive_scan"`
+	Rotation          ScheduleConfig `mapstructure:"rotation"`
+	Cleanup           ScheduleConfig `mapstructure:"cleanup"`
+	Scrub             ScheduleConfig `mapstructure:"scrub"`
+	Export            ScheduleConfig `mapstructure:"export"` // Boşsa sadece manuel (POST /admin/exports)
+}
+
+type ScheduleConfig struct {
+	Cron      string `mapstructure:"cron"`       // "58 23 * * *", "@every 5h", "@daily" ...
+	TimeZone  string `mapstructure:"timezone"`   // Boşsa jobs.timezone, o da boşsa sunucu saati
+	JitterSec int    `mapstructure:"jitter_sec"` // Her çalışmaya 0..jitter_sec arası rastgele gecikme
+}
+
+// ClusterConfig birden fazla instance aynı logs/backup dizinlerini paylaştığında
+// işlerin ve home_id klasörlerinin kilitlenmesi için ayarlar.
+type ClusterConfig struct {
+	LockBackend        string `mapstructure:"lock_backend"`          // "file" (flock, varsayılan), "mongo" (lease) veya "none"
+	LockDir            string `mapstructure:"lock_dir"`              // file backend için, boşsa backup_dir/.locks
+	InstanceId         string `mapstructure:"instance_id"`           // Boşsa hostname-pid; kilitlerde yalnızca bilgi amaçlıdır
+	LeaseTTLSec        int    `mapstructure:"lease_ttl_sec"`         // mongo lease süresi (varsayılan 60)
+	HomeLockTimeoutSec int    `mapstructure:"home_lock_timeout_sec"` // Upload'ın home kilidini bekleme süresi (varsayılan 30)
 }
 
 type AiServiceConfig struct {
@@ -66,48 +280,105 @@ type AiServiceConfig struct {
 	Endpoint string `mapstructure:"endpoint"` // Endpoint yolu (ör: /analyze)
 }
 
-var AppConfig Config
+// current geçerli config snapshot'ı. Yeniden yüklemede yeni bir Config oluşturulup
+// atomik olarak değiştirilir; Get ile alınan snapshot'lar değiştirilmemelidir.
+var current atomic.Pointer[Config]
 
-func Load() {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
+func init() {
+	current.Store(&Config{})
+}
 
-	if err := viper.ReadInConfig(); err != nil {
-		fmt.Printf("Error reading config file: %v\n", err)
+// Load config'i LoadFile ile yükler, hata durumunda çıkar.
+func Load(path string) {
+	if err := LoadFile(path); err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 	}
+}
 
-	if err := viper.Unmarshal(&AppConfig); err != nil {
-		fmt.Printf("Error unmarshalling config: %v\n", err)
-		os.Exit(1)
+// LoadFile config'i verilen dosyadan okur, secret referanslarını çözer, varsayılanları uygular
+// ve doğrular. Load'dan farklı olarak hata durumunda çıkmaz.
+//
+// path boşsa LOGSERVER_CONFIG, o da boşsa SearchPaths altındaki config.yaml kullanılır.
+// Dosya bulunamazsa config yalnızca LOGSERVER_ önekli ortam değişkenlerinden okunur.
+// Öncelik sırası: SetOverride (--set) > ortam değişkeni > dosya > varsayılan.
+func LoadFile(path string) error {
+	viperMu.Lock()
+	defer viperMu.Unlock()
+
+	if path == "" {
+		path = os.Getenv(ConfigFileEnv)
 	}
+	explicitFile = path != ""
+	if explicitFile {
+		viper.SetConfigFile(path)
+	} else {
+		viper.SetConfigName("config")
+		viper.SetConfigType("yaml")
+		for _, dir := range SearchPaths {
+			viper.AddConfigPath(dir)
+		}
+	}
+	bindEnv()
 
-	// ENC(...) ile şifrelenmiş değerleri çöz
-	if err := decryptSecrets(); err != nil {
-		fmt.Printf("Error decrypting secrets: %v\n", err)
-		os.Exit(1)
+	if err := readConfig(); err != nil {
+		return err
+	}
+
+	cfg, err := decode()
+	if err != nil {
+		return err
+	}
+	current.Store(cfg)
+	return nil
+}
+
+// explicitFile config dosyası --config veya LOGSERVER_CONFIG ile verildiyse true'dur;
+// bu durumda dosyanın bulunamaması hatadır.
+var explicitFile bool
+
+// readConfig config dosyasını okur. Dosya açıkça verilmediyse ve arama dizinlerinde
+// yoksa hata dönmez. viperMu tutulurken çağrılmalıdır.
+func readConfig() error {
+	err := viper.ReadInConfig()
+	var notFound viper.ConfigFileNotFoundError
+	if errors.As(err, &notFound) && !explicitFile {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading config file: %v", err)
 	}
+	return nil
+}
+
+// ConfigFileUsed okunan config dosyasının yolunu döner; yalnızca ortam değişkenleri kullanıldıysa boştur.
+func ConfigFileUsed() string {
+	viperMu.Lock()
+	defer viperMu.Unlock()
+	return viper.ConfigFileUsed()
 }
 
-// decryptSecrets ENC(...) ile sarılı tüm config değerlerini çözer
-func decryptSecrets() error {
-	fields := []*string{
-		&AppConfig.DB.Username,
-		&AppConfig.DB.Password,
+// decode viper'daki değerlerden yeni bir config snapshot'ı oluşturur ve doğrular.
+func decode() (*Config, error) {
+	cfg := &Config{}
+	if err := viper.Unmarshal(cfg, decodeHook()); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling config: %v", err)
 	}
 
-	for _, field := range fields {
-		decrypted, err := crypto.DecryptIfEncrypted(*field, magicKey)
-		if err != nil {
-			return fmt.Errorf("decrypt hatası [%s]: %v", *field, err)
-		}
-		*field = decrypted
+	// ENC(...), ${ENV} ve file: referanslarını çöz
+	if err := resolveSecrets(cfg); err != nil {
+		return nil, fmt.Errorf("Error resolving config secrets: %v", err)
 	}
 
-	return nil
+	ApplyDefaults(cfg)
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
+// Get geçerli config snapshot'ını döner. Uzun süren işlemler tutarlı değerler için
+// snapshot'ı bir kez alıp kullanmalıdır.
 func Get() *Config {
-	return &AppConfig
+	return current.Load()
 }
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log-server/crypto"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix config anahtarlarının ortam değişkeni öneki. Noktalar alt çizgiye çevrilir:
+// kettas_log.backup.retention_days → LOGSERVER_KETTAS_LOG_BACKUP_RETENTION_DAYS. İstisnalar
+// envAliases'tadır.
+const EnvPrefix = "LOGSERVER"
+
+// ConfigFileEnv config dosyasının yolunu veren ortam değişkeni (--config flag'i önceliklidir).
+const ConfigFileEnv = EnvPrefix + "_CONFIG"
+
+// SearchPaths config.yaml'ın sırayla arandığı dizinler.
+var SearchPaths = []string{".", "./config", "/etc/log-server"}
+
+// allKeys Config'deki tüm yaprak anahtarlar (ör: db.password). Map ve slice alanlar tek anahtardır.
+var allKeys = configKeys(reflect.TypeOf(Config{}), "")
+
+func configKeys(t reflect.Type, prefix string) []string {
+	var out []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		key := joinPath(prefix, name)
+		if field.Type.Kind() == reflect.Struct {
+			out = append(out, configKeys(field.Type, key)...)
+			continue
+		}
+		out = append(out, key)
+	}
+	return out
+}
+
+// Keys config'de kullanılabilen tüm anahtarları sıralı döner.
+func Keys() []string {
+	out := append([]string(nil), allKeys...)
+	sort.Strings(out)
+	return out
+}
+
+// envAliases otomatik adı yerine başka bir ortam değişkenine bağlanan anahtarlar.
+// secrets.key_file'ın otomatik adı LOGSERVER_SECRETS_KEY_FILE, anahtar dosyası için zaten
+// okunan LOGSERVER_SECRET_KEY_FILE'dan (crypto.KeyFileEnv) yalnızca bir harfle ayrılırdı;
+// ikisi aynı değişkendir.
+var envAliases = map[string]string{
+	"secrets.key_file": crypto.KeyFileEnv,
+}
+
+// EnvName anahtarın ortam değişkeni adını döner.
+func EnvName(key string) string {
+	if name, ok := envAliases[key]; ok {
+		return name
+	}
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// bindEnv tüm anahtarları LOGSERVER_ önekli ortam değişkenlerine bağlar. Böylece config dosyasında
+// bulunmayan anahtarlar da Unmarshal'da görünür ve sunucu yalnızca ortam değişkenleriyle başlatılabilir.
+// viperMu tutulurken çağrılmalıdır.
+func bindEnv() {
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	for _, key := range allKeys {
+		// BindEnv yalnızca anahtar verilmezse hata döner
+		_ = viper.BindEnv(key, EnvName(key))
+	}
+}
+
+// SetOverride bir anahtarı dosya ve ortam değişkenlerinden öncelikli olarak ayarlar (--set key=value).
+// Değerler ortam değişkenleriyle aynı biçimde yorumlanır; ENC(...) ve ${VAR} referansları çözülür.
+func SetOverride(key, value string) error {
+	key = strings.ToLower(strings.TrimSpace(key))
+	known := false
+	for _, k := range allKeys {
+		if k == key {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("bilinmeyen config anahtarı: %s", key)
+	}
+
+	viperMu.Lock()
+	viper.Set(key, value)
+	viperMu.Unlock()
+	return nil
+}
+
+// ParseOverride "key=value" biçimindeki --set argümanını ayırır.
+func ParseOverride(s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return "", "", fmt.Errorf("key=value biçiminde olmalı: %q", s)
+	}
+	return key, value, nil
+}
+
+// decodeHook ortam değişkeni ve --set ile gelen string değerleri alanın tipine çevirir:
+//
+//	slice  "a,b,c" veya JSON dizi (ör: export.schema için [{"name":"ts","type":"timestamp"}])
+//	map    "k=v,k2=v2" veya JSON nesne (ör: archive.home_timezones)
+func decodeHook() viper.DecoderConfigOption {
+	return viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		stringToCollectionHook,
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	))
+}
+
+func stringToCollectionHook(f, t reflect.Type, data any) (any, error) {
+	if f.Kind() != reflect.String || (t.Kind() != reflect.Map && t.Kind() != reflect.Slice) {
+		return data, nil
+	}
+
+	s := strings.TrimSpace(data.(string))
+	if strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") {
+		var v any
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, fmt.Errorf("geçersiz JSON: %w", err)
+		}
+		return v, nil
+	}
+	if t.Kind() != reflect.Map {
+		return data, nil
+	}
+
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("k=v,k2=v2 biçiminde olmalı: %q", s)
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m, nil
+}
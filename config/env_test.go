@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvBinding(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "secret.key")
+	if err := os.WriteFile(keyFile, []byte("v1:test\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		get  func(*Config) string
+		want string
+	}{
+		{"otomatik ad", map[string]string{"LOGSERVER_KETTAS_LOG_UPLOAD_DIR": "/srv/uploads"},
+			func(c *Config) string { return c.KettasLog.UploadDir }, "/srv/uploads"},
+		{"secrets.key_file LOGSERVER_SECRET_KEY_FILE'dan", map[string]string{"LOGSERVER_SECRET_KEY_FILE": keyFile},
+			func(c *Config) string { return c.Secrets.KeyFile }, keyFile},
+		{"LOGSERVER_SECRETS_KEY_FILE bağlı değil", map[string]string{"LOGSERVER_SECRETS_KEY_FILE": keyFile},
+			func(c *Config) string { return c.Secrets.KeyFile }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.yaml")
+			yaml := fmt.Sprintf("internal_log: {log_file: %q}\nkettas_log: {zip_password: test, backup: {backup_dir: %q}}\n",
+				filepath.Join(dir, "app.log"), filepath.Join(dir, "backups"))
+			if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := LoadFile(path); err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.get(Get()); got != tt.want {
+				t.Errorf("değer = %q, %q bekleniyordu", got, tt.want)
+			}
+		})
+	}
+}
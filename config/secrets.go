@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"log-server/crypto"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dosya referansları: file:/yol veya FILE(/yol). "file://" ile başlayan değerler URL
+// kabul edilir ve olduğu gibi bırakılır.
+const (
+	fileRefPrefix = "file:"
+	fileURLPrefix = "file://"
+	filePrefix    = "FILE("
+	fileSuffix    = ")"
+)
+
+// envPattern değer içindeki ${VAR} referanslarını bulur; $${ kaçış olarak ${ üretir.
+var envPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// secretResolver config'deki string değerleri çözer. Anahtar yalnızca şifreli bir değer
+// görüldüğünde yüklenir.
+type secretResolver struct {
+	keyFile string
+	keys    *crypto.KeySet
+}
+
+// resolveSecrets config'deki tüm string alanlarda (iç içe struct, slice ve map'ler dahil)
+// referansları çözer:
+//
+//	${VAR}       ortam değişkeninin değeriyle değiştirilir (değer içinde birden fazla olabilir; $${ kaçıştır)
+//	file:/yol    dosyanın içeriği (sondaki satır sonu atılır); değerin tamamı olmalıdır.
+//	             file:// URL'leri referans sayılmaz; FILE(/yol) aynı anlamdadır
+//	ENC(...)     secret anahtarıyla çözülür (${VAR} veya file: sonucu ENC(...) ise o da çözülür)
+//
+// Hatalar secret'ın kendisini değil, config anahtarını (ör: db.password) içerir.
+func resolveSecrets(cfg *Config) error {
+	// Anahtar dosyasının yolu da ${VAR} veya file: ile verilebilir
+	keyFile, err := expandRefs(cfg.Secrets.KeyFile)
+	if err != nil {
+		return fmt.Errorf("secrets.key_file: %w", err)
+	}
+	r := &secretResolver{keyFile: keyFile}
+	return r.walk(reflect.ValueOf(cfg).Elem(), "")
+}
+
+func (r *secretResolver) walk(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.String:
+		resolved, err := r.resolve(v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetString(resolved)
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			if err := r.walk(v.Field(i), joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := r.walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		// Map değerleri adreslenemez; kopyası çözülüp geri yazılır (map[string]RoleConfig gibi)
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := r.walk(elem, joinPath(path, fmt.Sprint(key.Interface()))); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return r.walk(v.Elem(), path)
+		}
+	}
+	return nil
+}
+
+func (r *secretResolver) resolve(value string) (string, error) {
+	value, err := expandRefs(value)
+	if err != nil {
+		return "", err
+	}
+	if !crypto.IsEncrypted(value) {
+		return value, nil
+	}
+
+	if r.keys == nil {
+		if r.keys, _, err = crypto.LoadKeySet(r.keyFile); err != nil {
+			return "", err
+		}
+	}
+	return r.keys.DecryptIfEncrypted(value)
+}
+
+// fileRef değer bir dosya referansıysa dosyanın yolunu döner.
+func fileRef(value string) (string, bool) {
+	switch {
+	case strings.HasPrefix(value, filePrefix) && strings.HasSuffix(value, fileSuffix):
+		return strings.TrimSpace(value[len(filePrefix) : len(value)-len(fileSuffix)]), true
+	case strings.HasPrefix(value, fileRefPrefix) && !strings.HasPrefix(value, fileURLPrefix):
+		return strings.TrimSpace(value[len(fileRefPrefix):]), true
+	}
+	return "", false
+}
+
+// expandRefs file: ve ${VAR} referanslarını çözer.
+func expandRefs(value string) (string, error) {
+	if path, ok := fileRef(value); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("secret dosyası okunamadı (%s): %w", path, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+	var missing []string
+	out := envPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		name := match[2 : len(match)-1]
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return env
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("ortam değişkeni tanımlı değil: %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
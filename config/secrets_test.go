@@ -0,0 +1,95 @@
+package config
+
+import (
+	"log-server/crypto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandRefs(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("LOGSERVER_TEST_USER", "admin")
+	t.Setenv("LOGSERVER_TEST_HOST", "db.local")
+	os.Unsetenv("LOGSERVER_TEST_MISSING")
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"düz değer", "plain", "plain", ""},
+		{"ortam değişkeni", "${LOGSERVER_TEST_USER}", "admin", ""},
+		{"birden fazla değişken", "mongodb://${LOGSERVER_TEST_USER}@${LOGSERVER_TEST_HOST}/x", "mongodb://admin@db.local/x", ""},
+		{"$${ kaçışı", "$${LOGSERVER_TEST_USER} ${LOGSERVER_TEST_USER}", "${LOGSERVER_TEST_USER} admin", ""},
+		{"tanımsız değişken", "${LOGSERVER_TEST_MISSING}", "", "LOGSERVER_TEST_MISSING"},
+		{"file:", "file:" + secretFile, "s3cret", ""},
+		{"FILE()", "FILE(" + secretFile + ")", "s3cret", ""},
+		{"file:// URL", "file:///var/lib/x", "file:///var/lib/x", ""},
+		{"olmayan dosya", "file:" + filepath.Join(dir, "yok"), "", "secret dosyası okunamadı"},
+		{"dosya yolu değerin ortasında", "x file:" + secretFile, "x file:" + secretFile, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandRefs(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandRefs hata = %v, %q içermeli", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("expandRefs = %q, %v; %q bekleniyordu", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	ks, err := crypto.ParseKeySet("testkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(crypto.KeyEnv, "testkey")
+	t.Setenv("LOGSERVER_TEST_TZ", "Europe/Istanbul")
+	enc, err := ks.Encrypt("zip-parola")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{}
+	cfg.KettasLog.ZipPassword = enc
+	cfg.KettasLog.Archive.HomeTimeZones = map[string]string{"home-1": "${LOGSERVER_TEST_TZ}"}
+	cfg.Auth.RBAC.Roles = map[string]RoleConfig{"support": {Permissions: []PermissionConfig{{Endpoints: []string{"$${x}"}}}}}
+	if err := resolveSecrets(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key       string
+		got, want string
+	}{
+		{"kettas_log.zip_password", cfg.KettasLog.ZipPassword, "zip-parola"},
+		{"kettas_log.archive.home_timezones.home-1", cfg.KettasLog.Archive.HomeTimeZones["home-1"], "Europe/Istanbul"},
+		{"auth.rbac.roles.support", cfg.Auth.RBAC.Roles["support"].Permissions[0].Endpoints[0], "${x}"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, %q bekleniyordu", tt.key, tt.got, tt.want)
+		}
+	}
+
+	// Hata secret'ı değil config anahtarını içermeli
+	cfg = &Config{}
+	cfg.KettasLog.Archive.HomeTimeZones = map[string]string{"home-1": "${LOGSERVER_TEST_MISSING}"}
+	err = resolveSecrets(cfg)
+	if err == nil || !strings.HasPrefix(err.Error(), "kettas_log.archive.home_timezones.home-1:") {
+		t.Fatalf("resolveSecrets hata = %v; config anahtarıyla başlamalı", err)
+	}
+}
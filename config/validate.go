@@ -0,0 +1,377 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Boş bırakılan alanlar için varsayılanlar
+const (
+	DefaultPort                   = "3000"
+	DefaultApiKeyHeader           = "inohom-api-key"
+	DefaultUploadDir              = "./uploads"
+	DefaultLogsDir                = "./logs"
+	DefaultInternalLogFile        = "./internal_logs/log-server.log"
+	DefaultLogLevel               = "info"
+	DefaultMaxFileSizeMB          = 100
+	DefaultMaxFolderSizeMB        = 1024
+	DefaultBackupDir              = "./backups"
+	DefaultKeyringFile            = "./keyring/keyring.json"
+	DefaultMaxBackupSizeMB        = 10240
+	DefaultRetentionDays          = 30
+	DefaultCheckIntervalMin       = 10
+	DefaultDailyArchiveTargetTime = "00:05"
+)
+
+// Problem config'deki tek bir hatalı alan. Key, config.yaml'daki noktalı yoldur
+// (ör: kettas_log.backup.check_interval_min).
+type Problem struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// ValidationError config'deki tüm sorunları birlikte raporlar.
+type ValidationError struct {
+	Problems []Problem `json:"problems"`
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "config geçersiz (%d sorun):", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  - %s: %s", p.Key, p.Message)
+	}
+	return b.String()
+}
+
+// Validator config paketinin bilmediği alanları (ör: cron ifadeleri) doğrulamak için
+// başka paketlerin eklediği kontrol.
+type Validator func(cfg *Config) []Problem
+
+var (
+	validators   []Validator
+	validatorsMu sync.Mutex
+)
+
+// RegisterValidator Validate'in çalıştıracağı ek bir kontrol ekler.
+// Genellikle paketin init fonksiyonundan çağrılır.
+func RegisterValidator(v Validator) {
+	validatorsMu.Lock()
+	validators = append(validators, v)
+	validatorsMu.Unlock()
+}
+
+// ApplyDefaults boş veya sıfır bırakılmış alanları varsayılanlarla doldurur.
+// Negatif değerler olduğu gibi bırakılır, Validate bunları hata olarak raporlar.
+func ApplyDefaults(cfg *Config) {
+	setDefault(&cfg.Server.Port, DefaultPort)
+	setDefault(&cfg.Auth.ApiKey, DefaultApiKeyHeader)
+	setDefault(&cfg.InternalLog.LogFile, DefaultInternalLogFile)
+	setDefault(&cfg.InternalLog.Level, DefaultLogLevel)
+
+	kl := &cfg.KettasLog
+	setDefault(&kl.UploadDir, DefaultUploadDir)
+	setDefault(&kl.LogsDir, DefaultLogsDir)
+	setDefaultInt64(&kl.MaxFileSizeMB, DefaultMaxFileSizeMB)
+	setDefaultInt64(&kl.MaxFolderSizeMB, DefaultMaxFolderSizeMB)
+
+	b := &kl.Backup
+	setDefault(&b.BackupDir, DefaultBackupDir)
+	setDefaultInt64(&b.MaxBackupSizeMB, DefaultMaxBackupSizeMB)
+	if b.RetentionDays == 0 {
+		b.RetentionDays = DefaultRetentionDays
+	}
+	if b.CheckIntervalMin == 0 {
+		b.CheckIntervalMin = DefaultCheckIntervalMin
+	}
+	setDefault(&b.DailyArchiveTargetTime, DefaultDailyArchiveTargetTime)
+}
+
+func setDefault(s *string, def string) {
+	if strings.TrimSpace(*s) == "" {
+		*s = def
+	}
+}
+
+func setDefaultInt64(n *int64, def int64) {
+	if *n == 0 {
+		*n = def
+	}
+}
+
+// Validate config'in tüm alanlarını kontrol eder ve bulunan bütün sorunları tek bir
+// *ValidationError içinde döner. ApplyDefaults'tan sonra çağrılmalıdır.
+func Validate(cfg *Config) error {
+	v := &checker{}
+
+	v.port("server.port", cfg.Server.Port)
+	if t := cfg.Server.TLS; t.Enabled {
+		if v.required("server.tls.cert_file", t.CertFile) {
+			v.file("server.tls.cert_file", t.CertFile)
+		}
+		if v.required("server.tls.key_file", t.KeyFile) {
+			v.file("server.tls.key_file", t.KeyFile)
+		}
+		v.oneOf("server.tls.min_version", t.MinVersion, "", "1.2", "1.3")
+		clientAuth := strings.ToLower(t.ClientAuth)
+		if v.oneOf("server.tls.client_auth", clientAuth, "", "off", "optional", "required") && clientAuth != "" && clientAuth != "off" {
+			if v.required("server.tls.client_ca_file", t.ClientCAFile) {
+				v.file("server.tls.client_ca_file", t.ClientCAFile)
+			}
+		}
+		for i, rule := range t.ClientCerts {
+			v.required(fmt.Sprintf("server.tls.client_certs[%d].common_name", i), rule.CommonName)
+		}
+	}
+	v.required("auth.api_key", cfg.Auth.ApiKey)
+	// required yalnızca header anahtarlarına uygulanır; JWT ve istemci sertifikası imzasız kabul edilir
+	v.oneOf("auth.signing.mode", strings.ToLower(cfg.Auth.Signing.Mode), "", "off", "optional", "required")
+	v.nonNegative("auth.signing.max_skew_sec", int64(cfg.Auth.Signing.MaxSkewSec))
+	v.nonNegative("auth.signing.nonce_cache_size", int64(cfg.Auth.Signing.NonceCacheSize))
+	if j := cfg.Auth.JWT; j.Enabled {
+		if j.JWKSFile == "" && j.JWKSUrl == "" {
+			v.add("auth.jwt", "jwks_file veya jwks_url gerekli")
+		}
+		if j.JWKSFile != "" {
+			v.file("auth.jwt.jwks_file", j.JWKSFile)
+		}
+		if j.JWKSUrl != "" {
+			if u, err := url.Parse(j.JWKSUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.add("auth.jwt.jwks_url", "http(s) URL'i olmalı: %q", j.JWKSUrl)
+			}
+		}
+		// Aynı IdP'nin başka uygulamalar için verdiği token'lar kabul edilmesin
+		v.required("auth.jwt.issuer", j.Issuer)
+		v.required("auth.jwt.audience", j.Audience)
+		v.nonNegative("auth.jwt.jwks_refresh_min", int64(j.JWKSRefreshMin))
+		v.nonNegative("auth.jwt.leeway_sec", int64(j.LeewaySec))
+	}
+
+	rl := cfg.RateLimit
+	buckets := []struct {
+		key string
+		b   BucketConfig
+	}{{"rate_limit.per_key", rl.PerKey}, {"rate_limit.per_ip", rl.PerIP}, {"rate_limit.per_home", rl.PerHome}}
+	for _, bc := range buckets {
+		key, b := bc.key, bc.b
+		if b.Rate < 0 {
+			v.add(key+".rate", "negatif olamaz (değer: %g)", b.Rate)
+		}
+		v.nonNegative(key+".burst", int64(b.Burst))
+	}
+	v.nonNegative("rate_limit.max_concurrent_downloads", int64(rl.MaxConcurrentDownloads))
+	v.nonNegative("rate_limit.max_concurrent_downloads_per_key", int64(rl.MaxConcurrentDownloadsPerKey))
+	v.nonNegative("rate_limit.auth_failures.max_failures", int64(rl.AuthFailures.MaxFailures))
+	v.nonNegative("rate_limit.auth_failures.window_sec", int64(rl.AuthFailures.WindowSec))
+	v.nonNegative("rate_limit.auth_failures.ban_sec", int64(rl.AuthFailures.BanSec))
+
+	if k := cfg.Audit.HMACKey; k != "" && len(k) < 16 {
+		v.add("audit.hmac_key", "en az 16 karakter olmalı")
+	}
+
+	if cfg.DB.Enabled {
+		v.required("db.host", cfg.DB.Host)
+		v.required("db.db_name", cfg.DB.DBName)
+		v.required("db.collection_name", cfg.DB.CollectionName)
+	}
+
+	v.required("internal_log.log_file", cfg.InternalLog.LogFile)
+	v.oneOf("internal_log.level", strings.ToLower(cfg.InternalLog.Level), "debug", "info", "warn", "error")
+
+	kl := cfg.KettasLog
+	v.required("kettas_log.upload_dir", kl.UploadDir)
+	v.required("kettas_log.logs_dir", kl.LogsDir)
+	if !kl.Keys.Enabled {
+		// Envelope encryption kapalıyken tüm arşivler bu parolayla şifrelenir
+		v.required("kettas_log.zip_password", kl.ZipPassword)
+	}
+	v.positive("kettas_log.max_file_size_mb", kl.MaxFileSizeMB)
+	v.positive("kettas_log.max_folder_size_mb", kl.MaxFolderSizeMB)
+
+	b := kl.Backup
+	v.required("kettas_log.backup.backup_dir", b.BackupDir)
+	v.positive("kettas_log.backup.check_interval_min", int64(b.CheckIntervalMin))
+	v.positive("kettas_log.backup.max_backup_size_mb", b.MaxBackupSizeMB)
+	v.positive("kettas_log.backup.retention_days", int64(b.RetentionDays))
+	v.clock("kettas_log.backup.daily_archive_target_time", b.DailyArchiveTargetTime)
+
+	v.timezone("kettas_log.backup.jobs.timezone", b.Jobs.TimeZone)
+	for name, sc := range JobSchedules(&b.Jobs) {
+		key := "kettas_log.backup.jobs." + name
+		v.timezone(key+".timezone", sc.TimeZone)
+		v.nonNegative(key+".jitter_sec", int64(sc.JitterSec))
+	}
+
+	a := kl.Archive
+	v.oneOf("kettas_log.archive.format", strings.ToLower(a.Format), "", "zip", "zstd")
+	v.nonNegative("kettas_log.archive.chunk_events", int64(a.ChunkEvents))
+	v.timezone("kettas_log.archive.default_timezone", a.DefaultTimeZone)
+	for id, tz := range a.HomeTimeZones {
+		v.timezone("kettas_log.archive.home_timezones."+id, tz)
+	}
+	for i, field := range a.TimestampFields {
+		v.required(fmt.Sprintf("kettas_log.archive.timestamp_fields[%d]", i), field)
+	}
+
+	e := kl.Export
+	v.oneOf("kettas_log.export.compression", strings.ToLower(e.Compression), "", "zstd", "snappy", "gzip", "none")
+	seen := make(map[string]bool)
+	for i, col := range e.Schema {
+		key := fmt.Sprintf("kettas_log.export.schema[%d]", i)
+		if v.required(key+".name", col.Name) {
+			if seen[col.Name] {
+				v.add(key+".name", "%q birden fazla kolonda kullanılmış", col.Name)
+			}
+			seen[col.Name] = true
+		}
+		v.oneOf(key+".type", strings.ToLower(col.Type), "string", "int", "double", "bool", "timestamp", "json")
+	}
+
+	if kl.Keys.Enabled && kl.Keys.MasterKeyFile != "" {
+		v.file("kettas_log.keys.master_key_file", kl.Keys.MasterKeyFile)
+	}
+	v.nonNegative("kettas_log.keys.keyring_backup_retention_days", int64(kl.Keys.KeyringBackupRetentionDays))
+
+	c := cfg.Cluster
+	if v.oneOf("cluster.lock_backend", c.LockBackend, "", "file", "mongo", "none") && c.LockBackend == "mongo" && !cfg.DB.Enabled {
+		v.add("cluster.lock_backend", "mongo backend'i için db.enabled gerekli")
+	}
+	v.nonNegative("cluster.lease_ttl_sec", int64(c.LeaseTTLSec))
+	v.nonNegative("cluster.home_lock_timeout_sec", int64(c.HomeLockTimeoutSec))
+
+	if cfg.AiService.Url != "" {
+		if u, err := url.Parse(cfg.AiService.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add("ai_service.url", "http(s) URL'i olmalı: %q", cfg.AiService.Url)
+		}
+	}
+
+	if cfg.Secrets.KeyFile != "" {
+		v.file("secrets.key_file", cfg.Secrets.KeyFile)
+	}
+
+	validatorsMu.Lock()
+	extra := append([]Validator(nil), validators...)
+	validatorsMu.Unlock()
+	for _, fn := range extra {
+		v.problems = append(v.problems, fn(cfg)...)
+	}
+
+	if len(v.problems) > 0 {
+		// Map'lerden gelen sorunlar her çalıştırmada aynı sırada raporlansın
+		sort.SliceStable(v.problems, func(i, j int) bool { return v.problems[i].Key < v.problems[j].Key })
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// JobSchedules jobs altındaki zamanlamaları config anahtarlarıyla döner.
+func JobSchedules(j *JobsConfig) map[string]ScheduleConfig {
+	return map[string]ScheduleConfig{
+		"daily_archive":       j.DailyArchive,
+		"missed_archive_scan": j.MissedArchiveScan,
+		"rotation":            j.Rotation,
+		"cleanup":             j.Cleanup,
+		"scrub":               j.Scrub,
+		"export":              j.Export,
+	}
+}
+
+// ParseClock "HH:MM" biçimindeki saati ayrıştırır.
+func ParseClock(s string) (hour, minute int, err error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("HH:MM biçiminde olmalı: %q", s)
+	}
+	hour, err = strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("saat 00-23 arasında olmalı: %q", s)
+	}
+	minute, err = strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("dakika 00-59 arasında olmalı: %q", s)
+	}
+	return hour, minute, nil
+}
+
+type checker struct {
+	problems []Problem
+}
+
+func (v *checker) add(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *checker) required(key, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(key, "boş olamaz")
+		return false
+	}
+	return true
+}
+
+func (v *checker) positive(key string, n int64) {
+	if n <= 0 {
+		v.add(key, "sıfırdan büyük olmalı (değer: %d)", n)
+	}
+}
+
+func (v *checker) nonNegative(key string, n int64) {
+	if n < 0 {
+		v.add(key, "negatif olamaz (değer: %d)", n)
+	}
+}
+
+func (v *checker) oneOf(key, value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	var names []string
+	for _, a := range allowed {
+		if a != "" {
+			names = append(names, a)
+		}
+	}
+	v.add(key, "%q geçersiz, şunlardan biri olmalı: %s", value, strings.Join(names, ", "))
+	return false
+}
+
+func (v *checker) port(key, value string) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > 65535 {
+		v.add(key, "1-65535 arasında bir port olmalı: %q", value)
+	}
+}
+
+func (v *checker) clock(key, value string) {
+	if _, _, err := ParseClock(value); err != nil {
+		v.add(key, "%v", err)
+	}
+}
+
+func (v *checker) timezone(key, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := time.LoadLocation(value); err != nil {
+		v.add(key, "bilinmeyen saat dilimi %q", value)
+	}
+}
+
+func (v *checker) file(key, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		v.add(key, "dosya okunamıyor: %v", err)
+		return
+	}
+	if info.IsDir() {
+		v.add(key, "%s bir dizin, dosya olmalı", path)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateJWT(t *testing.T) {
+	dir := t.TempDir()
+	jwks := filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(jwks, []byte(`{"keys":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		jwt     string
+		wantErr string
+	}{
+		{"kapalı", "{enabled: false}", ""},
+		{"tam", fmt.Sprintf("{enabled: true, jwks_file: %q, issuer: https://idp.example.com, audience: log-server}", jwks), ""},
+		{"issuer yok", fmt.Sprintf("{enabled: true, jwks_file: %q, audience: log-server}", jwks), "auth.jwt.issuer"},
+		{"audience yok", fmt.Sprintf("{enabled: true, jwks_file: %q, issuer: https://idp.example.com}", jwks), "auth.jwt.audience"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			yaml := fmt.Sprintf(`internal_log: {log_file: %q}
+kettas_log: {zip_password: test, backup: {backup_dir: %q}}
+auth: {api_key: test, jwt: %s}
+`, filepath.Join(dir, "app.log"), filepath.Join(dir, "backups"), tt.jwt)
+			if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err := LoadFile(path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("LoadFile hata = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("LoadFile hata = %v, %q içermeli", err, tt.wantErr)
+			}
+		})
+	}
+}
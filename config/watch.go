@@ -0,0 +1,137 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ChangeFunc config başarıyla yeniden yüklendiğinde eski ve yeni snapshot ile çağrılır.
+type ChangeFunc func(old, new *Config)
+
+var (
+	viperMu     sync.Mutex // viper eşzamanlı kullanım için güvenli değil
+	reloadMu    sync.Mutex // Yeniden yüklemeler ve bildirimler sırayla yapılır
+	subscribers []ChangeFunc
+	subsMu      sync.Mutex
+	watchOnce   sync.Once
+)
+
+// OnChange config değiştiğinde çağrılacak bir fonksiyon ekler. Fonksiyonlar
+// yeni snapshot Get ile görünür olduktan sonra, eklendikleri sırayla çağrılır.
+func OnChange(fn ChangeFunc) {
+	subsMu.Lock()
+	subscribers = append(subscribers, fn)
+	subsMu.Unlock()
+}
+
+// Watch config dosyasını izler; dosya değiştiğinde Reload çağrılır.
+// Config yalnızca ortam değişkenlerinden okunduysa izlenecek dosya yoktur.
+func Watch() {
+	watchOnce.Do(func() {
+		viperMu.Lock()
+		defer viperMu.Unlock()
+		if viper.ConfigFileUsed() == "" {
+			slog.Info("Config dosyası yok, dosya izleme atlandı")
+			return
+		}
+		viper.OnConfigChange(func(e fsnotify.Event) {
+			slog.Info("Config dosyası değişti", "file", e.Name, "op", e.Op.String())
+			if err := reload(false); err != nil {
+				slog.Error("Config yeniden yüklenemedi, önceki config kullanılmaya devam ediliyor", "error", err)
+			}
+		})
+		viper.WatchConfig()
+		slog.Info("Config dosyası izleniyor", "file", viper.ConfigFileUsed())
+	})
+}
+
+// Reload config dosyasını yeniden okur (ör: SIGHUP). Yeni config geçersizse hata döner
+// ve önceki config kullanılmaya devam edilir.
+func Reload() error {
+	return reload(true)
+}
+
+func reload(read bool) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	viperMu.Lock()
+	var err error
+	if read {
+		// Dosya izleyicisi kendi okumasını zaten yapmış olur
+		err = readConfig()
+	}
+	var cfg *Config
+	if err == nil {
+		cfg, err = decode()
+	}
+	viperMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	old := current.Load()
+	// Yeniden başlatma gerektiren ayarlar eski değerlerinde kalır; yeni değerler bir kısmı
+	// Get ile okunup bir kısmı başlangıçtaki haliyle kullanılmasın (ör: backup_dir değişince
+	// denetim zinciri yeni dizinde sıfırdan başlamasın)
+	if keys := keepRestartRequired(old, cfg); len(keys) > 0 {
+		slog.Warn("Bu ayarların değişikliği yeniden başlatılana kadar uygulanmaz", "keys", keys)
+	}
+	if reflect.DeepEqual(old, cfg) {
+		return nil
+	}
+	current.Store(cfg)
+	slog.Info("Config yeniden yüklendi")
+
+	subsMu.Lock()
+	subs := append([]ChangeFunc(nil), subscribers...)
+	subsMu.Unlock()
+	for _, fn := range subs {
+		fn(old, cfg)
+	}
+	return nil
+}
+
+// restartRequired çalışma sırasında uygulanamayan ayarlar. keep, ayar değişmişse eski değeri
+// yeni snapshot'a kopyalar ve true döner.
+var restartRequired = []struct {
+	key  string
+	keep func(old, new *Config) bool
+}{
+	{"server.port", func(o, n *Config) bool { return keep(&o.Server.Port, &n.Server.Port) }},
+	{"server.tls.enabled", func(o, n *Config) bool { return keep(&o.Server.TLS.Enabled, &n.Server.TLS.Enabled) }},
+	{"db", func(o, n *Config) bool { return keep(&o.DB, &n.DB) }},
+	{"cluster", func(o, n *Config) bool { return keep(&o.Cluster, &n.Cluster) }},
+	{"auth.keys_file", func(o, n *Config) bool { return keep(&o.Auth.KeysFile, &n.Auth.KeysFile) }},
+	{"internal_log.log_file", func(o, n *Config) bool { return keep(&o.InternalLog.LogFile, &n.InternalLog.LogFile) }},
+	{"kettas_log.upload_dir", func(o, n *Config) bool { return keep(&o.KettasLog.UploadDir, &n.KettasLog.UploadDir) }},
+	{"kettas_log.logs_dir", func(o, n *Config) bool { return keep(&o.KettasLog.LogsDir, &n.KettasLog.LogsDir) }},
+	{"kettas_log.max_file_size_mb", func(o, n *Config) bool { return keep(&o.KettasLog.MaxFileSizeMB, &n.KettasLog.MaxFileSizeMB) }},
+	{"kettas_log.backup.enabled", func(o, n *Config) bool { return keep(&o.KettasLog.Backup.Enabled, &n.KettasLog.Backup.Enabled) }},
+	{"kettas_log.backup.backup_dir", func(o, n *Config) bool { return keep(&o.KettasLog.Backup.BackupDir, &n.KettasLog.Backup.BackupDir) }},
+	{"kettas_log.keys", func(o, n *Config) bool { return keep(&o.KettasLog.Keys, &n.KettasLog.Keys) }},
+}
+
+// keepRestartRequired yeniden başlatma gerektiren ve değişmiş ayarların eski değerlerini
+// new'e kopyalar; bu ayarları döner.
+func keepRestartRequired(old, new *Config) []string {
+	var keys []string
+	for _, r := range restartRequired {
+		if r.keep(old, new) {
+			keys = append(keys, r.key)
+		}
+	}
+	return keys
+}
+
+func keep[T any](old, new *T) bool {
+	if reflect.DeepEqual(*old, *new) {
+		return false
+	}
+	*new = *old
+	return true
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReloadKeepsRestartRequired yeniden başlatma gerektiren ayarların Reload'dan sonra
+// Get'te eski değerlerinde kaldığını, diğerlerinin uygulandığını doğrular.
+func TestReloadKeepsRestartRequired(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	write := func(name, level string, maxMB int) {
+		t.Helper()
+		yaml := fmt.Sprintf(`internal_log: {log_file: %q, level: %s}
+kettas_log:
+  zip_password: test
+  upload_dir: %q
+  logs_dir: %q
+  max_file_size_mb: %d
+  backup: {backup_dir: %q}
+`, filepath.Join(dir, name, "app.log"), level, filepath.Join(dir, name, "uploads"), filepath.Join(dir, name, "logs"),
+			maxMB, filepath.Join(dir, name, "backups"))
+		if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a", "info", 10)
+	if err := LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	before := *Get()
+	write("b", "debug", 20)
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+	after := Get()
+
+	tests := []struct {
+		key       string
+		got, want any
+	}{
+		{"internal_log.log_file", after.InternalLog.LogFile, before.InternalLog.LogFile},
+		{"kettas_log.upload_dir", after.KettasLog.UploadDir, before.KettasLog.UploadDir},
+		{"kettas_log.logs_dir", after.KettasLog.LogsDir, before.KettasLog.LogsDir},
+		{"kettas_log.max_file_size_mb", after.KettasLog.MaxFileSizeMB, before.KettasLog.MaxFileSizeMB},
+		{"kettas_log.backup.backup_dir", after.KettasLog.Backup.BackupDir, before.KettasLog.Backup.BackupDir},
+		{"internal_log.level", after.InternalLog.Level, "debug"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("Get() = %v, %v bekleniyordu", tt.got, tt.want)
+			}
+		})
+	}
+}
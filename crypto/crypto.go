@@ -14,16 +14,15 @@ import (
 const encPrefix = "ENC("
 const encSuffix = ")"
 
-// generateKey magic key'den 32 byte AES-256 key türetir
-func generateKey(magicKey string) []byte {
-	hash := sha256.Sum256([]byte(magicKey))
+// DeriveKey bir parola (passphrase) metninden 32 byte AES-256 key türetir.
+// Eski sürümlerdeki sabit magic key de bu şekilde anahtara çevriliyordu.
+func DeriveKey(passphrase string) []byte {
+	hash := sha256.Sum256([]byte(passphrase))
 	return hash[:]
 }
 
 // Encrypt plaintext'i AES-GCM ile şifreler ve base64 olarak döner
-func Encrypt(plaintext, magicKey string) (string, error) {
-	key := generateKey(magicKey)
-
+func Encrypt(plaintext string, key []byte) (string, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", fmt.Errorf("cipher oluşturulamadı: %v", err)
@@ -44,9 +43,7 @@ func Encrypt(plaintext, magicKey string) (string, error) {
 }
 
 // Decrypt base64 encoded AES-GCM şifreli metni çözer
-func Decrypt(encoded, magicKey string) (string, error) {
-	key := generateKey(magicKey)
-
+func Decrypt(encoded string, key []byte) (string, error) {
 	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return "", fmt.Errorf("base64 decode hatası: %v", err)
@@ -80,14 +77,3 @@ func Decrypt(encoded, magicKey string) (string, error) {
 func IsEncrypted(value string) bool {
 	return strings.HasPrefix(value, encPrefix) && strings.HasSuffix(value, encSuffix)
 }
-
-// DecryptIfEncrypted ENC(...) ile sarılıysa çözer, değilse olduğu gibi döner
-func DecryptIfEncrypted(value, magicKey string) (string, error) {
-	if !IsEncrypted(value) {
-		return value, nil
-	}
-
-	// ENC(...) içindeki değeri çıkar
-	encoded := value[len(encPrefix) : len(value)-len(encSuffix)]
-	return Decrypt(encoded, magicKey)
-}
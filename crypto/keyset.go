@@ -0,0 +1,245 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// KeyEnv anahtarların kendisini içeren ortam değişkeni
+	KeyEnv = "LOGSERVER_SECRET_KEY"
+	// KeyFileEnv anahtar dosyasının yolunu içeren ortam değişkeni
+	KeyFileEnv = "LOGSERVER_SECRET_KEY_FILE"
+	// CredentialName systemd LoadCredential= ile verilen credential'ın adı ($CREDENTIALS_DIRECTORY altında)
+	CredentialName = "logserver-secret-key"
+
+	// legacyVersion sürüm öneki olmayan ENC(...) değerlerinin çözüldüğü anahtar sürümü
+	legacyVersion = 1
+)
+
+// encPattern bir metindeki ENC(...) değerlerini bulur (rekey için).
+var encPattern = regexp.MustCompile(`ENC\(([^()\s"']*)\)`)
+
+// KeySet config secret'larını şifrelemek için kullanılan sürümlü anahtarlar.
+// Yeni değerler en yüksek sürümle ENC(vN:...) olarak şifrelenir; eski sürümler
+// rotasyon sırasında mevcut değerleri çözebilmek için tutulur.
+type KeySet struct {
+	keys    map[int][]byte
+	current int
+}
+
+// ParseKeySet her satırda bir "vN:<anahtar>" girdisi olan metinden KeySet oluşturur.
+// Anahtar base64 veya hex kodlu 32 byte olabilir; değilse parola kabul edilip SHA-256 ile türetilir.
+// Sürüm öneki olmayan tek bir girdi v1 kabul edilir. # ile başlayan satırlar yok sayılır.
+// Parola virgül içerebilir; virgülle ayrılmış girdiler yalnızca ortam değişkeninde kabul edilir
+// (bkz. LoadKeySet).
+func ParseKeySet(s string) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[int][]byte)}
+
+	var entries []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	for _, entry := range entries {
+		version := legacyVersion
+		material := entry
+		if v, rest, ok := splitVersion(entry); ok {
+			version, material = v, rest
+		} else if len(entries) > 1 {
+			return nil, fmt.Errorf("birden fazla anahtar varsa her biri vN: önekiyle verilmeli")
+		}
+		if _, dup := ks.keys[version]; dup {
+			return nil, fmt.Errorf("v%d anahtarı birden fazla kez tanımlanmış", version)
+		}
+		ks.keys[version] = parseKeyMaterial(material)
+		if version > ks.current {
+			ks.current = version
+		}
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("anahtar bulunamadı")
+	}
+	return ks, nil
+}
+
+// splitVersion "v2:..." girdisini sürüm ve anahtar olarak ayırır.
+func splitVersion(entry string) (int, string, bool) {
+	if !strings.HasPrefix(entry, "v") {
+		return 0, "", false
+	}
+	prefix, rest, ok := strings.Cut(entry[1:], ":")
+	if !ok {
+		return 0, "", false
+	}
+	v, err := strconv.Atoi(prefix)
+	if err != nil || v <= 0 {
+		return 0, "", false
+	}
+	return v, strings.TrimSpace(rest), true
+}
+
+func parseKeyMaterial(s string) []byte {
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == 32 {
+		return key
+	}
+	if key, err := hex.DecodeString(s); err == nil && len(key) == 32 {
+		return key
+	}
+	return DeriveKey(s)
+}
+
+// LoadKeySet anahtarları sırasıyla LOGSERVER_SECRET_KEY, LOGSERVER_SECRET_KEY_FILE,
+// keyFile (config'deki secrets.key_file) ve systemd credential'dan
+// ($CREDENTIALS_DIRECTORY/logserver-secret-key) arar. Bulunan ilk kaynağı ve adını döner.
+func LoadKeySet(keyFile string) (*KeySet, string, error) {
+	if value := os.Getenv(KeyEnv); value != "" {
+		// Ortam değişkeni tek satır olduğu için birden fazla anahtar virgülle ayrılır
+		ks, err := ParseKeySet(strings.ReplaceAll(value, ",", "\n"))
+		if err != nil {
+			return nil, KeyEnv, fmt.Errorf("%s geçersiz: %w", KeyEnv, err)
+		}
+		return ks, KeyEnv, nil
+	}
+
+	var paths []string
+	if path := os.Getenv(KeyFileEnv); path != "" {
+		paths = append(paths, path)
+	}
+	if keyFile != "" {
+		paths = append(paths, keyFile)
+	}
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" {
+		paths = append(paths, filepath.Join(dir, CredentialName))
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, path, fmt.Errorf("anahtar dosyası okunamadı: %w", err)
+		}
+		ks, err := ParseKeySet(string(data))
+		if err != nil {
+			return nil, path, fmt.Errorf("%s geçersiz: %w", path, err)
+		}
+		return ks, path, nil
+	}
+
+	return nil, "", fmt.Errorf("secret anahtarı bulunamadı: %s, %s, secrets.key_file veya systemd credential %q gerekli",
+		KeyEnv, KeyFileEnv, CredentialName)
+}
+
+// Current yeni değerlerin şifrelendiği anahtar sürümü.
+func (ks *KeySet) Current() int {
+	return ks.current
+}
+
+// Versions tanımlı anahtar sürümlerini artan sırayla döner.
+func (ks *KeySet) Versions() []int {
+	versions := make([]int, 0, len(ks.keys))
+	for v := range ks.keys {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// Encrypt değeri güncel anahtarla şifreler ve ENC(vN:...) olarak döner.
+func (ks *KeySet) Encrypt(plaintext string) (string, error) {
+	return ks.EncryptVersion(plaintext, ks.current)
+}
+
+// EncryptVersion değeri verilen sürümdeki anahtarla şifreler.
+func (ks *KeySet) EncryptVersion(plaintext string, version int) (string, error) {
+	key, ok := ks.keys[version]
+	if !ok {
+		return "", fmt.Errorf("v%d anahtarı tanımlı değil", version)
+	}
+	encoded, err := Encrypt(plaintext, key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%sv%d:%s%s", encPrefix, version, encoded, encSuffix), nil
+}
+
+// DecryptIfEncrypted ENC(...) ile sarılıysa çözer, değilse olduğu gibi döner.
+// Sürüm öneki olmayan eski değerler v1 anahtarıyla çözülür.
+func (ks *KeySet) DecryptIfEncrypted(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	// ENC(...) içindeki değeri çıkar
+	inner := value[len(encPrefix) : len(value)-len(encSuffix)]
+	version := legacyVersion
+	if v, rest, ok := splitVersion(inner); ok {
+		version, inner = v, rest
+	}
+
+	key, ok := ks.keys[version]
+	if !ok {
+		return "", fmt.Errorf("v%d anahtarı tanımlı değil", version)
+	}
+	return Decrypt(inner, key)
+}
+
+// Version ENC(...) değerinin şifrelendiği anahtar sürümünü döner.
+func Version(value string) (int, bool) {
+	if !IsEncrypted(value) {
+		return 0, false
+	}
+	if v, _, ok := splitVersion(value[len(encPrefix) : len(value)-len(encSuffix)]); ok {
+		return v, true
+	}
+	return legacyVersion, true
+}
+
+// Rekey metindeki (ör: config.yaml içeriği) tüm ENC(...) değerlerini çözüp verilen sürümün
+// anahtarıyla yeniden şifreler. Metnin geri kalanı (yorumlar, biçim) değişmez.
+// Yeniden şifrelenen değer sayısını döner.
+func (ks *KeySet) Rekey(text string, version int) (string, int, error) {
+	if _, ok := ks.keys[version]; !ok {
+		return "", 0, fmt.Errorf("v%d anahtarı tanımlı değil", version)
+	}
+
+	var firstErr error
+	count := 0
+	out := encPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if firstErr != nil {
+			return match
+		}
+		if v, _ := Version(match); v == version {
+			return match
+		}
+		plain, err := ks.DecryptIfEncrypted(match)
+		if err != nil {
+			line := strings.Count(text[:strings.Index(text, match)], "\n") + 1
+			firstErr = fmt.Errorf("%d. satırdaki değer çözülemedi: %w", line, err)
+			return match
+		}
+		enc, err := ks.EncryptVersion(plain, version)
+		if err != nil {
+			firstErr = err
+			return match
+		}
+		count++
+		return enc
+	})
+	if firstErr != nil {
+		return "", 0, firstErr
+	}
+	return out, count, nil
+}
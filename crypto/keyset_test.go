@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseKeySet(t *testing.T) {
+	hexKey := strings.Repeat("ab", 32)
+	tests := []struct {
+		name    string
+		input   string
+		want    map[int][]byte
+		current int
+		wantErr bool
+	}{
+		{"tek parola", "gizli", map[int][]byte{1: DeriveKey("gizli")}, 1, false},
+		{"yorum başlığı ve sürümsüz anahtar", "# logserver anahtarı\n\ngizli\n", map[int][]byte{1: DeriveKey("gizli")}, 1, false},
+		{"virgüllü parola", "gizli,parola", map[int][]byte{1: DeriveKey("gizli,parola")}, 1, false},
+		{"sürümlü satırlar", "# rotasyon\nv1:eski\nv2:" + hexKey + "\n", map[int][]byte{1: DeriveKey("eski"), 2: bytes.Repeat([]byte{0xab}, 32)}, 2, false},
+		{"sürümsüz ve sürümlü", "gizli\nv2:yeni", nil, 0, true},
+		{"tekrarlanan sürüm", "v1:a\nv1:b", nil, 0, true},
+		{"yalnızca yorum", "# boş\n", nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := ParseKeySet(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeySet hata = %v, hata bekleniyor = %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if ks.current != tt.current || len(ks.keys) != len(tt.want) {
+				t.Fatalf("current = %d, %d anahtar; %d, %d bekleniyordu", ks.current, len(ks.keys), tt.current, len(tt.want))
+			}
+			for v, key := range tt.want {
+				if !bytes.Equal(ks.keys[v], key) {
+					t.Errorf("v%d anahtarı farklı", v)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadKeySetEnvCommas(t *testing.T) {
+	t.Setenv(KeyEnv, "v1:eski, v2:yeni")
+	ks, source, err := LoadKeySet("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source != KeyEnv || ks.current != 2 || !bytes.Equal(ks.keys[1], DeriveKey("eski")) {
+		t.Fatalf("kaynak = %s, current = %d", source, ks.current)
+	}
+}
@@ -75,6 +75,14 @@ func GetCollection() *mongo.Collection {
 	return collection
 }
 
+// GetDatabaseCollection aynı veritabanındaki başka bir koleksiyonu döndürür (ör: locks)
+func GetDatabaseCollection(name string) *mongo.Collection {
+	if client == nil {
+		return nil
+	}
+	return client.Database(config.Get().DB.DBName).Collection(name)
+}
+
 // InsertMany birden fazla dokümanı koleksiyona ekler
 func InsertMany(ctx context.Context, docs []interface{}) error {
 	if collection == nil {
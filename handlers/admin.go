@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"errors"
+	"log-server/audit"
+	"log-server/auth"
+	"log-server/backup"
+	"log-server/scheduler"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ──────────────────────────────────────────────────
+// GET /admin/backup/plan — Cleanup/rotation dry-run raporu
+// ──────────────────────────────────────────────────
+
+// GetBackupPlan bir sonraki cleanup ve rotation çalışmasında silinecek
+// veya arşivlenecek dosyaları (boyut ve sebepleriyle) döner. Hiçbir dosyaya dokunmaz.
+func GetBackupPlan(c *fiber.Ctx) error {
+	plan, err := backup.BuildPlan()
+	if err != nil {
+		slog.Error("Backup planı hesaplanamadı", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Backup planı hesaplanamadı",
+		})
+	}
+	return c.JSON(plan)
+}
+
+// ──────────────────────────────────────────────────
+// GET /admin/backup/deletions — Silme journal'ı
+// ──────────────────────────────────────────────────
+
+type deletionQueryParams struct {
+	Since  string `query:"since"` // RFC3339
+	Until  string `query:"until"` // RFC3339
+	HomeId string `query:"home_id"`
+	Job    string `query:"job"`
+	Limit  int    `query:"limit"`
+}
+
+// GetDeletionJournal gerçekleştirilmiş tüm silmeleri journal'dan filtreleyerek döner.
+// Query: since, until (RFC3339), home_id, job, limit
+func GetDeletionJournal(c *fiber.Ctx) error {
+	var params deletionQueryParams
+	if err := c.QueryParser(&params); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz query parametreleri",
+		})
+	}
+
+	q := backup.DeletionQuery{
+		Job:   params.Job,
+		Limit: params.Limit,
+	}
+	if params.HomeId != "" {
+		q.HomeIdDir = "home_id_" + params.HomeId
+	}
+
+	var err error
+	if params.Since != "" {
+		if q.Since, err = time.Parse(time.RFC3339, params.Since); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz since formatı. Beklenen: RFC3339",
+			})
+		}
+	}
+	if params.Until != "" {
+		if q.Until, err = time.Parse(time.RFC3339, params.Until); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz until formatı. Beklenen: RFC3339",
+			})
+		}
+	}
+
+	records, err := backup.ReadDeletionJournal(q)
+	if err != nil {
+		slog.Error("Deletion journal okunamadı", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Deletion journal okunamadı",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":     len(records),
+		"deletions": records,
+	})
+}
+
+// ──────────────────────────────────────────────────
+// GET /admin/audit — Denetim kaydı
+// ──────────────────────────────────────────────────
+
+type auditQueryParams struct {
+	Since  string `query:"since"` // RFC3339
+	Until  string `query:"until"` // RFC3339
+	Action string `query:"action"`
+	Actor  string `query:"actor"`
+	HomeId string `query:"home_id"`
+	Limit  int    `query:"limit"`
+}
+
+// GetAuditLog denetim kaydındaki erişimleri filtreleyerek döner. Zincir bütünlüğü
+// logctl audit verify ile kontrol edilir.
+// Query: since, until (RFC3339), action, actor, home_id, limit
+func GetAuditLog(c *fiber.Ctx) error {
+	var params auditQueryParams
+	if err := c.QueryParser(&params); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz query parametreleri",
+		})
+	}
+
+	q := audit.Query{
+		Action: params.Action,
+		Actor:  params.Actor,
+		HomeId: params.HomeId,
+		Limit:  params.Limit,
+	}
+	var err error
+	if params.Since != "" {
+		if q.Since, err = time.Parse(time.RFC3339, params.Since); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz since formatı. Beklenen: RFC3339",
+			})
+		}
+	}
+	if params.Until != "" {
+		if q.Until, err = time.Parse(time.RFC3339, params.Until); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz until formatı. Beklenen: RFC3339",
+			})
+		}
+	}
+
+	records, err := audit.Read(q)
+	if err != nil {
+		slog.Error("Denetim kaydı okunamadı", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Denetim kaydı okunamadı",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":   len(records),
+		"entries": records,
+	})
+}
+
+// ──────────────────────────────────────────────────
+// GET /admin/jobs — Zamanlanmış işler
+// ──────────────────────────────────────────────────
+
+// GetJobs scheduler'a kayıtlı işleri zamanlama, saat dilimi ve
+// bir sonraki çalışma zamanlarıyla birlikte döner.
+func GetJobs(c *fiber.Ctx) error {
+	jobs := scheduler.Get().Jobs()
+	return c.JSON(fiber.Map{
+		"count": len(jobs),
+		"jobs":  jobs,
+	})
+}
+
+// ──────────────────────────────────────────────────
+// POST /admin/jobs/:name/run — İşi manuel tetikler
+// ──────────────────────────────────────────────────
+
+type runJobRequest struct {
+	Date string `json:"date"` // DD_MM_YYYY, sadece daily_archive için (boşsa her evin saat dilimine göre dün)
+}
+
+// RunJob bir işi hemen arka planda çalıştırır. Aynı gruptaki zamanlanmış
+// çalışmalarla sıraya girer; sonuç /admin/jobs/history'den takip edilir.
+// daily_archive için body'de date verilirse o tarih arşivlenir.
+func RunJob(c *fiber.Ctx) error {
+	name := c.Params("name")
+
+	var req runJobRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz request body",
+			})
+		}
+	}
+
+	opts := scheduler.RunOptions{Trigger: scheduler.TriggerManual}
+	if req.Date != "" {
+		if name != "daily_archive" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "date parametresi sadece daily_archive için geçerli",
+			})
+		}
+		if _, err := time.Parse(dateLayout, req.Date); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz date formatı. Beklenen: DD_MM_YYYY",
+			})
+		}
+		date := req.Date
+		opts.Params = map[string]string{"date": date}
+		opts.Run = func(time.Time) (scheduler.Result, error) {
+			n, err := backup.ArchiveLogsForDate(date)
+			return scheduler.Result{FilesProcessed: n}, err
+		}
+	}
+
+	runId, err := scheduler.Get().Trigger(name, opts)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "İş bulunamadı: " + name,
+			})
+		}
+		slog.Error("İş tetiklenemedi", "job", name, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "İş tetiklenemedi",
+		})
+	}
+
+	slog.Info("İş manuel tetiklendi", "job", name, "run_id", runId, "ip", c.IP())
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "İş kuyruğa alındı",
+		"job":     name,
+		"run_id":  runId,
+	})
+}
+
+// ──────────────────────────────────────────────────
+// POST /admin/exports — Parquet export başlatır
+// ──────────────────────────────────────────────────
+
+type exportRequest struct {
+	HomeId    string `json:"home_id"`    // Boşsa tüm evler
+	StartDate string `json:"start_date"` // DD_MM_YYYY (zorunlu)
+	EndDate   string `json:"end_date"`   // DD_MM_YYYY (opsiyonel, boşsa start_date ile aynı)
+}
+
+// CreateExport arşivlenmiş event'leri Parquet'e aktaran parquet_export işini arka planda
+// başlatır. Dosyalar export dizinine yazılır; sonuç /admin/jobs/history'den takip edilir.
+// Body: { "home_id": "...", "start_date": "DD_MM_YYYY", "end_date": "DD_MM_YYYY" }
+func CreateExport(c *fiber.Ctx) error {
+	var req exportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz request body",
+		})
+	}
+
+	if req.HomeId != "" && !auth.ValidHomeId(req.HomeId) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz home_id",
+		})
+	}
+	if req.StartDate == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "start_date parametresi gerekli",
+		})
+	}
+	startDate, err := time.Parse(dateLayout, req.StartDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz start_date formatı. Beklenen: DD_MM_YYYY",
+		})
+	}
+	if req.EndDate != "" {
+		endDate, err := time.Parse(dateLayout, req.EndDate)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz end_date formatı. Beklenen: DD_MM_YYYY",
+			})
+		}
+		if endDate.Before(startDate) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "end_date, start_date'den önce olamaz",
+			})
+		}
+	}
+
+	exportReq := backup.ExportRequest{HomeId: req.HomeId, StartDate: req.StartDate, EndDate: req.EndDate}
+	opts := scheduler.RunOptions{
+		Trigger: scheduler.TriggerManual,
+		Params: map[string]string{
+			"home_id":    req.HomeId,
+			"start_date": req.StartDate,
+			"end_date":   req.EndDate,
+		},
+		Run: func(time.Time) (scheduler.Result, error) {
+			files, err := backup.ExportParquet(exportReq)
+			return scheduler.Result{FilesProcessed: len(files)}, err
+		},
+	}
+
+	runId, err := scheduler.Get().Trigger("parquet_export", opts)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrJobNotFound) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Export işi kayıtlı değil (backup devre dışı)",
+			})
+		}
+		slog.Error("Export tetiklenemedi", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Export tetiklenemedi",
+		})
+	}
+
+	slog.Info("Parquet export tetiklendi", "run_id", runId, "home_id", req.HomeId, "start_date", req.StartDate, "end_date", req.EndDate)
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Export kuyruğa alındı",
+		"job":     "parquet_export",
+		"run_id":  runId,
+	})
+}
+
+// ──────────────────────────────────────────────────
+// GET /admin/exports — Oluşturulmuş Parquet dosyaları
+// ──────────────────────────────────────────────────
+
+// GetExports export dizinindeki Parquet dosyalarını boyut ve tarihleriyle döner.
+func GetExports(c *fiber.Ctx) error {
+	files, err := backup.ListExports()
+	if err != nil {
+		slog.Error("Export dizini okunamadı", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Export dizini okunamadı",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"count":   len(files),
+		"exports": files,
+	})
+}
+
+// ──────────────────────────────────────────────────
+// GET /admin/jobs/history — İş çalışma geçmişi
+// ──────────────────────────────────────────────────
+
+type jobHistoryParams struct {
+	Job    string `query:"job"`
+	Status string `query:"status"`
+	Since  string `query:"since"` // RFC3339
+	Limit  int    `query:"limit"`
+}
+
+// GetJobHistory tamamlanmış iş çalışmalarını (süre, işlenen dosya, hata) en yeniden eskiye döner.
+// Query: job, status (success|failed), since (RFC3339), limit (varsayılan 100)
+func GetJobHistory(c *fiber.Ctx) error {
+	var params jobHistoryParams
+	if err := c.QueryParser(&params); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz query parametreleri",
+		})
+	}
+
+	q := scheduler.HistoryQuery{
+		Job:    params.Job,
+		Status: params.Status,
+		Limit:  params.Limit,
+	}
+	if q.Limit <= 0 {
+		q.Limit = 100
+	}
+	if params.Since != "" {
+		t, err := time.Parse(time.RFC3339, params.Since)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz since formatı. Beklenen: RFC3339",
+			})
+		}
+		q.Since = t
+	}
+
+	runs, err := scheduler.Get().History(q)
+	if err != nil {
+		slog.Error("Job history okunamadı", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Job history okunamadı",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"count": len(runs),
+		"runs":  runs,
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"log-server/auth"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ──────────────────────────────────────────────────
+// GET /admin/api-keys — API anahtarlarının listesi
+// ──────────────────────────────────────────────────
+
+// GetApiKeys tüm API anahtarlarını (secret ve hash olmadan) durumlarıyla döner.
+func GetApiKeys(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"keys": auth.Store().List(auth.KindApiKey, ""),
+	})
+}
+
+// ──────────────────────────────────────────────────
+// POST /admin/api-keys — Yeni API anahtarı
+// ──────────────────────────────────────────────────
+
+// CreateApiKey yeni bir anahtar oluşturur. Secret yalnızca bu yanıtta döner.
+// Body: { "name": "...", "scopes": ["read:home"], "home_ids": ["..."], "expires_at": "RFC3339" }
+func CreateApiKey(c *fiber.Ctx) error {
+	var req auth.CreateKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz request body",
+		})
+	}
+	if p := auth.FromCtx(c); p != nil {
+		req.CreatedBy = p.ID
+	}
+
+	info, secret, err := auth.Store().Create(req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	slog.Info("API anahtarı oluşturuldu", "key_id", info.ID, "name", info.Name, "scopes", info.Scopes, "created_by", info.CreatedBy)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"key":    info,
+		"secret": secret,
+	})
+}
+
+// ──────────────────────────────────────────────────
+// DELETE /admin/api-keys/:id — API anahtarını iptal et
+// ──────────────────────────────────────────────────
+
+// RevokeApiKey anahtarı iptal eder; kayıt listede "revoked" olarak kalır.
+func RevokeApiKey(c *fiber.Ctx) error {
+	info, err := auth.Store().Revoke(auth.KindApiKey, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, auth.ErrKeyNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "API anahtarı bulunamadı",
+			})
+		}
+		slog.Error("API anahtarı iptal edilemedi", "key_id", c.Params("id"), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "API anahtarı iptal edilemedi",
+		})
+	}
+
+	slog.Info("API anahtarı iptal edildi", "key_id", info.ID, "name", info.Name)
+	return c.JSON(fiber.Map{
+		"key": info,
+	})
+}
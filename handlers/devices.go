@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"log-server/auth"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ──────────────────────────────────────────────────
+// GET /admin/devices — Cihaz token'larının listesi
+// ──────────────────────────────────────────────────
+
+// GetDevices cihaz token'larını (secret ve hash olmadan) durumlarıyla döner.
+// Query: home_id (opsiyonel)
+func GetDevices(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"devices": auth.Store().List(auth.KindDevice, c.Query("home_id")),
+	})
+}
+
+// ──────────────────────────────────────────────────
+// POST /admin/devices — Ev için cihaz token'ı oluştur
+// ──────────────────────────────────────────────────
+
+// ProvisionDevice bir ev için upload yetkili cihaz token'ı oluşturur. Token ile yapılan
+// upload'larda dosya adındaki home_id token'ın evine eşit olmalıdır. Secret yalnızca bu yanıtta döner.
+// Body: { "home_id": "...", "name": "...", "expires_at": "RFC3339" }
+func ProvisionDevice(c *fiber.Ctx) error {
+	var req auth.ProvisionDeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz request body",
+		})
+	}
+	if p := auth.FromCtx(c); p != nil {
+		req.CreatedBy = p.ID
+	}
+
+	info, secret, err := auth.Store().ProvisionDevice(req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	slog.Info("Cihaz token'ı oluşturuldu", "device_id", info.ID, "home_id", req.HomeId, "created_by", info.CreatedBy)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"device": info,
+		"token":  secret,
+	})
+}
+
+// ──────────────────────────────────────────────────
+// DELETE /admin/devices/:id — Cihaz token'ını iptal et
+// ──────────────────────────────────────────────────
+
+// RevokeDevice cihaz token'ını iptal eder; evin diğer cihazları etkilenmez.
+func RevokeDevice(c *fiber.Ctx) error {
+	info, err := auth.Store().Revoke(auth.KindDevice, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, auth.ErrKeyNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Cihaz bulunamadı",
+			})
+		}
+		slog.Error("Cihaz token'ı iptal edilemedi", "device_id", c.Params("id"), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Cihaz token'ı iptal edilemedi",
+		})
+	}
+
+	slog.Info("Cihaz token'ı iptal edildi", "device_id", info.ID, "home_ids", info.HomeIds)
+	return c.JSON(fiber.Map{
+		"device": info,
+	})
+}
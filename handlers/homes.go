@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"log-server/audit"
+	"log-server/auth"
+	"log-server/backup"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ──────────────────────────────────────────────────
+// DELETE /v1/homes/:id — Bir evin tüm verilerinin silinmesi (GDPR)
+// ──────────────────────────────────────────────────
+
+// DeleteHome evin canlı loglarını, arşivlerini, export'larını ve MongoDB kayıtlarını siler,
+// evin veri anahtarlarını yok eder ve Ed25519 ile imzalanmış silme sertifikasını döner.
+// Bir adım başarısız olursa 500 ile birlikte (complete=false) sertifika yine döner;
+// istek tekrarlanabilir.
+func DeleteHome(c *fiber.Ctx) error {
+	homeId := c.Params("id")
+	audit.FromCtx(c).SetHomes(homeId)
+	if !auth.FromCtx(c).CanAccessHome(homeId) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Bu eve erişim yetkiniz yok",
+		})
+	}
+
+	cert, rec, err := backup.EraseHome(homeId)
+	if err != nil {
+		if errors.Is(err, backup.ErrInvalidHomeId) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz home_id",
+			})
+		}
+		slog.Error("Ev silinemedi", "home_id", homeId, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":       "Ev silinemedi",
+			"certificate": cert,
+		})
+	}
+
+	status := fiber.StatusOK
+	if !rec.Complete {
+		status = fiber.StatusInternalServerError
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"erasure_id":  rec.Id,
+		"complete":    rec.Complete,
+		"certificate": cert,
+	})
+}
+
+// ──────────────────────────────────────────────────
+// GET /v1/erasures/public-key — Silme sertifikalarını doğrulama anahtarı
+// ──────────────────────────────────────────────────
+
+// GetErasurePublicKey sertifika imzalarını doğrulamak için Ed25519 public key'i döner.
+func GetErasurePublicKey(c *fiber.Ctx) error {
+	pub, err := backup.ErasurePublicKey()
+	if err != nil {
+		slog.Error("İmza anahtarı okunamadı", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "İmza anahtarı okunamadı",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"algorithm":  "ed25519",
+		"public_key": base64.StdEncoding.EncodeToString(pub),
+	})
+}
@@ -6,7 +6,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log-server/archive"
+	"log-server/audit"
+	"log-server/auth"
+	"log-server/backup"
 	"log-server/config"
+	"log-server/ratelimit"
 	"log/slog"
 	"mime/multipart"
 	"net/http"
@@ -14,6 +19,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -36,7 +42,7 @@ type LogRequest struct {
 // GET /logs — Tüm home_id'ler için log zip dosyalarını döner
 // ──────────────────────────────────────────────────
 
-// GetAllLogs tüm home_id klasörlerindeki zip dosyalarını
+// GetAllLogs kimliğin okuyabildiği tüm home_id klasörlerindeki zip dosyalarını
 // (tarih filtresine göre) tek bir zip içinde toplar.
 // Ziplenen yapı: home_id_XXX/filename.zip
 // Body: { "start_date": "DD_MM_YYYY", "end_date": "DD_MM_YYYY" }
@@ -48,6 +54,9 @@ func GetAllLogs(c *fiber.Ctx) error {
 		})
 	}
 
+	access := audit.FromCtx(c)
+	access.SetRange(req.StartDate, req.EndDate)
+
 	if req.StartDate == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "start_date parametresi gerekli",
@@ -101,16 +110,25 @@ func GetAllLogs(c *fiber.Ctx) error {
 	c.Set("Content-Type", "application/zip")
 	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", bundleName))
 
+	// Kimliğin ev bağlaması ve rolleri (auth.rbac) dışındaki evler atlanır
+	readable := auth.ReadableHome(c)
+
 	// Stream writer ile zip oluştur
-	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+	streamBody(c, func(w io.Writer) {
 		zipWriter := zip.NewWriter(w)
 		defer zipWriter.Close()
 
+		var homes []string
+		defer func() { access.SetHomes(homes...) }()
+
 		for _, entry := range entries {
 			// Sadece home_id_ ile başlayan klasörleri işle
 			if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "home_id_") {
 				continue
 			}
+			if !readable(strings.TrimPrefix(entry.Name(), "home_id_")) {
+				continue
+			}
 
 			homeDirName := entry.Name()
 			homePath := filepath.Join(backupRoot, homeDirName)
@@ -122,6 +140,9 @@ func GetAllLogs(c *fiber.Ctx) error {
 				continue
 			}
 
+			if len(matchingFiles) > 0 {
+				homes = append(homes, strings.TrimPrefix(homeDirName, "home_id_"))
+			}
 			for _, filePath := range matchingFiles {
 				// Zip içindeki yapı: home_id_XXX/dosya.zip
 				archiveName := filepath.Join(homeDirName, filepath.Base(filePath))
@@ -157,6 +178,16 @@ func GetLogByHomeId(c *fiber.Ctx) error {
 		})
 	}
 
+	access := audit.FromCtx(c)
+	access.SetHomes(req.HomeId)
+	access.SetRange(req.StartDate, req.EndDate)
+
+	if !auth.CanReadHome(c, req.HomeId) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Bu eve erişim yetkiniz yok",
+		})
+	}
+
 	startDate, err := time.Parse(dateLayout, req.StartDate)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -210,8 +241,27 @@ func GetLogByHomeId(c *fiber.Ctx) error {
 	// Tek zip ise direkt gönder
 	if len(matchingFiles) == 1 {
 		filePath := matchingFiles[0]
-		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filepath.Base(filePath)))
-		return c.SendFile(filePath)
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", archive.LegacyName(filePath)))
+		if !archive.IsLSA(filePath) {
+			if keyId, err := archive.KeyID(filePath); err == nil && keyId == "" {
+				if err := streamFile(c, filePath); err != nil {
+					slog.Error("Zip dosyası açılamadı", "file", filePath, "error", err)
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+						"error": "Backup dosyası okunamadı",
+					})
+				}
+				return nil
+			}
+		}
+
+		// .lsa veya veri anahtarıyla şifreli arşiv → mevcut istemciler için zip_password'lü zip'e dönüştürerek gönder
+		c.Set("Content-Type", "application/zip")
+		streamBody(c, func(w io.Writer) {
+			if err := archive.WriteLegacyZip(w, filePath, backup.ArchiveCredentials(), cfg.KettasLog.ZipPassword); err != nil {
+				slog.Error("Arşiv zip'e dönüştürülemedi", "file", filePath, "error", err)
+			}
+		})
+		return nil
 	}
 
 	// Birden fazla zip → hepsini tek bir zip'e sar
@@ -225,7 +275,7 @@ func GetLogByHomeId(c *fiber.Ctx) error {
 	c.Set("Content-Type", "application/zip")
 	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", bundleName))
 
-	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+	streamBody(c, func(w io.Writer) {
 		zipWriter := zip.NewWriter(w)
 		defer zipWriter.Close()
 
@@ -302,12 +352,6 @@ func SendToAiService(homeId, date string) error {
 
 // postZipToAiService tek bir zip dosyasını multipart/form-data ile AI servisine POST eder.
 func postZipToAiService(targetUrl, homeId, date, zipPath string) error {
-	file, err := os.Open(zipPath)
-	if err != nil {
-		return fmt.Errorf("zip dosyası açılamadı: %w", err)
-	}
-	defer file.Close()
-
 	// Multipart form oluştur
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -323,12 +367,13 @@ func postZipToAiService(targetUrl, homeId, date, zipPath string) error {
 	}
 
 	// zip file field
-	part, err := writer.CreateFormFile("file", filepath.Base(zipPath))
+	part, err := writer.CreateFormFile("file", archive.LegacyName(zipPath))
 	if err != nil {
 		return fmt.Errorf("form file oluşturulamadı: %w", err)
 	}
 
-	if _, err := io.Copy(part, file); err != nil {
+	// .lsa arşivler AI servisine de eski zip formatında gider
+	if err := archive.WriteLegacyZip(part, zipPath, backup.ArchiveCredentials(), config.Get().KettasLog.ZipPassword); err != nil {
 		return fmt.Errorf("dosya kopyalanamadı: %w", err)
 	}
 
@@ -357,7 +402,7 @@ func postZipToAiService(targetUrl, homeId, date, zipPath string) error {
 	return nil
 }
 
-// findZipsByDateRange backupPath içinde tarih aralığına uyan zip dosyalarını bulur.
+// findZipsByDateRange backupPath içinde tarih aralığına uyan arşivleri (.zip, .lsa) bulur.
 func findZipsByDateRange(backupPath string, startDate, endDate time.Time) ([]string, error) {
 	files, err := os.ReadDir(backupPath)
 	if err != nil {
@@ -366,7 +411,7 @@ func findZipsByDateRange(backupPath string, startDate, endDate time.Time) ([]str
 
 	var matchingFiles []string
 	for _, f := range files {
-		if f.IsDir() || !strings.HasSuffix(f.Name(), ".zip") {
+		if f.IsDir() || !archive.IsArchive(f.Name()) {
 			continue
 		}
 
@@ -401,14 +446,25 @@ func extractDateFromZipName(fileName string) (time.Time, bool) {
 }
 
 // addFileToZip bir dosyayı zip archive'a ekler.
+// .lsa arşivler eski formatta (şifreli zip) eklenir.
 func addFileToZip(zipWriter *zip.Writer, filePath string, archiveName string) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
+	if archive.IsLSA(filePath) {
+		header := &zip.FileHeader{
+			Name:     strings.TrimSuffix(archiveName, archive.Ext) + ".zip",
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		}
+		if info, err := os.Stat(filePath); err == nil {
+			header.Modified = info.ModTime()
+		}
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		return archive.WriteLegacyZip(writer, filePath, backup.ArchiveCredentials(), config.Get().KettasLog.ZipPassword)
 	}
-	defer file.Close()
 
-	info, err := file.Stat()
+	info, err := os.Stat(filePath)
 	if err != nil {
 		return err
 	}
@@ -425,6 +481,74 @@ func addFileToZip(zipWriter *zip.Writer, filePath string, archiveName string) er
 		return err
 	}
 
-	_, err = io.Copy(writer, file)
+	// Veri anahtarıyla şifreli zip'ler zip_password'e dönüştürülür, diğerleri olduğu gibi kopyalanır
+	return archive.WriteLegacyZip(writer, filePath, backup.ArchiveCredentials(), config.Get().KettasLog.ZipPassword)
+}
+
+// streamBody yanıtı akışla yazar. İndirme slotu ve denetim kaydı akış bitene kadar tutulur;
+// denetim kaydına gönderilen byte sayısı eklenir.
+func streamBody(c *fiber.Ctx, fn func(w io.Writer)) {
+	release := ratelimit.Hold(c)
+	finish := audit.Hold(c)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		cw := &countingWriter{w: w}
+		defer func() {
+			release()
+			finish(cw.n)
+		}()
+		fn(cw)
+	})
+}
+
+// streamFile dosyayı Content-Length ile akışla gönderir. c.SendFile'dan farkı, indirme slotu ve
+// denetim kaydının streamBody'deki gibi dosya gönderilip kapanana kadar tutulmasıdır.
+func streamFile(c *fiber.Ctx, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	c.Set("Content-Type", "application/zip")
+	c.Context().SetBodyStream(&heldFile{f: f, release: ratelimit.Hold(c), finish: audit.Hold(c)}, int(info.Size()))
+	return nil
+}
+
+// heldFile okunan byte'ları sayar; fasthttp gövdeyi gönderdikten (veya bağlantı koptuktan) sonra
+// Close'u çağırır. *os.File gömülmez: WriteTo/sendfile sayacı atlardı.
+type heldFile struct {
+	f       *os.File
+	n       int64
+	release func()
+	finish  func(bytes int64)
+	once    sync.Once
+}
+
+func (hf *heldFile) Read(p []byte) (int, error) {
+	n, err := hf.f.Read(p)
+	hf.n += int64(n)
+	return n, err
+}
+
+func (hf *heldFile) Close() error {
+	err := hf.f.Close()
+	hf.once.Do(func() {
+		hf.release()
+		hf.finish(hf.n)
+	})
 	return err
 }
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log-server/audit"
+	"log-server/auth"
+	"log-server/backup"
+	"log-server/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var newline = []byte{'\n'}
+
+const (
+	defaultSearchLimit = 1000
+	maxSearchLimit     = 100000
+)
+
+type SearchRequest struct {
+	HomeId   string `json:"home_id"`
+	From     string `json:"from"`     // RFC3339 (opsiyonel)
+	To       string `json:"to"`       // RFC3339 (opsiyonel)
+	Contains string `json:"contains"` // Event içinde geçmesi gereken metin (opsiyonel)
+	Limit    int    `json:"limit"`    // Varsayılan 1000
+}
+
+// ──────────────────────────────────────────────────
+// GET /home-logs/search — Bir evin arşivlerinde event araması
+// ──────────────────────────────────────────────────
+
+// SearchHomeLogs bir evin arşivlenmiş event'lerini zaman aralığı ve metne göre arar,
+// sonuçları NDJSON olarak akıtır. zstd (.lsa) arşivlerde yalnızca aralıkla kesişen
+// chunk'lar çözülür; eski zip arşivler tamamen okunur.
+// Body: { "home_id": "...", "from": "RFC3339", "to": "RFC3339", "contains": "...", "limit": 1000 }
+func SearchHomeLogs(c *fiber.Ctx) error {
+	var req SearchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz request body",
+		})
+	}
+
+	if req.HomeId == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "home_id parametresi gerekli",
+		})
+	}
+
+	access := audit.FromCtx(c)
+	access.SetHomes(req.HomeId)
+	access.SetRange(req.From, req.To)
+
+	if !auth.CanReadHome(c, req.HomeId) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Bu eve erişim yetkiniz yok",
+		})
+	}
+
+	q := backup.SearchQuery{
+		HomeId:   req.HomeId,
+		Contains: req.Contains,
+		Limit:    req.Limit,
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultSearchLimit
+	}
+	if q.Limit > maxSearchLimit {
+		q.Limit = maxSearchLimit
+	}
+
+	var err error
+	if req.From != "" {
+		if q.From, err = time.Parse(time.RFC3339, req.From); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz from formatı. Beklenen: RFC3339",
+			})
+		}
+	}
+	if req.To != "" {
+		if q.To, err = time.Parse(time.RFC3339, req.To); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz to formatı. Beklenen: RFC3339",
+			})
+		}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.To.Before(q.From) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "to, from'dan önce olamaz",
+		})
+	}
+
+	// Akış başladıktan sonra durum kodu değiştirilemeyeceği için klasörü önceden kontrol et
+	backupPath := filepath.Join(config.Get().KettasLog.Backup.BackupDir, fmt.Sprintf("home_id_%s", req.HomeId))
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Bu home_id için backup bulunamadı",
+		})
+	}
+
+	c.Set("Content-Type", "application/x-ndjson")
+	streamBody(c, func(w io.Writer) {
+		found, err := backup.SearchArchives(q, func(event []byte) error {
+			if _, err := w.Write(event); err != nil {
+				return err
+			}
+			_, err := w.Write(newline)
+			return err
+		})
+		if err != nil {
+			slog.Error("Arşiv araması yarıda kaldı", "home_id", req.HomeId, "found", found, "error", err)
+		}
+	})
+
+	return nil
+}
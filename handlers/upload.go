@@ -11,8 +11,11 @@ import (
 	"strings"
 	"time"
 
+	"log-server/auth"
 	"log-server/config"
 	"log-server/db"
+	"log-server/lock"
+	"log-server/ratelimit"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -82,6 +85,30 @@ func Upload(c *fiber.Ctx) error {
 	}
 	homeId := parts[0]
 
+	// Cihaz token'ları yalnızca bağlı oldukları ev için, evlere bağlı anahtarlar
+	// yalnızca kendi evleri için log yükleyebilir
+	p := auth.FromCtx(c)
+	if deviceHome, ok := p.DeviceHome(); ok && homeId != deviceHome {
+		slog.Warn("Upload for foreign home rejected", "home_id", homeId, "device_home_id", deviceHome, "key_id", p.ID)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Filename home_id does not match the device credential",
+		})
+	}
+	if !p.CanAccessHome(homeId) {
+		slog.Warn("Upload for foreign home rejected", "home_id", homeId, "key_id", p.ID)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Not allowed to upload logs for this home",
+		})
+	}
+
+	if wait, ok := ratelimit.AllowHome(homeId); !ok {
+		slog.Warn("Rate limit exceeded", "home_id", homeId, "key_id", p.ID, "limit", "per_home")
+		ratelimit.RetryAfter(c, wait)
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "Too many uploads for this home",
+		})
+	}
+
 	// Zip dosyasını geçici dizine kaydet
 	tempFilePath := filepath.Join(cfg.KettasLog.UploadDir, filename)
 	if err := c.SaveFile(file, tempFilePath); err != nil {
@@ -94,6 +121,20 @@ func Upload(c *fiber.Ctx) error {
 
 	// Hedef dizin oluştur (homeId bazlı, örn: logs/home_id_UUID)
 	targetDirName := fmt.Sprintf("home_id_%s", homeId)
+
+	// Arşivleme bu klasörü işlerken dosya bırakmamak için home kilidini al
+	lockCtx, cancel := context.WithTimeout(context.Background(), lock.HomeLockTimeout())
+	release, err := lock.Get().Lock(lockCtx, lock.HomeKey(targetDirName))
+	cancel()
+	if err != nil {
+		slog.Warn("Home kilidi alınamadı", "home_id", homeId, "error", err)
+		c.Set("Retry-After", "30")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Home directory is busy, retry later",
+		})
+	}
+	defer release()
+
 	targetDir := filepath.Join(cfg.KettasLog.LogsDir, targetDirName)
 	if err := os.MkdirAll(targetDir, 0755); err != nil {
 		slog.Error("Failed to create target directory", "error", err.Error())
@@ -176,6 +217,7 @@ func processAndInsertLogs(targetDir, homeId string) (int, error) {
 			now := time.Now().UTC()
 			logEntry["db_server_received_at_utc"] = now
 			logEntry["db_server_received_at_timestamp"] = now.Unix()
+			logEntry["db_home_id"] = homeId // Ev silme (erasure) için
 			allDocs = append(allDocs, logEntry)
 		}
 	}
@@ -0,0 +1,693 @@
+// Package keys arşivler için envelope encryption anahtar yönetimini sağlar.
+//
+// Her evin (home_id) arşivleri kendi rastgele veri anahtarıyla (data key) şifrelenir.
+// Veri anahtarları master key ile AES-GCM kullanılarak sarılır (wrap) ve keyring
+// dosyasında saklanır; master key diske yazılmaz. Master key rotasyonu yalnızca
+// keyring'deki sarılmış anahtarları yeniden sarar, arşivlere dokunmaz.
+//
+// Keyring backup_dir ile aynı dizin ağacında olamaz: arşivlerin kopyalandığı her yere
+// anahtarlar da gitmesin ve bir evin anahtarları silindiğinde arşiv yedeklerinden geri
+// gelmesin. Keyring'in kendi yedekleri ayrı tutulmalıdır; bir evin anahtarları bu yedeklerde
+// saklama süresi dolana kadar kalır ve crypto-shredding ancak o zaman tamamlanır. Yedekleri
+// beklemeden tamamlamak için master key döndürülüp (logctl keyring rotate-master) eskisi imha
+// edilmelidir; eski yedekler eski master key olmadan açılamaz.
+package keys
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log-server/config"
+	"log-server/lock"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	keySize         = 32
+	defaultEnvName  = "LOGSERVER_MASTER_KEY"
+	keyringLockName = "keyring"
+	keyringVersion  = 1
+)
+
+var (
+	ErrKeyNotFound    = errors.New("anahtar keyring'de bulunamadı")
+	ErrMasterMismatch = errors.New("keyring farklı bir master key ile sarılmış")
+)
+
+type wrappedKey struct {
+	ID          string    `json:"id"`
+	MasterKeyID string    `json:"master_key_id"`
+	Wrapped     string    `json:"wrapped"` // base64(nonce | AES-GCM ciphertext)
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type homeKeys struct {
+	Active string       `json:"active"` // Yeni arşivlerde kullanılan anahtar
+	Keys   []wrappedKey `json:"keys"`   // Eski arşivleri okumak için önceki anahtarlar da tutulur
+}
+
+type keyringFile struct {
+	Version     int                  `json:"version"`
+	MasterKeyID string               `json:"master_key_id"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Homes       map[string]*homeKeys `json:"homes"`
+}
+
+// Keyring sarılmış veri anahtarlarını tutar ve ihtiyaç oldukça çözer.
+type Keyring struct {
+	mu       sync.Mutex
+	path     string
+	master   []byte
+	masterId string
+	file     keyringFile
+	stamp    fileStamp         // Son okunan/yazılan keyring dosyasının durumu
+	cache    map[string][]byte // anahtar id → çözülmüş anahtar; dışarıya yalnızca kopyaları verilir
+}
+
+// fileStamp keyring dosyasının başka bir instance tarafından değiştirilip değiştirilmediğini
+// anlamak için kullanılır.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(info os.FileInfo) fileStamp {
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+func (s fileStamp) equal(o fileStamp) bool {
+	return s.modTime.Equal(o.modTime) && s.size == o.size
+}
+
+// HomeKeyInfo bir evin anahtarlarının özeti (anahtarların kendisi olmadan).
+type HomeKeyInfo struct {
+	HomeIdDir string    `json:"home_id_dir"`
+	Active    string    `json:"active"`
+	KeyIds    []string  `json:"key_ids"`
+	CreatedAt time.Time `json:"created_at"` // Aktif anahtarın oluşturulma zamanı
+}
+
+var ring *Keyring
+
+func init() {
+	config.RegisterValidator(validateKeyring)
+}
+
+// validateKeyring keyring'in backup_dir ile aynı ağaçta olmadığını kontrol eder.
+func validateKeyring(cfg *config.Config) []config.Problem {
+	kl := cfg.KettasLog
+	if !kl.Keys.Enabled {
+		return nil
+	}
+	const key = "kettas_log.keys.keyring_file"
+	path := keyringPath(cfg)
+	if sameTree(filepath.Dir(path), kl.Backup.BackupDir) {
+		return []config.Problem{{Key: key, Message: fmt.Sprintf(
+			"keyring (%s) backup_dir (%s) ile aynı dizin ağacında olamaz", path, kl.Backup.BackupDir)}}
+	}
+	return nil
+}
+
+// sameTree dizinlerden birinin diğerinin içinde (veya aynı) olup olmadığını döner.
+// Var olan yollardaki sembolik linkler çözülür.
+func sameTree(a, b string) bool {
+	a, b = resolvePath(a), resolvePath(b)
+	return within(a, b) || within(b, a)
+}
+
+func within(dir, parent string) bool {
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolvePath yolu mutlak hale getirir; var olan en uzun ön ekteki sembolik linkleri çözer.
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	var rest []string
+	for p := abs; ; p = filepath.Dir(p) {
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...)
+		}
+		if filepath.Dir(p) == p {
+			return abs
+		}
+		rest = append([]string{filepath.Base(p)}, rest...)
+	}
+}
+
+// Init keys.enabled ise master key'i ve keyring'i yükler. Kapalıysa hiçbir şey yapmaz.
+func Init() error {
+	if !config.Get().KettasLog.Keys.Enabled {
+		return nil
+	}
+
+	master, err := LoadMasterKey()
+	if err != nil {
+		return err
+	}
+	k, err := Open(KeyringPath(), master)
+	if err != nil {
+		return err
+	}
+	ring = k
+
+	slog.Info("Envelope encryption aktif", "keyring", k.path, "master_key_id", k.masterId, "homes", len(k.file.Homes))
+	return nil
+}
+
+// Get aktif keyring'i döner; envelope encryption kapalıysa nil.
+func Get() *Keyring {
+	return ring
+}
+
+// Enabled envelope encryption'ın aktif olup olmadığını döner.
+func Enabled() bool {
+	return ring != nil
+}
+
+// KeyringPath config'deki keyring dosyasının yolunu döner (varsayılan: ./keyring/keyring.json).
+func KeyringPath() string {
+	return keyringPath(config.Get())
+}
+
+func keyringPath(cfg *config.Config) string {
+	if cfg.KettasLog.Keys.KeyringFile != "" {
+		return cfg.KettasLog.Keys.KeyringFile
+	}
+	return config.DefaultKeyringFile
+}
+
+// LoadMasterKey master key'i master_key_file'dan, yoksa master_key_env ortam değişkeninden okur.
+func LoadMasterKey() ([]byte, error) {
+	cfg := config.Get().KettasLog.Keys
+	if cfg.MasterKeyFile != "" {
+		data, err := os.ReadFile(cfg.MasterKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("master key dosyası okunamadı: %w", err)
+		}
+		return ParseMasterKey(string(data))
+	}
+
+	envName := cfg.MasterKeyEnv
+	if envName == "" {
+		envName = defaultEnvName
+	}
+	value := os.Getenv(envName)
+	if value == "" {
+		return nil, fmt.Errorf("master key bulunamadı: keys.master_key_file veya %s ortam değişkeni gerekli", envName)
+	}
+	return ParseMasterKey(value)
+}
+
+// ParseMasterKey base64 veya hex kodlu 32 byte anahtarı çözer.
+func ParseMasterKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(s); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("master key 32 byte olmalı (base64 veya hex)")
+}
+
+// GenerateMasterKey yeni bir master key üretir ve base64 olarak döner.
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// MasterKeyID master key'in parmak izini döner; anahtarın kendisini açığa çıkarmaz.
+func MasterKeyID(master []byte) string {
+	sum := sha256.Sum256(master)
+	return "mk_" + hex.EncodeToString(sum[:8])
+}
+
+// Open keyring dosyasını yükler; dosya yoksa boş keyring ile başlar.
+func Open(path string, master []byte) (*Keyring, error) {
+	k := &Keyring{
+		path:     path,
+		master:   master,
+		masterId: MasterKeyID(master),
+		cache:    make(map[string][]byte),
+	}
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// refresh keyring dosyası son okumadan beri değiştiyse (başka bir instance veya logctl)
+// yeniden okur. mu tutulurken çağrılmalıdır.
+func (k *Keyring) refresh() error {
+	info, err := os.Stat(k.path)
+	if err != nil {
+		if os.IsNotExist(err) && k.stamp == (fileStamp{}) {
+			return nil
+		}
+		return k.reload()
+	}
+	if stampOf(info).equal(k.stamp) {
+		return nil
+	}
+	return k.reload()
+}
+
+// reload keyring dosyasını diskten tekrar okur ve keyring'de artık olmayan (ör: başka bir
+// instance'ta silinen) anahtarları cache'ten çıkarır. mu tutulurken çağrılmalıdır.
+func (k *Keyring) reload() error {
+	// Stat okumadan önce alınır; arada dosya değişirse bir sonraki refresh yeniden okur
+	var stamp fileStamp
+	if info, err := os.Stat(k.path); err == nil {
+		stamp = stampOf(info)
+	}
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			k.file = keyringFile{Version: keyringVersion, MasterKeyID: k.masterId, Homes: map[string]*homeKeys{}}
+			k.stamp = fileStamp{}
+			k.pruneCache()
+			return nil
+		}
+		return fmt.Errorf("keyring okunamadı: %w", err)
+	}
+
+	var f keyringFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("keyring çözülemedi: %w", err)
+	}
+	if f.MasterKeyID == "" {
+		f.MasterKeyID = k.masterId
+	}
+	if f.MasterKeyID != k.masterId {
+		return fmt.Errorf("%w (keyring: %s, verilen: %s)", ErrMasterMismatch, f.MasterKeyID, k.masterId)
+	}
+	if f.Homes == nil {
+		f.Homes = map[string]*homeKeys{}
+	}
+	k.file = f
+	k.stamp = stamp
+	k.pruneCache()
+	return nil
+}
+
+// pruneCache keyring'de olmayan anahtarları cache'ten silip belleklerini sıfırlar.
+func (k *Keyring) pruneCache() {
+	for id, key := range k.cache {
+		if _, ok := k.homeOfLocked(id); !ok {
+			clear(key)
+			delete(k.cache, id)
+		}
+	}
+}
+
+// update keyring'i diğer instance'larla kilitleyerek diskten yeniden okur, fn ile değiştirir
+// ve atomik olarak kaydeder. fn bir kopya üzerinde çalışır; kopya yalnızca diske yazıldıktan
+// sonra kullanılmaya başlanır, yazılamazsa bellekteki keyring diskteki haliyle kalır (ör:
+// kaydedilemeyen bir veri anahtarıyla arşiv şifrelenmez). mu tutulurken çağrılmalıdır.
+func (k *Keyring) update(fn func(f *keyringFile) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	release, err := lock.Get().Lock(ctx, keyringLockName)
+	if err != nil {
+		return fmt.Errorf("keyring kilidi alınamadı: %w", err)
+	}
+	defer release()
+
+	if err := k.reload(); err != nil {
+		return err
+	}
+	f := k.file.clone()
+	err = fn(&f)
+	if err == nil {
+		f.Version = keyringVersion
+		f.UpdatedAt = time.Now()
+		err = k.save(f)
+	}
+	if err != nil {
+		// fn'in cache'e eklediği, kaydedilmemiş anahtarlar atılır
+		k.pruneCache()
+		return err
+	}
+	k.file = f
+	return nil
+}
+
+// clone keyring içeriğinin derin kopyasını döner.
+func (f keyringFile) clone() keyringFile {
+	c := f
+	c.Homes = make(map[string]*homeKeys, len(f.Homes))
+	for homeIdDir, hk := range f.Homes {
+		c.Homes[homeIdDir] = &homeKeys{Active: hk.Active, Keys: slices.Clone(hk.Keys)}
+	}
+	return c
+}
+
+// save keyring'i geçici dosyaya yazıp fsync ettikten sonra yerine taşır.
+func (k *Keyring) save(file keyringFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return fmt.Errorf("keyring dizini oluşturulamadı: %w", err)
+	}
+
+	tmpPath := k.path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("keyring yazılamadı: %w", err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("keyring yazılamadı: %w", err)
+	}
+	if err := os.Rename(tmpPath, k.path); err != nil {
+		return fmt.Errorf("keyring yazılamadı: %w", err)
+	}
+	if info, err := os.Stat(k.path); err == nil {
+		k.stamp = stampOf(info)
+	}
+	return nil
+}
+
+// ActiveKey evin yeni arşivlerde kullanılacak veri anahtarını döner; ev için anahtar yoksa oluşturur.
+// Keyring dosyası başka bir instance tarafından değiştirildiyse (ör: rotate-home) önce yeniden okunur.
+// Dönen anahtar çağıranındır; keyring'in cache'iyle paylaşılmaz.
+func (k *Keyring) ActiveKey(homeIdDir string) (string, []byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.refresh(); err != nil {
+		return "", nil, err
+	}
+	if hk := k.file.Homes[homeIdDir]; hk != nil && hk.Active != "" {
+		key, err := k.unwrapLocked(homeIdDir, hk.Active)
+		return hk.Active, key, err
+	}
+
+	var id string
+	err := k.update(func(f *keyringFile) error {
+		// Başka bir instance bu arada oluşturmuş olabilir
+		if hk := f.Homes[homeIdDir]; hk != nil && hk.Active != "" {
+			id = hk.Active
+			return nil
+		}
+		wk, err := k.newWrappedKey(homeIdDir)
+		if err != nil {
+			return err
+		}
+		if f.Homes[homeIdDir] == nil {
+			f.Homes[homeIdDir] = &homeKeys{}
+		}
+		f.Homes[homeIdDir].Keys = append(f.Homes[homeIdDir].Keys, wk)
+		f.Homes[homeIdDir].Active = wk.ID
+		id = wk.ID
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	slog.Info("Ev için yeni veri anahtarı oluşturuldu", "home_id_dir", homeIdDir, "key_id", id)
+	key, err := k.unwrapLocked(homeIdDir, id)
+	return id, key, err
+}
+
+// Key anahtar id'sine göre veri anahtarını döner (arşiv okurken). Başka bir instance'ta
+// eklenen veya silinen anahtarlar için keyring dosyası değiştiyse önce yeniden okunur.
+func (k *Keyring) Key(id string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.refresh(); err != nil {
+		return nil, err
+	}
+	homeIdDir, ok := k.homeOfLocked(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	return k.unwrapLocked(homeIdDir, id)
+}
+
+func (k *Keyring) homeOfLocked(id string) (string, bool) {
+	for homeIdDir, hk := range k.file.Homes {
+		for _, wk := range hk.Keys {
+			if wk.ID == id {
+				return homeIdDir, true
+			}
+		}
+	}
+	return "", false
+}
+
+// RotateHomeKey ev için yeni bir aktif veri anahtarı oluşturur. Eski anahtarlar
+// önceki arşivleri okuyabilmek için keyring'de kalır.
+func (k *Keyring) RotateHomeKey(homeIdDir string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var id string
+	err := k.update(func(f *keyringFile) error {
+		wk, err := k.newWrappedKey(homeIdDir)
+		if err != nil {
+			return err
+		}
+		if f.Homes[homeIdDir] == nil {
+			f.Homes[homeIdDir] = &homeKeys{}
+		}
+		f.Homes[homeIdDir].Keys = append(f.Homes[homeIdDir].Keys, wk)
+		f.Homes[homeIdDir].Active = wk.ID
+		id = wk.ID
+		return nil
+	})
+	return id, err
+}
+
+// DestroyHome evin tüm veri anahtarlarını keyring'den kalıcı olarak siler (crypto-shredding).
+// Bu anahtarlarla şifrelenmiş arşivlerin kopyaları (ör: harici yedekler) artık açılamaz;
+// ancak keyring'in yedeklerinde anahtarlar saklama süreleri dolana kadar kalır.
+// Silinen anahtar id'lerini döner; ev için anahtar yoksa boş döner.
+func (k *Keyring) DestroyHome(homeIdDir string) ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var destroyed []string
+	err := k.update(func(f *keyringFile) error {
+		hk := f.Homes[homeIdDir]
+		if hk == nil {
+			return nil
+		}
+		for _, wk := range hk.Keys {
+			destroyed = append(destroyed, wk.ID)
+		}
+		delete(f.Homes, homeIdDir)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache'teki anahtarlar yalnızca keyring'indir (dışarıya kopyaları verilir), sıfırlanabilir
+	for _, id := range destroyed {
+		if key, ok := k.cache[id]; ok {
+			clear(key)
+			delete(k.cache, id)
+		}
+	}
+	return destroyed, nil
+}
+
+// Rewrap tüm veri anahtarlarını mevcut master key ile çözüp yeni master key ile sarar ve
+// keyring'i kaydeder. Arşivler değişmez. Sonrasında sunucular yeni master key ile başlatılmalıdır.
+// Yeniden sarılan anahtar sayısını döner.
+func (k *Keyring) Rewrap(newMaster []byte) (int, error) {
+	if len(newMaster) != keySize {
+		return 0, fmt.Errorf("yeni master key 32 byte olmalı")
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	count := 0
+	newId := MasterKeyID(newMaster)
+	err := k.update(func(f *keyringFile) error {
+		// Önce tüm anahtarları çöz; biri bile çözülemezse hiçbir şey yazılmaz
+		rewrapped := make(map[string][]wrappedKey, len(f.Homes))
+		for homeIdDir, hk := range f.Homes {
+			for _, wk := range hk.Keys {
+				key, err := unwrap(k.master, homeIdDir, wk)
+				if err != nil {
+					return fmt.Errorf("%s/%s çözülemedi: %w", homeIdDir, wk.ID, err)
+				}
+				wrapped, err := wrap(newMaster, homeIdDir, wk.ID, key)
+				if err != nil {
+					return err
+				}
+				wk.Wrapped = wrapped
+				wk.MasterKeyID = newId
+				rewrapped[homeIdDir] = append(rewrapped[homeIdDir], wk)
+				count++
+			}
+		}
+		for homeIdDir, wks := range rewrapped {
+			f.Homes[homeIdDir].Keys = wks
+		}
+		f.MasterKeyID = newId
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	k.master = newMaster
+	k.masterId = newId
+	k.cache = make(map[string][]byte)
+	return count, nil
+}
+
+// List evlerin anahtar özetlerini döner.
+func (k *Keyring) List() []HomeKeyInfo {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	infos := make([]HomeKeyInfo, 0, len(k.file.Homes))
+	for homeIdDir, hk := range k.file.Homes {
+		info := HomeKeyInfo{HomeIdDir: homeIdDir, Active: hk.Active, KeyIds: []string{}}
+		for _, wk := range hk.Keys {
+			info.KeyIds = append(info.KeyIds, wk.ID)
+			if wk.ID == hk.Active {
+				info.CreatedAt = wk.CreatedAt
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].HomeIdDir < infos[j].HomeIdDir })
+	return infos
+}
+
+// Path keyring dosyasının yolu.
+func (k *Keyring) Path() string {
+	return k.path
+}
+
+// MasterKeyID keyring'in kullandığı master key'in parmak izi.
+func (k *Keyring) MasterKeyID() string {
+	return k.masterId
+}
+
+func (k *Keyring) newWrappedKey(homeIdDir string) (wrappedKey, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return wrappedKey{}, err
+	}
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return wrappedKey{}, err
+	}
+	id := "dk_" + hex.EncodeToString(idBytes)
+
+	wrapped, err := wrap(k.master, homeIdDir, id, key)
+	if err != nil {
+		return wrappedKey{}, err
+	}
+	k.cache[id] = key
+	return wrappedKey{ID: id, MasterKeyID: k.masterId, Wrapped: wrapped, CreatedAt: time.Now()}, nil
+}
+
+// unwrapLocked anahtarı cache'ten veya çözerek döner. Dönen slice cache'le paylaşılmaz:
+// DestroyHome cache'i sıfırlarken çağıranların elindeki anahtarlar bozulmamalıdır.
+func (k *Keyring) unwrapLocked(homeIdDir, id string) ([]byte, error) {
+	if key, ok := k.cache[id]; ok {
+		return bytes.Clone(key), nil
+	}
+	hk := k.file.Homes[homeIdDir]
+	if hk == nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	for _, wk := range hk.Keys {
+		if wk.ID == id {
+			key, err := unwrap(k.master, homeIdDir, wk)
+			if err != nil {
+				return nil, err
+			}
+			k.cache[id] = key
+			return bytes.Clone(key), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+}
+
+// wrapAAD sarılmış anahtarın başka bir eve veya id'ye taşınmasını engeller.
+func wrapAAD(homeIdDir, id string) []byte {
+	return []byte(homeIdDir + "/" + id)
+}
+
+func wrap(master []byte, homeIdDir, id string, key []byte) (string, error) {
+	aead, err := newAEAD(master)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, key, wrapAAD(homeIdDir, id))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func unwrap(master []byte, homeIdDir string, wk wrappedKey) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(wk.Wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("sarılmış anahtar çözülemedi: %w", err)
+	}
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	ns := aead.NonceSize()
+	if len(data) < ns {
+		return nil, fmt.Errorf("sarılmış anahtar bozuk")
+	}
+	key, err := aead.Open(nil, data[:ns], data[ns:], wrapAAD(homeIdDir, wk.ID))
+	if err != nil {
+		return nil, fmt.Errorf("anahtar açılamadı (yanlış master key?): %w", err)
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,301 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log-server/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSameTree(t *testing.T) {
+	root := t.TempDir()
+	backups := filepath.Join(root, "backups")
+	if err := os.MkdirAll(backups, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(backups, link); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		keyring string
+		want    bool
+	}{
+		{"aynı dizin", backups, true},
+		{"alt dizin", filepath.Join(backups, "keys"), true},
+		{"üst dizin", root, true},
+		{"göreli yol", filepath.Join(backups, "..", "backups", "x"), true},
+		{"sembolik link", filepath.Join(link, "keys"), true},
+		{"kardeş dizin", filepath.Join(root, "keyring"), false},
+		{"benzer ön ek", filepath.Join(root, "backups-keys"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameTree(tt.keyring, backups); got != tt.want {
+				t.Errorf("sameTree(%q) = %v, %v bekleniyordu", tt.keyring, got, tt.want)
+			}
+		})
+	}
+}
+
+func testMaster(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keySize)
+}
+
+func TestParseMasterKey(t *testing.T) {
+	key := testMaster(1)
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{"base64", base64.StdEncoding.EncodeToString(key), false},
+		{"hex", hex.EncodeToString(key), false},
+		{"boşluklu", "  " + base64.StdEncoding.EncodeToString(key) + "\n", false},
+		{"kısa", base64.StdEncoding.EncodeToString(key[:16]), true},
+		{"geçersiz", "not-a-key", true},
+		{"boş", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMasterKey(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("hata = %v, hata bekleniyor = %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, key) {
+				t.Errorf("anahtar = %x", got)
+			}
+		})
+	}
+}
+
+func openTestKeyring(t *testing.T, path string, master []byte) *Keyring {
+	t.Helper()
+	k, err := Open(path, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// TestRotateDestroy iki instance'ın aynı keyring dosyasını paylaştığı durumda anahtar
+// döndürme ve silmenin diğer instance'a yansıdığını doğrular.
+func TestRotateDestroy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	a := openTestKeyring(t, path, testMaster(1))
+	b := openTestKeyring(t, path, testMaster(1))
+	const home = "home_id_a"
+
+	firstId, firstKey, err := a.ActiveKey(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := bytes.Clone(firstKey)
+
+	// b'nin anahtarı a'nınkiyle aynı olmalı
+	if id, key, err := b.ActiveKey(home); err != nil || id != firstId || !bytes.Equal(key, held) {
+		t.Fatalf("b.ActiveKey = %s, %v; %s bekleniyordu", id, err, firstId)
+	}
+
+	rotatedId, err := b.RotateHomeKey(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name    string
+		run     func() (string, error)
+		want    string
+		wantErr error
+	}{
+		{"a döndürülen anahtarı görür", func() (string, error) {
+			id, _, err := a.ActiveKey(home)
+			return id, err
+		}, rotatedId, nil},
+		{"eski anahtar okunabilir", func() (string, error) {
+			key, err := a.Key(firstId)
+			if err == nil && !bytes.Equal(key, held) {
+				return "", fmt.Errorf("anahtar değişmiş")
+			}
+			return firstId, err
+		}, firstId, nil},
+		{"silme", func() (string, error) {
+			ids, err := b.DestroyHome(home)
+			return fmt.Sprint(len(ids)), err
+		}, "2", nil},
+		{"a silinen anahtarı okuyamaz", func() (string, error) {
+			_, err := a.Key(firstId)
+			return "", err
+		}, "", ErrKeyNotFound},
+		{"a yeni anahtar oluşturur", func() (string, error) {
+			id, _, err := a.ActiveKey(home)
+			if err == nil && (id == firstId || id == rotatedId) {
+				return "", fmt.Errorf("silinen anahtar döndü: %s", id)
+			}
+			return "yeni", err
+		}, "yeni", nil},
+	}
+	for _, s := range steps {
+		got, err := s.run()
+		if s.wantErr != nil {
+			if !errors.Is(err, s.wantErr) {
+				t.Fatalf("%s: hata = %v, %v bekleniyordu", s.name, err, s.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != s.want {
+			t.Fatalf("%s: %q, %v; %q bekleniyordu", s.name, got, err, s.want)
+		}
+	}
+
+	// Silme çağıranlara verilmiş anahtarları sıfırlamamalı
+	if !bytes.Equal(firstKey, held) {
+		t.Error("DestroyHome çağıranın elindeki anahtarı değiştirdi")
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	k := openTestKeyring(t, path, testMaster(1))
+	id, key, err := k.ActiveKey("home_id_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := k.Rewrap(testMaster(2)); err != nil || n != 1 {
+		t.Fatalf("Rewrap = %d, %v", n, err)
+	}
+
+	tests := []struct {
+		name    string
+		master  []byte
+		wantErr error
+	}{
+		{"yeni master", testMaster(2), nil},
+		{"eski master", testMaster(1), ErrMasterMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := Open(path, tt.master)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("hata = %v, %v bekleniyordu", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := k.Key(id)
+			if err != nil || !bytes.Equal(got, key) {
+				t.Fatalf("Key = %v; anahtar değişmemeliydi", err)
+			}
+		})
+	}
+}
+
+// TestFailedSave keyring diske yazılamadığında bellekteki keyring'in değişmediğini doğrular.
+func TestFailedSave(t *testing.T) {
+	const home = "home_id_a"
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	k := openTestKeyring(t, path, testMaster(1))
+	activeId, activeKey, err := k.ActiveKey(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Geçici dosyanın yerinde bir dizin varsa save başarısız olur (root için de)
+	blocker := path + ".tmp"
+	if err := os.Mkdir(blocker, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"yeni ev", func() error {
+			_, _, err := k.ActiveKey("home_id_b")
+			return err
+		}},
+		{"döndürme", func() error {
+			_, err := k.RotateHomeKey(home)
+			return err
+		}},
+		{"silme", func() error {
+			_, err := k.DestroyHome(home)
+			return err
+		}},
+		{"master key değişimi", func() error {
+			_, err := k.Rewrap(testMaster(2))
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err == nil {
+				t.Fatal("yazılamayan keyring'e kaydetme başarılı döndü")
+			}
+			if infos := k.List(); len(infos) != 1 || infos[0].HomeIdDir != home || len(infos[0].KeyIds) != 1 {
+				t.Errorf("bellekteki keyring değişti: %+v", infos)
+			}
+			if k.MasterKeyID() != MasterKeyID(testMaster(1)) {
+				t.Errorf("master key id = %s", k.MasterKeyID())
+			}
+			if id, key, err := k.ActiveKey(home); err != nil || id != activeId || !bytes.Equal(key, activeKey) {
+				t.Errorf("ActiveKey = %s, %v; %s bekleniyordu", id, err, activeId)
+			}
+		})
+	}
+
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	id, _, err := k.ActiveKey("home_id_b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openTestKeyring(t, path, testMaster(1)).Key(id); err != nil {
+		t.Errorf("kaydedilen anahtar diskte yok: %v", err)
+	}
+}
+
+func TestValidateKeyring(t *testing.T) {
+	root := t.TempDir()
+	backups := filepath.Join(root, "backups")
+	if err := os.MkdirAll(backups, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	// Önceki sürümlerin varsayılan konumunda kalmış bir dosya yapılandırmayı engellemez
+	if err := os.WriteFile(filepath.Join(backups, "keyring.json"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		enabled     bool
+		keyringFile string
+		wantProblem bool
+	}{
+		{"kapalı", false, filepath.Join(backups, "keyring.json"), false},
+		{"ayrı dizin", true, filepath.Join(root, "keyring", "keyring.json"), false},
+		{"varsayılan konum", true, "", false},
+		{"backup_dir içinde", true, filepath.Join(backups, "keyring.json"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.KettasLog.Backup.BackupDir = backups
+			cfg.KettasLog.Keys.Enabled = tt.enabled
+			cfg.KettasLog.Keys.KeyringFile = tt.keyringFile
+			if problems := validateKeyring(cfg); (len(problems) > 0) != tt.wantProblem {
+				t.Errorf("validateKeyring = %v, sorun bekleniyor = %v", problems, tt.wantProblem)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package lock
+
+// fileLocker flock olmayan platformlarda yalnızca process içi kilit sağlar
+// (layered'daki yerel kilit yeterlidir).
+type fileLocker struct {
+	dir string
+}
+
+func (f *fileLocker) TryLock(string) (func(), bool, error) {
+	return func() {}, true, nil
+}
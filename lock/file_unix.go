@@ -0,0 +1,47 @@
+//go:build unix
+
+package lock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// fileLocker paylaşılan diskte flock ile kilitleme yapar.
+// Kilit, dosya tanımlayıcısı kapanınca (process çökse bile) çekirdek tarafından bırakılır.
+type fileLocker struct {
+	dir string
+}
+
+func (f *fileLocker) TryLock(name string) (func(), bool, error) {
+	path := filepath.Join(f.dir, lockFileName(name))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, false, fmt.Errorf("kilit dosyası açılamadı: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("flock hatası: %w", err)
+	}
+
+	// Kilidin kimde olduğunu görmek için sahip bilgisini yaz (sadece bilgi amaçlı)
+	file.Truncate(0)
+	file.WriteAt([]byte(instanceId()+"\n"), 0)
+
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, true, nil
+}
+
+// lockFileName "home:home_id_xxx" → "home_home_id_xxx.lock"
+func lockFileName(name string) string {
+	return strings.NewReplacer(":", "_", "/", "_", string(os.PathSeparator), "_").Replace(name) + ".lock"
+}
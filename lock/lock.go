@@ -0,0 +1,234 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-server/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Locker isimli kilitler sağlar. Aynı isim için aynı anda yalnızca bir sahip olabilir;
+// backend'e göre bu garanti tek process (none), paylaşılan disk (file) veya
+// aynı MongoDB'yi kullanan tüm instance'lar (mongo) için geçerlidir.
+type Locker interface {
+	// TryLock beklemeden kilidi almayı dener. ok=false ise kilit başkasındadır.
+	TryLock(name string) (release func(), ok bool, err error)
+	// TryLockContext TryLock gibidir; ek olarak kilit tutulduğu sürece geçerli bir context döner.
+	// Context, kilit kaybedildiğinde (ör: mongo lease'i yenilenemeyip başka instance'a geçtiğinde;
+	// context.Cause ErrLost olur), parent bittiğinde veya kilit bırakıldığında iptal edilir.
+	// Kilit altında uzun süren işler bu context'i izleyip kaybedilince durmalıdır.
+	TryLockContext(parent context.Context, name string) (held context.Context, release func(), ok bool, err error)
+	// Lock kilit alınana veya ctx bitene kadar bekler.
+	Lock(ctx context.Context, name string) (release func(), err error)
+}
+
+var (
+	// ErrTimeout kilit beklenen süre içinde alınamadığında döner.
+	ErrTimeout = errors.New("kilit zaman aşımı")
+	// ErrLost kilit tutulurken kaybedildiğinde TryLockContext'in context'inin iptal nedenidir.
+	ErrLost = errors.New("kilit kaybedildi")
+)
+
+const pollInterval = 100 * time.Millisecond
+
+var (
+	current Locker = newLayered(noopLocker{})
+	mu      sync.RWMutex
+)
+
+// Init config'deki cluster.lock_backend'e göre kilit sağlayıcısını oluşturur.
+// mongo backend'i için db.Connect'ten sonra çağrılmalıdır.
+func Init() error {
+	cfg := config.Get()
+
+	var remote tryLocker
+	switch cfg.Cluster.LockBackend {
+	case "", "file":
+		dir := LockDir()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("kilit dizini oluşturulamadı: %w", err)
+		}
+		remote = &fileLocker{dir: dir}
+	case "mongo":
+		if !cfg.DB.Enabled {
+			return fmt.Errorf("cluster.lock_backend=mongo için db.enabled gerekli")
+		}
+		ml, err := newMongoLocker(instanceId(), leaseTTL())
+		if err != nil {
+			return err
+		}
+		remote = ml
+	case "none":
+		remote = noopLocker{}
+	default:
+		return fmt.Errorf("bilinmeyen cluster.lock_backend: %s", cfg.Cluster.LockBackend)
+	}
+
+	mu.Lock()
+	current = newLayered(remote)
+	mu.Unlock()
+
+	slog.Info("Kilit sağlayıcısı hazır", "backend", cfg.Cluster.LockBackend, "instance_id", instanceId())
+	return nil
+}
+
+// Get aktif kilit sağlayıcısını döner.
+func Get() Locker {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// LockDir file backend'inin kilit dosyalarını tuttuğu dizin.
+func LockDir() string {
+	cfg := config.Get()
+	if cfg.Cluster.LockDir != "" {
+		return cfg.Cluster.LockDir
+	}
+	return filepath.Join(cfg.KettasLog.Backup.BackupDir, ".locks")
+}
+
+// JobKey bir iş grubunun kilit adı.
+func JobKey(group string) string {
+	return "job:" + group
+}
+
+// HomeKey bir home_id klasörünün kilit adı (ör: home_id_xxx).
+// Upload ve arşivleme aynı klasöre aynı anda dokunmasın diye kullanılır.
+func HomeKey(homeIdDir string) string {
+	return "home:" + homeIdDir
+}
+
+// HomeLockTimeout upload'ın home kilidini bekleyeceği süre.
+func HomeLockTimeout() time.Duration {
+	if sec := config.Get().Cluster.HomeLockTimeoutSec; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return 30 * time.Second
+}
+
+func leaseTTL() time.Duration {
+	if sec := config.Get().Cluster.LeaseTTLSec; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return 60 * time.Second
+}
+
+func instanceId() string {
+	if id := config.Get().Cluster.InstanceId; id != "" {
+		return id
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// layered önce process içi kilidi, ardından paylaşılan (remote) kilidi alır.
+// Böylece aynı process içindeki iki goroutine de birbirini dışlar;
+// mongo lease'i gibi sahip bazlı kilitler tek başına bunu sağlamaz.
+type layered struct {
+	localMu sync.Mutex
+	held    map[string]bool
+	remote  tryLocker
+}
+
+// tryLocker paylaşılan kilit backend'lerinin uygulaması gereken arayüz.
+type tryLocker interface {
+	TryLock(name string) (release func(), ok bool, err error)
+}
+
+// leaseLocker süresi dolabilen (lease) kilitler sağlayan backend'lerin arayüzü. lost, kilit
+// bırakılmadan kaybedildiğinde en fazla bir kez çağrılır.
+type leaseLocker interface {
+	TryLockLease(name string, lost func()) (release func(), ok bool, err error)
+}
+
+func newLayered(remote tryLocker) *layered {
+	return &layered{held: make(map[string]bool), remote: remote}
+}
+
+func (l *layered) tryLocal(name string) bool {
+	l.localMu.Lock()
+	defer l.localMu.Unlock()
+	if l.held[name] {
+		return false
+	}
+	l.held[name] = true
+	return true
+}
+
+func (l *layered) releaseLocal(name string) {
+	l.localMu.Lock()
+	delete(l.held, name)
+	l.localMu.Unlock()
+}
+
+func (l *layered) TryLock(name string) (func(), bool, error) {
+	return l.tryLock(name, nil)
+}
+
+func (l *layered) TryLockContext(parent context.Context, name string) (context.Context, func(), bool, error) {
+	ctx, cancel := context.WithCancelCause(parent)
+	release, ok, err := l.tryLock(name, func() {
+		slog.Error("Kilit kaybedildi, kilit altındaki iş durduruluyor", "lock", name)
+		cancel(ErrLost)
+	})
+	if err != nil || !ok {
+		cancel(nil)
+		return nil, nil, ok, err
+	}
+	return ctx, func() {
+		release()
+		cancel(nil)
+	}, true, nil
+}
+
+func (l *layered) tryLock(name string, lost func()) (func(), bool, error) {
+	if !l.tryLocal(name) {
+		return nil, false, nil
+	}
+	var release func()
+	var ok bool
+	var err error
+	if ll, isLease := l.remote.(leaseLocker); isLease && lost != nil {
+		release, ok, err = ll.TryLockLease(name, lost)
+	} else {
+		release, ok, err = l.remote.TryLock(name)
+	}
+	if err != nil || !ok {
+		l.releaseLocal(name)
+		return nil, ok, err
+	}
+	return func() {
+		release()
+		l.releaseLocal(name)
+	}, true, nil
+}
+
+func (l *layered) Lock(ctx context.Context, name string) (func(), error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		release, ok, err := l.TryLock(name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return release, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %s", ErrTimeout, name)
+		case <-ticker.C:
+		}
+	}
+}
+
+// noopLocker paylaşılan kilit kullanmaz (tek instance kurulumları için).
+type noopLocker struct{}
+
+func (noopLocker) TryLock(string) (func(), bool, error) { return func() {}, true, nil }
@@ -0,0 +1,98 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeLease lost callback'ini testin tetiklemesi için saklayan bir lease backend'i.
+type fakeLease struct {
+	lost     func()
+	released bool
+}
+
+func (f *fakeLease) TryLock(name string) (func(), bool, error) {
+	return f.TryLockLease(name, nil)
+}
+
+func (f *fakeLease) TryLockLease(_ string, lost func()) (func(), bool, error) {
+	f.lost = lost
+	return func() { f.released = true }, true, nil
+}
+
+func TestTryLockContext(t *testing.T) {
+	tests := []struct {
+		name      string
+		end       func(f *fakeLease, cancelParent context.CancelFunc, release func())
+		wantCause error
+	}{
+		{"kilit kaybedildi", func(f *fakeLease, _ context.CancelFunc, _ func()) { f.lost() }, ErrLost},
+		{"bırakıldı", func(_ *fakeLease, _ context.CancelFunc, release func()) { release() }, context.Canceled},
+		{"parent iptal", func(_ *fakeLease, cancel context.CancelFunc, _ func()) { cancel() }, context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeLease{}
+			l := newLayered(f)
+			parent, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ctx, release, ok, err := l.TryLockContext(parent, "job:test")
+			if err != nil || !ok {
+				t.Fatalf("TryLockContext = %v, %v", ok, err)
+			}
+			if ctx.Err() != nil {
+				t.Fatal("context kilit alınır alınmaz iptal edildi")
+			}
+			if f.lost == nil {
+				t.Fatal("lease backend'ine lost callback'i verilmedi")
+			}
+
+			tt.end(f, cancel, release)
+			<-ctx.Done()
+			if cause := context.Cause(ctx); !errors.Is(cause, tt.wantCause) {
+				t.Errorf("context.Cause = %v, %v bekleniyordu", cause, tt.wantCause)
+			}
+			release()
+			if !f.released {
+				t.Error("backend kilidi bırakılmadı")
+			}
+		})
+	}
+}
+
+func TestLayeredExcludesLocally(t *testing.T) {
+	l := newLayered(noopLocker{})
+	release, ok, err := l.TryLock("home:a")
+	if err != nil || !ok {
+		t.Fatalf("ilk TryLock = %v, %v", ok, err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want bool
+	}{
+		{"aynı kilit", "home:a", false},
+		{"farklı kilit", "home:b", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok, err := l.TryLock(tt.key)
+			if err != nil || ok != tt.want {
+				t.Fatalf("TryLock(%s) = %v, %v; %v bekleniyordu", tt.key, ok, err, tt.want)
+			}
+			if ok {
+				r()
+			}
+		})
+	}
+
+	release()
+	if r, ok, _ := l.TryLock("home:a"); !ok {
+		t.Error("bırakılan kilit tekrar alınamadı")
+	} else {
+		r()
+	}
+}
@@ -0,0 +1,152 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"log-server/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const lockCollectionName = "locks"
+
+// mongoLocker MongoDB'de süreli kiralama (lease) ile kilitleme yapar.
+// Her kilit bir dokümandır: { _id: name, owner, token, expires_at }.
+// Kilit tutulduğu sürece arka planda yenilenir; instance çökerse lease süresi dolunca
+// başka bir instance kilidi devralabilir. Her alım rastgele bir token ile yapılır ve yenileme
+// ile bırakma bu token'a göre eşleşir; owner (instance_id) yalnızca bilgi amaçlıdır. Böylece
+// aynı instance_id ile çalışan iki instance birbirinin kilidini alamaz, yenileyemez veya bırakamaz.
+type mongoLocker struct {
+	collection *mongo.Collection
+	owner      string
+	ttl        time.Duration
+}
+
+func newMongoLocker(owner string, ttl time.Duration) (*mongoLocker, error) {
+	collection := db.GetDatabaseCollection(lockCollectionName)
+	if collection == nil {
+		return nil, fmt.Errorf("MongoDB bağlantısı yok, mongo kilidi kullanılamaz")
+	}
+	return &mongoLocker{collection: collection, owner: owner, ttl: ttl}, nil
+}
+
+func (m *mongoLocker) TryLock(name string) (func(), bool, error) {
+	return m.TryLockLease(name, nil)
+}
+
+// TryLockLease kilidi alır ve bırakılana kadar yeniler. Lease yenilenemeden süresi dolarsa
+// veya başka bir sahibe geçmişse lost çağrılır ve yenileme durur.
+func (m *mongoLocker) TryLockLease(name string, lost func()) (func(), bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token, err := newLeaseToken()
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := m.acquire(ctx, name, token)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go m.renew(name, token, lost, stop, done)
+
+	return func() {
+		close(stop)
+		<-done
+		m.release(name, token)
+	}, true, nil
+}
+
+func newLeaseToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// acquire lease süresi dolmuş kilidi günceller, yoksa yeni doküman ekler. Kilit başka bir
+// sahipteyse (aynı instance_id'ye sahip olsa bile) filtre eşleşmez, upsert duplicate key hatası
+// verir → kilit alınamadı.
+func (m *mongoLocker) acquire(ctx context.Context, name, token string) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":        name,
+		"expires_at": bson.M{"$lt": now},
+	}
+	update := bson.M{"$set": bson.M{
+		"owner":       m.owner,
+		"token":       token,
+		"acquired_at": now,
+		"expires_at":  now.Add(m.ttl),
+	}}
+
+	_, err := m.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("mongo kilit hatası: %w", err)
+	}
+	return true, nil
+}
+
+// renew kilit bırakılana kadar lease'i ttl/3 aralıklarla uzatır. Doküman artık bu alıma ait
+// değilse veya lease bir sonraki denemeden önce dolacaksa (başka bir instance devralabilir)
+// kilit kaybedilmiş sayılır: lost çağrılır ve yenileme durur. Böylece tutan taraf lease'in
+// dolmasından en az ttl/3 önce haberdar olur.
+func (m *mongoLocker) renew(name, token string, lost func(), stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(m.ttl / 3)
+	defer ticker.Stop()
+
+	renewedAt := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			now := time.Now()
+			res, err := m.collection.UpdateOne(ctx,
+				bson.M{"_id": name, "token": token},
+				bson.M{"$set": bson.M{"expires_at": now.Add(m.ttl)}},
+			)
+			cancel()
+			switch {
+			case err == nil && res.MatchedCount > 0:
+				renewedAt = now
+				continue
+			case err == nil:
+				slog.Error("Mongo kilidi kaybedildi (lease başka instance'a geçti)", "lock", name)
+			case time.Since(renewedAt)+m.ttl/3 < m.ttl:
+				slog.Error("Mongo kilit lease'i yenilenemedi", "lock", name, "error", err)
+				continue
+			default:
+				slog.Error("Mongo kilit lease'i süresi içinde yenilenemedi, kilit kaybedildi", "lock", name, "error", err)
+			}
+			if lost != nil {
+				lost()
+			}
+			return
+		}
+	}
+}
+
+func (m *mongoLocker) release(name, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := m.collection.DeleteOne(ctx, bson.M{"_id": name, "token": token}); err != nil {
+		slog.Error("Mongo kilidi bırakılamadı", "lock", name, "error", err)
+	}
+}
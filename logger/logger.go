@@ -13,6 +13,9 @@ import (
 
 var Log *slog.Logger
 
+// level config yeniden yüklendiğinde logger'ı yeniden oluşturmadan değiştirilir.
+var level = new(slog.LevelVar)
+
 func Init() {
 	cfg := config.Get()
 
@@ -30,11 +33,29 @@ func Init() {
 		Compress:   true,
 	}
 
+	level.Set(parseLevel(cfg.InternalLog.Level))
 	Log = slog.New(slog.NewJSONHandler(io.MultiWriter(os.Stdout, logRotator), &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: level,
 	}))
 
 	slog.SetDefault(Log)
+
+	config.OnChange(func(old, new *config.Config) {
+		if old.InternalLog.Level == new.InternalLog.Level {
+			return
+		}
+		level.Set(parseLevel(new.InternalLog.Level))
+		slog.Info("Log seviyesi değişti", "level", level.Level().String())
+	})
+}
+
+// parseLevel config'deki seviye adını slog seviyesine çevirir; bilinmeyen değerler info olur.
+func parseLevel(s string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
 }
 
 func Get() *slog.Logger {
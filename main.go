@@ -1,23 +1,52 @@
 package main
 
 import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
 	"os/signal"
 	"syscall"
 
 	"log/slog"
 	"os"
 
+	"log-server/auth"
 	"log-server/backup"
+	"log-server/certs"
 	"log-server/config"
 	"log-server/db"
+	"log-server/keys"
+	"log-server/lock"
 	"log-server/logger"
 	"log-server/router"
+	"log-server/scheduler"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func main() {
-	config.Load()
+	checkConfig := flag.Bool("check-config", false, "Config'i yükle, doğrula ve sunucuyu başlatmadan çık")
+	configPath := flag.String("config", "", "Config dosyası (varsayılan: $"+config.ConfigFileEnv+" veya ./config.yaml, ./config/config.yaml, /etc/log-server/config.yaml)")
+	flag.Func("set", "Config anahtarını ayarla, tekrar edilebilir (ör: -set kettas_log.backup.retention_days=30)", func(s string) error {
+		key, value, err := config.ParseOverride(s)
+		if err != nil {
+			return err
+		}
+		return config.SetOverride(key, value)
+	})
+	flag.Parse()
+
+	if *checkConfig {
+		if err := config.LoadFile(*configPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("Config geçerli")
+		return
+	}
+
+	config.Load(*configPath)
 	logger.Init()
 
 	cfg := config.Get()
@@ -33,6 +62,32 @@ func main() {
 		slog.Info("MongoDB bağlantısı atlandı (devredışı)")
 	}
 
+	// Çoklu instance için iş ve home_id kilitleri (file/mongo)
+	if err := lock.Init(); err != nil {
+		slog.Error("Kilit sağlayıcısı başlatılamadı", "error", err)
+		os.Exit(1)
+	}
+
+	// Scope'lu API anahtarları
+	if err := auth.Init(); err != nil {
+		slog.Error("API anahtarı deposu açılamadı", "error", err)
+		os.Exit(1)
+	}
+
+	// HTTPS ve istemci sertifikaları
+	if cfg.Server.TLS.Enabled {
+		if err := certs.Init(); err != nil {
+			slog.Error("TLS sertifikaları yüklenemedi", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Envelope encryption: master key ve ev veri anahtarları
+	if err := keys.Init(); err != nil {
+		slog.Error("Keyring başlatılamadı", "error", err)
+		os.Exit(1)
+	}
+
 	app := fiber.New(fiber.Config{
 		BodyLimit: int(cfg.KettasLog.MaxFileSizeMB*1024*1024 + 1024*1024),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -58,6 +113,9 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Önceki çalışmadan yarım kalan arşivlemeleri geri al veya tamamla
+	backup.RecoverArchives()
+
 	// Start Backup Manager
 	bm := backup.NewBackupManager()
 	bm.Start()
@@ -66,13 +124,29 @@ func main() {
 	dc := backup.NewDailyLogArchiver()
 	dc.Start()
 
+	// Kayıtlı işlerin zamanlayıcılarını başlat
+	scheduler.Get().Start()
+
+	// Config dosyasındaki değişiklikleri yeniden başlatmadan uygula
+	config.Watch()
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			slog.Info("SIGHUP alındı, config yeniden yükleniyor")
+			if err := config.Reload(); err != nil {
+				slog.Error("Config yeniden yüklenemedi, önceki config kullanılmaya devam ediliyor", "error", err)
+			}
+		}
+	}()
+
 	// Graceful Shutdown Chan
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		slog.Info("Server starting", "port", cfg.Server.Port)
-		if err := app.Listen(":" + cfg.Server.Port); err != nil {
+		slog.Info("Server starting", "port", cfg.Server.Port, "tls", cfg.Server.TLS.Enabled)
+		if err := listen(app, cfg); err != nil {
 			slog.Error("Server error", "error", err)
 		}
 	}()
@@ -85,11 +159,27 @@ func main() {
 		slog.Error("Server forced to shutdown", "error", err)
 	}
 
-	// 2. Backup Manager'ı durdur (Varsa süren işlemi bekle)
+	// 2. Scheduler'ı durdur (Süren işlerin bitmesini bekler)
+	scheduler.Get().Stop()
+
+	// 3. Backup Manager'ı durdur (Varsa süren işlemi bekle)
 	bm.Stop()
 
-	// 3. Daily Log Archiver'ı durdur
+	// 4. Daily Log Archiver'ı durdur
 	dc.Stop()
 
 	slog.Info("Server exited")
 }
+
+// listen sunucuyu düz HTTP veya server.tls.enabled ise HTTPS olarak başlatır.
+func listen(app *fiber.App, cfg *config.Config) error {
+	addr := ":" + cfg.Server.Port
+	if !cfg.Server.TLS.Enabled {
+		return app.Listen(addr)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return app.Listener(tls.NewListener(ln, certs.ServerTLS()))
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"log-server/audit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Audit kimliği doğrulanmış isteği denetim kaydına yazar. Yetki ve sınır kontrollerinden
+// önce eklenmelidir; böylece reddedilen denemeler de kaydedilir. Akışla yanıt veren
+// handler'lar kaydı audit.Hold ile akış bitene kadar tutar.
+func Audit(action string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		audit.Begin(c, action)
+		err := c.Next()
+		audit.End(c, err)
+		return err
+	}
+}
@@ -1,13 +1,34 @@
 package middleware
 
 import (
+	"crypto/x509"
+	"errors"
 	"log/slog"
+	"reflect"
+	"strings"
 
+	"log-server/auth"
 	"log-server/config"
+	"log-server/ratelimit"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+func init() {
+	// Auth her istekte güncel config'i okur; değişikliği sadece kayda geçiriyoruz.
+	// Değerin kendisi loglanmaz.
+	config.OnChange(func(old, new *config.Config) {
+		if !reflect.DeepEqual(old.Auth, new.Auth) {
+			slog.Info("API key ayarları güncellendi", "header", new.Auth.ApiKey)
+		}
+	})
+}
+
+// Auth isteği imzayla (auth.signing.mode açıksa ve X-Signature varsa), bearer token'la
+// (auth.jwt.enabled ise), server.tls.client_certs'e uyan istemci sertifikasıyla (header
+// anahtarı yoksa) veya api_key header'ındaki anahtarla doğrular ve kimliği isteğe ekler.
+// Yetki kontrolü route bazında Require ile yapılır. auth.signing.mode=required yalnızca header
+// anahtarlarını kapsar; bearer token ve istemci sertifikası kendi doğrulamalarıyla kabul edilir.
 func Auth() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		cfg := config.Get()
@@ -18,15 +39,110 @@ func Auth() fiber.Handler {
 			headerName = "inohom-api-key"
 		}
 
-		value := c.Get(headerName)
+		bearer, hasBearer := bearerToken(c)
+		certPrincipal, hasCert := auth.ClientCertPrincipal(clientCert(c))
 
-		if value != cfg.Auth.ApiValue {
-			slog.Warn("Unauthorized access attempt", "ip", c.IP(), "header", headerName, "provided_value", value)
+		var p *auth.Principal
+		var err error
+		switch mode := auth.SigningMode(); {
+		case mode != auth.SigningOff && c.Get(auth.HeaderSignature) != "":
+			p, err = auth.VerifySignature(auth.SignedRequest{
+				KeyId:     c.Get(auth.HeaderKeyId),
+				Method:    c.Method(),
+				Path:      c.OriginalURL(),
+				Timestamp: c.Get(auth.HeaderTimestamp),
+				Nonce:     c.Get(auth.HeaderNonce),
+				Body:      c.Body(),
+				Signature: c.Get(auth.HeaderSignature),
+			})
+		case hasBearer && auth.JWTEnabled():
+			// Kullanıcı token'ları imza gerektirmez; imza modu makineden makineye çağrılar içindir
+			p, err = auth.VerifyJWT(bearer)
+		case hasCert && c.Get(headerName) == "":
+			// Doğrulanmış istemci sertifikası; TLS bağlantısı imzanın sağladığı korumayı zaten sağlar
+			p = certPrincipal
+		case mode == auth.SigningRequired:
+			err = auth.ErrSignatureRequired
+		default:
+			p, err = auth.Authenticate(c.Get(headerName))
+		}
+		if errors.Is(err, auth.ErrNonceCacheFull) {
+			// İmza geçerli; cache'teki nonce'lar atılıp tekrar oynatmaya izin verilmez, istemci sonra dener
+			slog.Warn("Nonce cache dolu, imzalı istek reddedildi", "ip", c.IP(), "path", c.Path())
+			ratelimit.RetryAfter(c, auth.NonceCacheRetryAfter())
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Service busy, retry later",
+			})
+		}
+		if err != nil {
+			slog.Warn("Unauthorized access attempt", "ip", c.IP(), "header", headerName, "path", c.Path(), "reason", err.Error())
+			if ban, banned := ratelimit.AuthFailed(c.IP()); banned {
+				slog.Warn("IP temporarily banned after repeated auth failures", "ip", c.IP(), "ban_sec", int(ban.Seconds()))
+			}
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Unauthorized",
 			})
 		}
 
+		auth.SetPrincipal(c, p)
+		return c.Next()
+	}
+}
+
+// bearerToken "Authorization: Bearer <token>" header'ındaki token'ı döner.
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// clientCert TLS bağlantısında CA ile doğrulanmış istemci sertifikasını döner.
+func clientCert(c *fiber.Ctx) *x509.Certificate {
+	state := c.Context().TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return state.PeerCertificates[0]
+}
+
+// Require isteği yapan kimliğin scope'a sahip olmasını şart koşar.
+func Require(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p := auth.FromCtx(c)
+		if !p.HasScope(scope) {
+			var id string
+			if p != nil {
+				id = p.ID
+			}
+			slog.Warn("Forbidden", "ip", c.IP(), "key_id", id, "path", c.Path(), "required_scope", scope)
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Forbidden",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// Authorize auth.rbac açıksa kimliğin rollerinden birinin endpoint'e izin vermesini şart koşar
+// ve okunabilecek evleri isteğe ekler. Evlerin kontrolü handler'da auth.CanReadHome ile yapılır.
+func Authorize(endpoint string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p := auth.FromCtx(c)
+		g, err := auth.Authorize(p, endpoint)
+		if err != nil {
+			var id string
+			if p != nil {
+				id = p.ID
+			}
+			slog.Warn("Forbidden", "ip", c.IP(), "key_id", id, "path", c.Path(), "endpoint", endpoint, "reason", err.Error())
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Forbidden",
+			})
+		}
+		auth.SetGrant(c, g)
 		return c.Next()
 	}
 }
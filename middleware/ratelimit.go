@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"log-server/auth"
+	"log-server/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IPLimit kimlik doğrulamadan önce engellenmiş IP'leri reddeder ve IP başına sınırı uygular.
+func IPLimit() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if left, banned := ratelimit.Banned(c.IP()); banned {
+			return tooManyRequests(c, left, "Too many failed authentication attempts")
+		}
+		if wait, ok := ratelimit.AllowIP(c.IP()); !ok {
+			slog.Warn("Rate limit exceeded", "ip", c.IP(), "path", c.Path(), "limit", "per_ip")
+			return tooManyRequests(c, wait, "Too many requests")
+		}
+		return c.Next()
+	}
+}
+
+// KeyLimit Auth'tan sonra kimlik başına sınırı uygular.
+func KeyLimit() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p := auth.FromCtx(c)
+		if p == nil {
+			return c.Next()
+		}
+		if wait, ok := ratelimit.AllowKey(p.ID); !ok {
+			slog.Warn("Rate limit exceeded", "ip", c.IP(), "key_id", p.ID, "path", c.Path(), "limit", "per_key")
+			return tooManyRequests(c, wait, "Too many requests")
+		}
+		return c.Next()
+	}
+}
+
+// Downloads ağır indirme endpoint'lerinin eşzamanlılığını sınırlar. Akışla yanıt veren
+// handler'lar slotu ratelimit.Hold ile akış bitene kadar tutar.
+func Downloads() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var id string
+		if p := auth.FromCtx(c); p != nil {
+			id = p.ID
+		}
+		release, err := ratelimit.Acquire(id)
+		if err != nil {
+			slog.Warn("Download rejected", "ip", c.IP(), "key_id", id, "path", c.Path(), "reason", err.Error())
+			ratelimit.RetryAfter(c, ratelimit.BusyRetryAfter)
+			status := fiber.StatusTooManyRequests
+			if err == ratelimit.ErrServerBusy {
+				status = fiber.StatusServiceUnavailable
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		ratelimit.SetSlot(c, release)
+		defer ratelimit.ReleaseSlot(c)
+		return c.Next()
+	}
+}
+
+func tooManyRequests(c *fiber.Ctx, wait time.Duration, msg string) error {
+	ratelimit.RetryAfter(c, wait)
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error": msg,
+	})
+}
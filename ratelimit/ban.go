@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"log-server/config"
+	"sync"
+	"time"
+)
+
+type failureState struct {
+	count       int
+	windowStart time.Time
+	bannedUntil time.Time
+}
+
+var failures = struct {
+	mu      sync.Mutex
+	m       map[string]*failureState
+	sweptAt time.Time
+}{m: make(map[string]*failureState)}
+
+// AuthFailed IP'nin kimlik doğrulama hatasını kaydeder. Pencere içindeki hata sayısı
+// max_failures'a ulaştıysa IP ban_sec boyunca engellenir ve true döner.
+func AuthFailed(ip string) (time.Duration, bool) {
+	return authFailed(ip, time.Now())
+}
+
+func authFailed(ip string, now time.Time) (time.Duration, bool) {
+	cfg := config.Get().RateLimit.AuthFailures
+	if cfg.MaxFailures <= 0 {
+		return 0, false
+	}
+	window, ban := defaultWindow, defaultBan
+	if cfg.WindowSec > 0 {
+		window = time.Duration(cfg.WindowSec) * time.Second
+	}
+	if cfg.BanSec > 0 {
+		ban = time.Duration(cfg.BanSec) * time.Second
+	}
+
+	failures.mu.Lock()
+	defer failures.mu.Unlock()
+	sweepFailures(now)
+
+	st, ok := failures.m[ip]
+	if !ok || now.Sub(st.windowStart) > window {
+		st = &failureState{windowStart: now}
+		failures.m[ip] = st
+	}
+	st.count++
+	if st.count < cfg.MaxFailures {
+		return 0, false
+	}
+	st.bannedUntil = now.Add(ban)
+	st.count = 0
+	st.windowStart = now
+	return ban, true
+}
+
+// Banned IP engelliyse kalan süreyi ve true döner.
+func Banned(ip string) (time.Duration, bool) {
+	return banned(ip, time.Now())
+}
+
+func banned(ip string, now time.Time) (time.Duration, bool) {
+	if config.Get().RateLimit.AuthFailures.MaxFailures <= 0 {
+		return 0, false
+	}
+	failures.mu.Lock()
+	defer failures.mu.Unlock()
+	st, ok := failures.m[ip]
+	if !ok {
+		return 0, false
+	}
+	if left := st.bannedUntil.Sub(now); left > 0 {
+		return left, true
+	}
+	return 0, false
+}
+
+// sweepFailures süresi geçmiş kayıtları atar. failures.mu tutulurken çağrılmalıdır.
+func sweepFailures(now time.Time) {
+	if now.Sub(failures.sweptAt) < sweepInterval {
+		return
+	}
+	failures.sweptAt = now
+	for ip, st := range failures.m {
+		if now.After(st.bannedUntil) && now.Sub(st.windowStart) > idleTTL {
+			delete(failures.m, ip)
+		}
+	}
+}
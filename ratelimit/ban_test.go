@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuthFailedBan(t *testing.T) {
+	loadConfig(t, "{auth_failures: {max_failures: 3, window_sec: 60, ban_sec: 120}}")
+
+	type step struct {
+		at         time.Duration // Başlangıca göre
+		fail       bool          // false ise yalnızca banned kontrol edilir
+		wantBanned bool
+		wantLeft   time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"sınırın altında", []step{
+			{0, true, false, 0}, {time.Second, true, false, 0}, {2 * time.Second, false, false, 0},
+		}},
+		{"pencere içinde sınır", []step{
+			{0, true, false, 0}, {time.Second, true, false, 0}, {2 * time.Second, true, true, 120 * time.Second},
+			{62 * time.Second, false, true, 60 * time.Second}, {122 * time.Second, false, false, 0},
+		}},
+		{"pencere dışına taşan hatalar", []step{
+			{0, true, false, 0}, {30 * time.Second, true, false, 0}, {61 * time.Second, true, false, 0},
+			{62 * time.Second, true, false, 0}, {63 * time.Second, true, true, 120 * time.Second},
+		}},
+		{"engelden sonra sayaç sıfırlanır", []step{
+			{0, true, false, 0}, {0, true, false, 0}, {0, true, true, 120 * time.Second},
+			{121 * time.Second, true, false, 0}, {122 * time.Second, false, false, 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFailures()
+			ip := "192.0.2.1"
+			start := time.Now()
+			for i, s := range tt.steps {
+				now := start.Add(s.at)
+				var left time.Duration
+				var isBanned bool
+				if s.fail {
+					left, isBanned = authFailed(ip, now)
+				} else {
+					left, isBanned = banned(ip, now)
+				}
+				if isBanned != s.wantBanned || left != s.wantLeft {
+					t.Fatalf("adım %d: %v, %v; %v, %v bekleniyordu", i, left, isBanned, s.wantLeft, s.wantBanned)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthFailedDisabled(t *testing.T) {
+	loadConfig(t, "{auth_failures: {max_failures: 0}}")
+	resetFailures()
+	for range 10 {
+		if _, isBanned := authFailed("192.0.2.1", time.Now()); isBanned {
+			t.Fatal("max_failures 0 iken IP engellendi")
+		}
+	}
+}
+
+func resetFailures() {
+	failures.mu.Lock()
+	defer failures.mu.Unlock()
+	failures.m = make(map[string]*failureState)
+	failures.sweptAt = time.Time{}
+}
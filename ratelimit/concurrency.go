@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"log-server/config"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var downloads = struct {
+	mu    sync.Mutex
+	total int
+	byKey map[string]int
+}{byKey: make(map[string]int)}
+
+// Acquire kimlik için bir indirme slotu alır. Sınır doluysa ErrServerBusy veya
+// ErrTooManyDownloads döner; başarılıysa dönen fonksiyon slotu bırakır (bir kez).
+func Acquire(id string) (func(), error) {
+	cfg := config.Get().RateLimit
+
+	downloads.mu.Lock()
+	defer downloads.mu.Unlock()
+	if cfg.MaxConcurrentDownloadsPerKey > 0 && downloads.byKey[id] >= cfg.MaxConcurrentDownloadsPerKey {
+		return nil, ErrTooManyDownloads
+	}
+	if cfg.MaxConcurrentDownloads > 0 && downloads.total >= cfg.MaxConcurrentDownloads {
+		return nil, ErrServerBusy
+	}
+	downloads.total++
+	downloads.byKey[id]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			downloads.mu.Lock()
+			defer downloads.mu.Unlock()
+			downloads.total--
+			if downloads.byKey[id]--; downloads.byKey[id] <= 0 {
+				delete(downloads.byKey, id)
+			}
+		})
+	}, nil
+}
+
+const slotKey = "ratelimit.slot"
+
+type slot struct {
+	release func()
+	held    bool
+}
+
+// SetSlot middleware'in aldığı slotu isteğe ekler.
+func SetSlot(c *fiber.Ctx, release func()) {
+	c.Locals(slotKey, &slot{release: release})
+}
+
+// Hold isteğin slotunu handler döndükten sonra da tutar ve bırakma fonksiyonunu döner.
+// SetBodyStreamWriter kullanan handler'lar yazıcının sonunda çağırmalıdır; aksi halde slot
+// akış başlamadan bırakılır. Slot yoksa boş bir fonksiyon döner.
+func Hold(c *fiber.Ctx) func() {
+	s, ok := c.Locals(slotKey).(*slot)
+	if !ok {
+		return func() {}
+	}
+	s.held = true
+	return s.release
+}
+
+// ReleaseSlot handler Hold çağırmadıysa slotu bırakır.
+func ReleaseSlot(c *fiber.Ctx) {
+	if s, ok := c.Locals(slotKey).(*slot); ok && !s.held {
+		s.release()
+	}
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	loadConfig(t, "{max_concurrent_downloads: 3, max_concurrent_downloads_per_key: 2}")
+
+	var held []func()
+	t.Cleanup(func() {
+		for _, release := range held {
+			release()
+		}
+	})
+	tests := []struct {
+		id      string
+		wantErr error
+	}{
+		{"a", nil},
+		{"a", nil},
+		{"a", ErrTooManyDownloads},
+		{"b", nil},
+		{"c", ErrServerBusy},
+	}
+	for i, tt := range tests {
+		release, err := Acquire(tt.id)
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+			t.Fatalf("adım %d: Acquire(%s) hata = %v, %v bekleniyordu", i, tt.id, err, tt.wantErr)
+		}
+		if err == nil {
+			held = append(held, release)
+		}
+	}
+
+	// Bırakma idempotent; iki kez çağrılsa da tek slot açılır
+	held[0]()
+	held[0]()
+	release, err := Acquire("c")
+	if err != nil {
+		t.Fatalf("slot bırakıldıktan sonra Acquire hata = %v", err)
+	}
+	held = append(held, release)
+	if _, err := Acquire("d"); !errors.Is(err, ErrServerBusy) {
+		t.Fatalf("Acquire hata = %v, ErrServerBusy bekleniyordu (slot iki kez bırakıldı)", err)
+	}
+}
@@ -0,0 +1,124 @@
+// Package ratelimit istekleri kimlik, IP ve home_id başına token bucket'larla sınırlar, ağır
+// indirme endpoint'lerinin eşzamanlılığını kısıtlar ve tekrarlanan kimlik doğrulama
+// hatalarından sonra IP'leri geçici olarak engeller.
+//
+// Sayaçlar instance başına bellekte tutulur; birden fazla instance varsa her biri sınırı
+// ayrı uygular. Limitler her istekte güncel config'ten okunur.
+package ratelimit
+
+import (
+	"errors"
+	"log-server/config"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultWindow = 5 * time.Minute
+	defaultBan    = 15 * time.Minute
+
+	// Bu süre boyunca yeni hata olmayan (ve engeli bitmiş) IP kayıtları bellekten atılır
+	idleTTL       = 10 * time.Minute
+	sweepInterval = time.Minute
+
+	// BusyRetryAfter eşzamanlılık sınırına takılan istemcilere önerilen bekleme
+	BusyRetryAfter = 10 * time.Second
+)
+
+var (
+	ErrServerBusy       = errors.New("eşzamanlı indirme sınırına ulaşıldı")
+	ErrTooManyDownloads = errors.New("kimlik için eşzamanlı indirme sınırına ulaşıldı")
+)
+
+var (
+	perKey  = newBuckets()
+	perIP   = newBuckets()
+	perHome = newBuckets()
+)
+
+// AllowKey kimlik başına sınırı uygular. Sınır aşılmışsa bir sonraki isteğe kadar
+// beklenecek süreyi ve false döner.
+func AllowKey(id string) (time.Duration, bool) {
+	return perKey.take(id, config.Get().RateLimit.PerKey, time.Now())
+}
+
+// AllowIP IP başına sınırı uygular.
+func AllowIP(ip string) (time.Duration, bool) {
+	return perIP.take(ip, config.Get().RateLimit.PerIP, time.Now())
+}
+
+// AllowHome home_id başına yükleme sınırını uygular.
+func AllowHome(homeId string) (time.Duration, bool) {
+	return perHome.take(homeId, config.Get().RateLimit.PerHome, time.Now())
+}
+
+// RetryAfter Retry-After header'ını saniye olarak ayarlar (en az 1).
+func RetryAfter(c *fiber.Ctx, d time.Duration) {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(d)))
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(max(d, time.Second).Seconds()))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type buckets struct {
+	mu      sync.Mutex
+	m       map[string]*bucket
+	sweptAt time.Time
+}
+
+func newBuckets() *buckets {
+	return &buckets{m: make(map[string]*bucket)}
+}
+
+// take key'in bucket'ından bir istek düşer.
+func (b *buckets) take(key string, limit config.BucketConfig, now time.Time) (time.Duration, bool) {
+	if limit.Rate <= 0 {
+		return 0, true
+	}
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = max(1, math.Ceil(limit.Rate))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sweep(now, limit.Rate, burst)
+
+	bk, ok := b.m[key]
+	if !ok {
+		bk = &bucket{tokens: burst, last: now}
+		b.m[key] = bk
+	}
+	bk.tokens = min(burst, bk.tokens+now.Sub(bk.last).Seconds()*limit.Rate)
+	bk.last = now
+	if bk.tokens < 1 {
+		return time.Duration((1 - bk.tokens) / limit.Rate * float64(time.Second)), false
+	}
+	bk.tokens--
+	return 0, true
+}
+
+// sweep şimdiye kadar dolmuş olacak bucket'ları atar; atılan bucket bir sonraki istekte dolu
+// olarak yeniden oluşturulduğu için sınır değişmez. Yavaş dolan bucket'lar (rate*idleTTL < burst)
+// boşta geçen süreye bakılarak atılsaydı sınır sıfırlanmış olurdu. mu tutulurken çağrılmalıdır.
+func (b *buckets) sweep(now time.Time, rate, burst float64) {
+	if now.Sub(b.sweptAt) < sweepInterval {
+		return
+	}
+	b.sweptAt = now
+	for key, bk := range b.m {
+		if bk.tokens+now.Sub(bk.last).Seconds()*rate >= burst {
+			delete(b.m, key)
+		}
+	}
+}
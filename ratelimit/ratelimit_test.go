@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log-server/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// loadConfig geçerli bir temel config'e rate_limit bölümünü ekleyip yükler.
+func loadConfig(t *testing.T, rateLimit string) {
+	t.Helper()
+	dir := t.TempDir()
+	yaml := fmt.Sprintf("internal_log: {log_file: %q}\nkettas_log: {zip_password: test, backup: {backup_dir: %q}}\nrate_limit: %s\n",
+		filepath.Join(dir, "app.log"), filepath.Join(dir, "backups"), rateLimit)
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTake(t *testing.T) {
+	type step struct {
+		at       time.Duration // Başlangıca göre
+		wantOK   bool
+		wantWait time.Duration
+	}
+	tests := []struct {
+		name  string
+		limit config.BucketConfig
+		steps []step
+	}{
+		{"kapalı", config.BucketConfig{}, []step{{0, true, 0}, {0, true, 0}, {0, true, 0}}},
+		{"burst kadar izin", config.BucketConfig{Rate: 1, Burst: 2}, []step{
+			{0, true, 0}, {0, true, 0}, {0, false, time.Second},
+		}},
+		{"dolum", config.BucketConfig{Rate: 2, Burst: 1}, []step{
+			{0, true, 0}, {250 * time.Millisecond, false, 250 * time.Millisecond}, {500 * time.Millisecond, true, 0},
+		}},
+		{"varsayılan burst rate'in yukarı yuvarlanmışı", config.BucketConfig{Rate: 1.5}, []step{
+			{0, true, 0}, {0, true, 0}, {0, false, 666666666},
+		}},
+		{"yavaş rate, burst en az 1", config.BucketConfig{Rate: 0.1}, []step{
+			{0, true, 0}, {time.Second, false, 9 * time.Second}, {10 * time.Second, true, 0},
+		}},
+		{"dolum burst'ü aşmaz", config.BucketConfig{Rate: 1, Burst: 2}, []step{
+			{0, true, 0}, {0, true, 0}, {time.Hour, true, 0}, {time.Hour, true, 0}, {time.Hour, false, time.Second},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBuckets()
+			start := time.Now()
+			for i, s := range tt.steps {
+				wait, ok := b.take("k", tt.limit, start.Add(s.at))
+				if ok != s.wantOK || (wait-s.wantWait).Abs() > time.Millisecond {
+					t.Fatalf("adım %d: take = %v, %v; %v, %v bekleniyordu", i, wait, ok, s.wantWait, s.wantOK)
+				}
+			}
+		})
+	}
+}
+
+// TestSweepKeepsPartialBuckets yavaş dolan bucket'ların boşta kaldıkları için değil, ancak
+// dolduklarında atıldığını doğrular.
+func TestSweepKeepsPartialBuckets(t *testing.T) {
+	// 100 saniyede bir istek, burst 10: boş bucket'ın dolması 1000 saniye (> idleTTL) sürer
+	limit := config.BucketConfig{Rate: 0.01, Burst: 10}
+	b := newBuckets()
+	start := time.Now()
+	for range 10 {
+		b.take("k", limit, start)
+	}
+
+	tests := []struct {
+		at     time.Duration
+		wantOK bool
+	}{
+		{idleTTL + sweepInterval, true},               // ~6 token birikti
+		{idleTTL + sweepInterval + time.Second, true}, // Bucket atılsaydı 10 istek geçerdi
+		{idleTTL + sweepInterval + 2*time.Second, true},
+		{idleTTL + sweepInterval + 3*time.Second, true},
+		{idleTTL + sweepInterval + 4*time.Second, true},
+		{idleTTL + sweepInterval + 5*time.Second, true},
+		{idleTTL + sweepInterval + 6*time.Second, false},
+	}
+	for i, tt := range tests {
+		if _, ok := b.take("other", limit, start.Add(tt.at)); !ok {
+			t.Fatal("diğer anahtar sınırlandı")
+		}
+		if _, ok := b.take("k", limit, start.Add(tt.at)); ok != tt.wantOK {
+			t.Fatalf("adım %d: take = %v, %v bekleniyordu", i, ok, tt.wantOK)
+		}
+	}
+
+	// Dolmuş bucket'lar atılır
+	b.take("x", limit, start.Add(2*time.Hour))
+	if _, ok := b.m["other"]; ok {
+		t.Error("dolmuş bucket atılmadı")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 1},
+		{-time.Second, 1},
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{time.Second + time.Millisecond, 2},
+		{90 * time.Second, 90},
+	}
+	for _, tt := range tests {
+		if got := retryAfterSeconds(tt.d); got != tt.want {
+			t.Errorf("retryAfterSeconds(%v) = %d, %d bekleniyordu", tt.d, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"log-server/audit"
+	"log-server/auth"
 	"log-server/handlers"
 	"log-server/middleware"
 
@@ -9,15 +11,73 @@ import (
 
 func Setup(app *fiber.App) {
 	app.Use(middleware.RequestLogger())
+	app.Use(middleware.IPLimit())
 	app.Use(middleware.Auth())
+	app.Use(middleware.KeyLimit())
 
-	app.Post("/upload", handlers.Upload)
+	app.Post("/upload", middleware.Require(auth.ScopeUpload), handlers.Upload)
 
-	// Tüm evlerin loglarını tarih bazlı zip olarak döner
+	// Log indirme, arama ve yönetim istekleri denetim kaydına (audit) yazılır (middleware.Audit)
+
+	// Tüm evlerin loglarını tarih bazlı zip olarak döner (auth.rbac açıksa rollerin izin verdiği evler)
 	// Body: start_date, (end_date opsiyonel)
-	app.Get("/all-logs", handlers.GetAllLogs)
+	app.Get("/all-logs", middleware.Audit(audit.ActionAllLogs), middleware.Require(auth.ScopeReadAll), middleware.Authorize(auth.EndpointAllLogs), middleware.Downloads(), handlers.GetAllLogs)
 
-	// Belirli bir evin loglarını döner
+	// Belirli bir evin loglarını döner (anahtar evlere bağlıysa yalnızca o evler)
 	// Body: home_id, start_date, (end_date opsiyonel)
-	app.Get("/home-logs", handlers.GetLogByHomeId)
+	app.Get("/home-logs", middleware.Audit(audit.ActionHomeLogs), middleware.Require(auth.ScopeReadHome), middleware.Authorize(auth.EndpointHomeLogs), middleware.Downloads(), handlers.GetLogByHomeId)
+
+	// Bir evin arşivlenmiş event'lerinde arama (NDJSON)
+	// Body: home_id, (from, to, contains, limit opsiyonel)
+	app.Get("/home-logs/search", middleware.Audit(audit.ActionSearch), middleware.Require(auth.ScopeReadHome), middleware.Authorize(auth.EndpointSearch), middleware.Downloads(), handlers.SearchHomeLogs)
+
+	v1 := app.Group("/v1")
+
+	// Evin tüm verilerini (loglar, arşivler, export'lar, MongoDB, anahtarlar) sil
+	// ve imzalı silme sertifikası döndür
+	v1.Delete("/homes/:id", middleware.Audit(audit.ActionAdmin), middleware.Require(auth.ScopeAdmin), handlers.DeleteHome)
+
+	// Silme sertifikalarını doğrulamak için public key
+	v1.Get("/erasures/public-key", handlers.GetErasurePublicKey)
+
+	// Yönetim endpoint'leri
+	admin := app.Group("/admin", middleware.Audit(audit.ActionAdmin), middleware.Require(auth.ScopeAdmin))
+
+	// API anahtarları (secret yalnızca oluşturulurken döner)
+	// POST body: name, scopes, (home_ids, expires_at opsiyonel)
+	admin.Get("/api-keys", handlers.GetApiKeys)
+	admin.Post("/api-keys", handlers.CreateApiKey)
+	admin.Delete("/api-keys/:id", handlers.RevokeApiKey)
+
+	// Eve bağlı cihaz token'ları (yalnızca upload; home_id token'dan alınır)
+	// GET query: home_id; POST body: home_id, (name, expires_at opsiyonel)
+	admin.Get("/devices", handlers.GetDevices)
+	admin.Post("/devices", handlers.ProvisionDevice)
+	admin.Delete("/devices/:id", handlers.RevokeDevice)
+
+	// Bir sonraki cleanup/rotation'ın sileceği dosyalar (dry-run)
+	admin.Get("/backup/plan", handlers.GetBackupPlan)
+
+	// Gerçekleşmiş silmelerin journal'ı
+	// Query: since, until (RFC3339), home_id, job, limit
+	admin.Get("/backup/deletions", handlers.GetDeletionJournal)
+
+	// Veri erişimlerinin denetim kaydı (bütünlük: logctl audit verify)
+	// Query: since, until (RFC3339), action, actor, home_id, limit
+	admin.Get("/audit", handlers.GetAuditLog)
+
+	// Zamanlanmış işler ve bir sonraki çalışma zamanları
+	admin.Get("/jobs", handlers.GetJobs)
+
+	// Tamamlanmış iş çalışmaları
+	// Query: job, status, since (RFC3339), limit
+	admin.Get("/jobs/history", handlers.GetJobHistory)
+
+	// İşi manuel tetikle (daily_archive için body: date)
+	admin.Post("/jobs/:name/run", handlers.RunJob)
+
+	// Arşivlenmiş event'lerin Parquet export'u
+	// Body: start_date, (home_id, end_date opsiyonel)
+	admin.Post("/exports", handlers.CreateExport)
+	admin.Get("/exports", handlers.GetExports)
 }
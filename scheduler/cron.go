@@ -0,0 +1,268 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule bir işin bir sonraki çalışma zamanını hesaplar.
+type Schedule interface {
+	// Next t'den sonraki ilk çalışma zamanını döner (t'nin location'ında).
+	Next(t time.Time) time.Time
+}
+
+// everySchedule "@every 5h" gibi sabit aralıklı zamanlama.
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// SpecManual zamanlanmayan, sadece RunNow/Trigger ile çalıştırılan işler için.
+const SpecManual = "@manual"
+
+// manualSchedule hiçbir zaman kendiliğinden çalışmaz.
+type manualSchedule struct{}
+
+func (manualSchedule) Next(time.Time) time.Time {
+	return time.Time{}
+}
+
+// cronSchedule standart 5 alanlı cron ifadesi: dakika saat gün ay haftanın-günü
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar, hourStar    bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = cronField{name: "dakika", min: 0, max: 59}
+	hourField   = cronField{name: "saat", min: 0, max: 23}
+	domField    = cronField{name: "ayın günü", min: 1, max: 31}
+	monthField  = cronField{name: "ay", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = cronField{name: "haftanın günü", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse cron ifadesini çözer.
+// Desteklenenler: "m h dom mon dow" (*, */n, a-b, a-b/n, listeler, jan/mon gibi adlar),
+// @daily/@hourly/@weekly/@monthly/@yearly, "@every 1h30m" ve @manual.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("boş cron ifadesi")
+	}
+
+	if strings.EqualFold(spec, SpecManual) {
+		return manualSchedule{}, nil
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("geçersiz @every süresi %q: %w", spec, err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("@every süresi en az 1s olmalı: %q", spec)
+		}
+		return everySchedule{interval: d}, nil
+	}
+
+	if expanded, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron ifadesi 5 alan içermeli (dakika saat gün ay haftanın-günü): %q", spec)
+	}
+
+	s := &cronSchedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	// Haftanın günü için 7 de pazar kabul edilir
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+	s.hourStar = s.hour == 1<<24-1
+
+	return s, nil
+}
+
+// parseField tek bir cron alanını bit maskesine çevirir.
+func parseField(expr string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s alanında geçersiz adım: %q", f.name, part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := f.value(part)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// "5/15" → 5'ten max'a kadar 15'er adım
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		if lo > hi {
+			return 0, fmt.Errorf("%s alanında geçersiz aralık: %q", f.name, part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s alanında geçersiz değer: %q", f.name, s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s alanı %d-%d aralığında olmalı: %d", f.name, f.min, f.max, v)
+	}
+	return v, nil
+}
+
+// Next t'den sonraki ilk eşleşen dakikayı döner. Hesap t'nin location'ında yapılır,
+// böylece DST geçişlerinde duvar saati korunur:
+//   - Saat ileri alındığında atlanan saatlere denk gelen çalışma geçişten hemen sonra yapılır.
+//   - Saat geri alındığında tekrar eden saatteki çalışma, saat alanı * değilse bir kez yapılır.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// 5 yıl içinde eşleşme yoksa (ör: 30 şubat) sıfır zaman döner
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			// Bir sonraki saatin başı mutlak zamanla bulunur; time.Date DST'de var olmayan
+			// saatleri geriye normalize edebilir
+			next := t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			if next.Day() == t.Day() && s.skippedHourMatches(t.Hour()+1, next.Hour()) {
+				return next
+			}
+			t = next
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		if !s.hourStar && repeatedWallTime(t) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// skippedHourMatches [from, to) saatlerinden (DST ile atlanan) biri eşleşiyorsa true döner.
+func (s *cronSchedule) skippedHourMatches(from, to int) bool {
+	for h := from; h < to; h++ {
+		if s.hour&(1<<uint(h)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// repeatedWallTime t'nin duvar saati bir saat önce de yaşandıysa (saat geri alındı) true döner.
+func repeatedWallTime(t time.Time) bool {
+	p := t.Add(-time.Hour)
+	return p.Day() == t.Day() && p.Hour() == t.Hour() && p.Minute() == t.Minute()
+}
+
+// advance duvar saatine göre hesaplanan next'i döner; DST nedeniyle next t'nin gerisine
+// düştüyse (ör: gece yarısının olmadığı günler) sonsuz döngüyü önlemek için bir saat ilerler.
+func advance(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Hour)
+}
+
+// dayMatches cron semantiği: gün ve haftanın günü ikisi de kısıtlıysa biri eşleşmesi yeterli.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
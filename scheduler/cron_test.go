@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skip("tzdata yok:", err)
+	}
+	return loc
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"0 0 * * *", false},
+		{"*/15 9-17 * * mon-fri", false},
+		{"0 0 1,15 jan,jul 7", false},
+		{"5/15 * * * *", false},
+		{"@daily", false},
+		{"@WEEKLY", false},
+		{"@every 1h30m", false},
+		{"@manual", false},
+		{"", true},
+		{"* * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"* * * * 8", true},
+		{"*/0 * * * *", true},
+		{"10-5 * * * *", true},
+		{"* * * foo *", true},
+		{"@every 500ms", true},
+		{"@every soon", true},
+		{"@fortnightly", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) hata = %v, hata bekleniyor = %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	utc := time.UTC
+	newYork := mustLoad(t, "America/New_York")
+	istanbul := mustLoad(t, "Europe/Istanbul")
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want []time.Time // Ardışık Next sonuçları
+	}{
+		{"her gün", "30 2 * * *", time.Date(2024, 1, 1, 0, 0, 0, 0, utc), []time.Time{
+			time.Date(2024, 1, 1, 2, 30, 0, 0, utc),
+			time.Date(2024, 1, 2, 2, 30, 0, 0, utc),
+		}},
+		{"saniyeler atlanır", "* * * * *", time.Date(2024, 1, 1, 0, 0, 30, 0, utc), []time.Time{
+			time.Date(2024, 1, 1, 0, 1, 0, 0, utc),
+		}},
+		{"adımlı", "*/20 * * * *", time.Date(2024, 1, 1, 0, 50, 0, 0, utc), []time.Time{
+			time.Date(2024, 1, 1, 1, 0, 0, 0, utc),
+			time.Date(2024, 1, 1, 1, 20, 0, 0, utc),
+		}},
+		{"hafta içi", "0 9 * * mon-fri", time.Date(2024, 1, 5, 10, 0, 0, 0, utc), []time.Time{
+			time.Date(2024, 1, 8, 9, 0, 0, 0, utc), // cuma 10:00 → pazartesi
+		}},
+		{"pazar 7", "0 0 * * 7", time.Date(2024, 1, 1, 0, 0, 0, 0, utc), []time.Time{
+			time.Date(2024, 1, 7, 0, 0, 0, 0, utc),
+		}},
+		{"gün veya haftanın günü", "0 0 13 * fri", time.Date(2024, 1, 1, 0, 0, 0, 0, utc), []time.Time{
+			time.Date(2024, 1, 5, 0, 0, 0, 0, utc),
+			time.Date(2024, 1, 12, 0, 0, 0, 0, utc),
+			time.Date(2024, 1, 13, 0, 0, 0, 0, utc),
+		}},
+		{"29 şubat", "0 0 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, utc), []time.Time{
+			time.Date(2028, 2, 29, 0, 0, 0, 0, utc),
+		}},
+		{"30 şubat", "0 0 30 2 *", time.Date(2024, 1, 1, 0, 0, 0, 0, utc), []time.Time{{}}},
+		{"@monthly", "@monthly", time.Date(2024, 1, 15, 0, 0, 0, 0, utc), []time.Time{
+			time.Date(2024, 2, 1, 0, 0, 0, 0, utc),
+		}},
+		{"@hourly", "@hourly", time.Date(2024, 1, 1, 23, 30, 0, 0, utc), []time.Time{
+			time.Date(2024, 1, 2, 0, 0, 0, 0, utc),
+		}},
+		{"@every", "@every 90m", time.Date(2024, 1, 1, 0, 0, 0, 0, utc), []time.Time{
+			time.Date(2024, 1, 1, 1, 30, 0, 0, utc),
+			time.Date(2024, 1, 1, 3, 0, 0, 0, utc),
+		}},
+		{"@manual", "@manual", time.Date(2024, 1, 1, 0, 0, 0, 0, utc), []time.Time{{}}},
+		{"saat dilimi", "0 3 * * *", time.Date(2024, 1, 1, 0, 0, 0, 0, istanbul), []time.Time{
+			time.Date(2024, 1, 1, 0, 0, 0, 0, utc), // İstanbul 03:00 = UTC 00:00
+		}},
+
+		// New York'ta 10 Mart 2024 02:00 → 03:00 (ileri), 3 Kasım 2024 02:00 → 01:00 (geri)
+		{"DST ileri: atlanan saat geçişten sonra çalışır", "30 2 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), []time.Time{
+			time.Date(2024, 3, 10, 3, 0, 0, 0, newYork),
+			time.Date(2024, 3, 11, 2, 30, 0, 0, newYork),
+		}},
+		{"DST ileri: diğer saatler korunur", "15 3 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), []time.Time{
+			time.Date(2024, 3, 10, 3, 15, 0, 0, newYork),
+			time.Date(2024, 3, 11, 3, 15, 0, 0, newYork),
+		}},
+		{"DST geri: sabit saat bir kez çalışır", "30 1 * * *", time.Date(2024, 11, 3, 0, 0, 0, 0, newYork), []time.Time{
+			time.Date(2024, 11, 3, 5, 30, 0, 0, utc), // 01:30 EDT
+			time.Date(2024, 11, 4, 6, 30, 0, 0, utc), // ertesi gün 01:30 EST
+		}},
+		{"DST geri: saatlik iş iki kez çalışır", "0 * * * *", time.Date(2024, 11, 3, 0, 30, 0, 0, newYork), []time.Time{
+			time.Date(2024, 11, 3, 5, 0, 0, 0, utc), // 01:00 EDT
+			time.Date(2024, 11, 3, 6, 0, 0, 0, utc), // 01:00 EST
+			time.Date(2024, 11, 3, 7, 0, 0, 0, utc), // 02:00 EST
+		}},
+		{"DST ileri: saatlik iş", "0 * * * *", time.Date(2024, 3, 10, 1, 30, 0, 0, newYork), []time.Time{
+			time.Date(2024, 3, 10, 7, 0, 0, 0, utc), // 03:00 EDT
+			time.Date(2024, 3, 10, 8, 0, 0, 0, utc),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := tt.from
+			for i, want := range tt.want {
+				got := s.Next(at)
+				if !got.Equal(want) {
+					t.Fatalf("Next #%d(%v) = %v, %v bekleniyordu", i+1, at, got, want)
+				}
+				if !got.IsZero() && got.Location() != tt.from.Location() {
+					t.Errorf("Next #%d location = %v, %v bekleniyordu", i+1, got.Location(), tt.from.Location())
+				}
+				at = got
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log-server/config"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Çalışma durumları
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" // Kilit başka instance'ta olduğu için çalışmadı
+)
+
+// RunRecord bir işin tek bir çalışmasının kalıcı kaydı (job history, NDJSON).
+type RunRecord struct {
+	ID             string            `json:"id"`
+	Job            string            `json:"job"`
+	Trigger        string            `json:"trigger"`
+	Params         map[string]string `json:"params,omitempty"`
+	StartedAt      time.Time         `json:"started_at"`
+	FinishedAt     time.Time         `json:"finished_at"`
+	DurationMs     int64             `json:"duration_ms"`
+	FilesProcessed int               `json:"files_processed"`
+	Status         string            `json:"status"`
+	Error          string            `json:"error,omitempty"`
+}
+
+// HistoryQuery job history sorgusu için filtreler. Boş alanlar filtre uygulanmaz.
+type HistoryQuery struct {
+	Job    string
+	Status string
+	Since  time.Time
+	Limit  int
+}
+
+func (s *Scheduler) newRecord(e *entry, trigger string, params map[string]string) RunRecord {
+	return RunRecord{
+		ID:      newRunID(),
+		Job:     e.job.Name,
+		Trigger: trigger,
+		Params:  params,
+	}
+}
+
+// newRunID zaman sıralı, çakışması pratikte imkânsız bir çalışma ID'si üretir.
+func newRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405"), hex.EncodeToString(b))
+}
+
+// historyPath config'deki history dosyasının yolunu döner (varsayılan: backup_dir/job_history.ndjson).
+func historyPath() string {
+	cfg := config.Get()
+	if cfg.KettasLog.Backup.Jobs.HistoryFile != "" {
+		return cfg.KettasLog.Backup.Jobs.HistoryFile
+	}
+	return filepath.Join(cfg.KettasLog.Backup.BackupDir, "job_history.ndjson")
+}
+
+func (s *Scheduler) appendHistory(rec RunRecord) error {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	path := historyPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("history dizini oluşturulamadı: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("history dosyası açılamadı: %w", err)
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// History tamamlanmış çalışmaları en yeniden eskiye doğru döner.
+func (s *Scheduler) History(q HistoryQuery) ([]RunRecord, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	f, err := os.Open(historyPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []RunRecord{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []RunRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// Yarım yazılmış satırları atla
+			continue
+		}
+		if q.Job != "" && rec.Job != q.Job {
+			continue
+		}
+		if q.Status != "" && rec.Status != q.Status {
+			continue
+		}
+		if !q.Since.IsZero() && rec.StartedAt.Before(q.Since) {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// En yeni kayıtlar önce
+	out := make([]RunRecord, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		out = append(out, records[i])
+		if q.Limit > 0 && len(out) >= q.Limit {
+			break
+		}
+	}
+	return out, nil
+}
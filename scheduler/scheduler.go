@@ -0,0 +1,417 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log-server/lock"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Result bir çalışmanın özet çıktısı.
+type Result struct {
+	FilesProcessed int
+}
+
+// RunFunc işi çalıştırır. scheduledAt işin (jitter eklenmeden önceki) planlanan zamanıdır,
+// Location'a göre ifade edilir; manuel çalışmalarda tetikleme anıdır.
+type RunFunc func(scheduledAt time.Time) (Result, error)
+
+// Job zamanlanmış bir işi tanımlar.
+type Job struct {
+	Name     string
+	Group    string         // Aynı gruptaki işler aynı anda çalışmaz (boşsa Name)
+	Spec     string         // cron ifadesi veya @every/@daily gibi descriptor
+	Location *time.Location // nil ise time.Local
+	Jitter   time.Duration  // her çalışmaya eklenecek rastgele gecikme üst sınırı
+	Run      RunFunc
+}
+
+// Tetikleme türleri
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+	TriggerStartup  = "startup"
+)
+
+// RunOptions RunNow/Trigger ile yapılan çalışmaların ayarları.
+type RunOptions struct {
+	Trigger string            // Boşsa TriggerManual
+	Params  map[string]string // History'ye yazılır (ör: date)
+	Run     RunFunc           // Boşsa işin kendi Run fonksiyonu
+}
+
+// ErrJobNotFound kayıtlı olmayan bir iş tetiklenmeye çalışıldığında döner.
+var ErrJobNotFound = errors.New("iş bulunamadı")
+
+// JobInfo GET /admin/jobs için bir işin durumunu özetler.
+type JobInfo struct {
+	Name           string     `json:"name"`
+	Spec           string     `json:"spec"`
+	TimeZone       string     `json:"timezone"`
+	Jitter         string     `json:"jitter"`
+	NextRun        *time.Time `json:"next_run,omitempty"` // Manuel işlerde yok
+	LastRun        time.Time  `json:"last_run,omitempty"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	LastStatus     string     `json:"last_status,omitempty"`
+	RunCount       int        `json:"run_count"`
+	Running        bool       `json:"running"`
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule
+	stop     chan struct{}
+
+	group string
+	runMu *sync.Mutex // Aynı gruptaki işlerin üst üste binmesini engeller
+
+	mu           sync.Mutex
+	nextRun      time.Time
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastStatus   string
+	runCount     int
+	running      bool
+}
+
+// Scheduler kayıtlı işleri kendi zamanlamalarına göre çalıştırır.
+type Scheduler struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	groups  map[string]*sync.Mutex
+	started bool
+	wg      sync.WaitGroup
+	runWg   sync.WaitGroup // Trigger ile başlatılan arka plan çalışmaları
+
+	historyMu sync.Mutex
+}
+
+var defaultScheduler = New()
+
+// Get uygulama genelinde kullanılan scheduler'ı döner.
+func Get() *Scheduler {
+	return defaultScheduler
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		entries: make(map[string]*entry),
+		groups:  make(map[string]*sync.Mutex),
+	}
+}
+
+// Add bir işi kaydeder. Aynı isimde iş varsa hata döner.
+// Scheduler zaten başlatılmışsa iş hemen zamanlanır.
+func (s *Scheduler) Add(job Job) error {
+	schedule, err := Parse(job.Spec)
+	if err != nil {
+		return fmt.Errorf("%s işi için geçersiz zamanlama: %w", job.Name, err)
+	}
+	if job.Location == nil {
+		job.Location = time.Local
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[job.Name]; exists {
+		return fmt.Errorf("%s işi zaten kayıtlı", job.Name)
+	}
+
+	group := job.Group
+	if group == "" {
+		group = job.Name
+	}
+	if s.groups[group] == nil {
+		s.groups[group] = &sync.Mutex{}
+	}
+
+	e := &entry{
+		job:      job,
+		schedule: schedule,
+		stop:     make(chan struct{}),
+		group:    group,
+		runMu:    s.groups[group],
+	}
+	s.entries[job.Name] = e
+
+	if s.started {
+		s.launch(e)
+	}
+	return nil
+}
+
+// Reschedule kayıtlı bir işin zamanlamasını değiştirir. Bekleyen zamanlayıcı iptal edilip
+// yenisi kurulur; süren bir çalışma kesilmez. Zamanlama aynıysa changed=false döner.
+func (s *Scheduler) Reschedule(name, spec string, loc *time.Location, jitter time.Duration) (changed bool, err error) {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return false, fmt.Errorf("%s işi için geçersiz zamanlama: %w", name, err)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.entries[name]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if old.job.Spec == spec && old.job.Location.String() == loc.String() && old.job.Jitter == jitter {
+		return false, nil
+	}
+
+	job := old.job
+	job.Spec, job.Location, job.Jitter = spec, loc, jitter
+	e := &entry{
+		job:      job,
+		schedule: schedule,
+		stop:     make(chan struct{}),
+		group:    old.group,
+		runMu:    old.runMu,
+	}
+	old.mu.Lock()
+	e.lastRun, e.lastDuration, e.lastStatus, e.runCount = old.lastRun, old.lastDuration, old.lastStatus, old.runCount
+	old.mu.Unlock()
+
+	// Eski zamanlama döngüsü durur; s.started değilse kanalı dinleyen yoktur
+	close(old.stop)
+	s.entries[name] = e
+	if s.started {
+		s.launch(e)
+	}
+	return true, nil
+}
+
+// Start tüm kayıtlı işlerin zamanlayıcılarını başlatır.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, e := range s.entries {
+		s.launch(e)
+	}
+	slog.Info("Scheduler başlatıldı", "job_count", len(s.entries))
+}
+
+// Stop zamanlayıcıları durdurur ve süren işlerin bitmesini bekler.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = false
+	for _, e := range s.entries {
+		close(e.stop)
+		e.stop = make(chan struct{})
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.runWg.Wait()
+	slog.Info("Scheduler durduruldu")
+}
+
+// Jobs kayıtlı işlerin durumunu isme göre sıralı döner.
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]JobInfo, 0, len(s.entries))
+	for _, e := range s.entries {
+		e.mu.Lock()
+		var nextRun *time.Time
+		if !e.nextRun.IsZero() {
+			next := e.nextRun
+			nextRun = &next
+		}
+		infos = append(infos, JobInfo{
+			Name:           e.job.Name,
+			Spec:           e.job.Spec,
+			TimeZone:       e.job.Location.String(),
+			Jitter:         e.job.Jitter.String(),
+			NextRun:        nextRun,
+			LastRun:        e.lastRun,
+			LastDurationMs: e.lastDuration.Milliseconds(),
+			LastStatus:     e.lastStatus,
+			RunCount:       e.runCount,
+			Running:        e.running,
+		})
+		e.mu.Unlock()
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// launch işin zamanlama döngüsünü başlatır. s.mu tutulurken çağrılmalıdır.
+func (s *Scheduler) launch(e *entry) {
+	// Manuel işlerin zamanlayıcısı yok
+	if _, ok := e.schedule.(manualSchedule); ok {
+		return
+	}
+
+	stop := e.stop
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			scheduledAt := e.schedule.Next(time.Now().In(e.job.Location))
+			if scheduledAt.IsZero() {
+				slog.Error("İş için bir sonraki çalışma zamanı bulunamadı", "job", e.job.Name, "spec", e.job.Spec)
+				return
+			}
+
+			fireAt := scheduledAt
+			if e.job.Jitter > 0 {
+				fireAt = fireAt.Add(time.Duration(rand.Int63n(int64(e.job.Jitter))))
+			}
+
+			e.mu.Lock()
+			e.nextRun = fireAt
+			e.mu.Unlock()
+
+			slog.Info("İş bir sonraki çalışma zamanı",
+				"job", e.job.Name,
+				"target", fireAt.Format("02/01/2006 15:04:05 MST"),
+				"wait_duration", time.Until(fireAt).Round(time.Second).String(),
+			)
+
+			timer := time.NewTimer(time.Until(fireAt))
+			select {
+			case <-timer.C:
+				s.execute(e, s.newRecord(e, TriggerSchedule, nil), e.job.Run, scheduledAt)
+			case <-stop:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// RunNow işi hemen ve senkron olarak çalıştırır; aynı gruptaki bir iş çalışıyorsa bitmesini bekler.
+func (s *Scheduler) RunNow(name string, opts RunOptions) (RunRecord, error) {
+	e, rec, run, err := s.prepare(name, opts)
+	if err != nil {
+		return RunRecord{}, err
+	}
+	return s.execute(e, rec, run, time.Now().In(e.job.Location)), nil
+}
+
+// Trigger işi arka planda çalıştırır ve çalışma ID'sini hemen döner.
+// Sonuç GET /admin/jobs/history üzerinden takip edilir.
+func (s *Scheduler) Trigger(name string, opts RunOptions) (string, error) {
+	e, rec, run, err := s.prepare(name, opts)
+	if err != nil {
+		return "", err
+	}
+
+	s.runWg.Add(1)
+	go func() {
+		defer s.runWg.Done()
+		s.execute(e, rec, run, time.Now().In(e.job.Location))
+	}()
+	return rec.ID, nil
+}
+
+func (s *Scheduler) prepare(name string, opts RunOptions) (*entry, RunRecord, RunFunc, error) {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, RunRecord{}, nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	trigger := opts.Trigger
+	if trigger == "" {
+		trigger = TriggerManual
+	}
+	run := opts.Run
+	if run == nil {
+		run = e.job.Run
+	}
+	return e, s.newRecord(e, trigger, opts.Params), run, nil
+}
+
+// execute işi çalıştırır, istatistikleri günceller ve history'ye yazar.
+// Aynı gruptaki bir iş halen çalışıyorsa bitmesini bekler. Grup kilidi başka bir
+// instance'taysa iş atlanır ve history'ye "skipped" olarak yazılır. Kilit alınamazsa
+// (ör: kilit backend'ine ulaşılamıyor) iş çalışmamıştır ve "failed" olarak yazılır.
+func (s *Scheduler) execute(e *entry, rec RunRecord, run RunFunc, scheduledAt time.Time) (out RunRecord) {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+
+	release, ok, err := lock.Get().TryLock(lock.JobKey(e.group))
+	if err != nil || !ok {
+		if err != nil {
+			rec.Status = StatusFailed
+			rec.Error = fmt.Sprintf("iş kilidi alınamadı: %v", err)
+			slog.Error("İş kilidi alınamadı, iş çalıştırılmadı", "job", e.job.Name, "error", err)
+			e.mu.Lock()
+			e.lastStatus = rec.Status
+			e.mu.Unlock()
+		} else {
+			rec.Status = StatusSkipped
+			rec.Error = "iş başka bir instance'ta çalışıyor"
+			slog.Info("İş atlandı", "job", e.job.Name, "reason", rec.Error)
+		}
+		rec.StartedAt = time.Now()
+		rec.FinishedAt = rec.StartedAt
+		if err := s.appendHistory(rec); err != nil {
+			slog.Error("Job history yazılamadı", "job", e.job.Name, "error", err)
+		}
+		return rec
+	}
+	defer release()
+
+	start := time.Now()
+	e.mu.Lock()
+	e.running = true
+	e.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("İş panic ile sonlandı", "job", e.job.Name, "panic", r)
+			rec.Status = StatusFailed
+			rec.Error = fmt.Sprintf("panic: %v", r)
+		}
+		duration := time.Since(start)
+
+		e.mu.Lock()
+		e.running = false
+		e.lastRun = start
+		e.lastDuration = duration
+		e.lastStatus = rec.Status
+		e.runCount++
+		e.mu.Unlock()
+
+		rec.StartedAt = start
+		rec.FinishedAt = start.Add(duration)
+		rec.DurationMs = duration.Milliseconds()
+		if err := s.appendHistory(rec); err != nil {
+			slog.Error("Job history yazılamadı", "job", e.job.Name, "error", err)
+		}
+		out = rec
+	}()
+
+	res, err := run(scheduledAt)
+	rec.FilesProcessed = res.FilesProcessed
+	rec.Status = StatusSuccess
+	if err != nil {
+		rec.Status = StatusFailed
+		rec.Error = err.Error()
+		slog.Error("İş hata ile tamamlandı", "job", e.job.Name, "run_id", rec.ID, "error", err)
+	}
+	return rec
+}